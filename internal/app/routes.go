@@ -7,6 +7,7 @@ import (
 	"github.com/filipio/athletics-backend/internal/controllers"
 	m "github.com/filipio/athletics-backend/internal/middleware"
 	"github.com/filipio/athletics-backend/internal/models"
+	"github.com/filipio/athletics-backend/internal/storage"
 	"github.com/filipio/athletics-backend/pkg/httpio"
 )
 
@@ -14,6 +15,11 @@ import (
 func addRoutes(mux *http.ServeMux, deps *config.Dependencies) {
 	db := deps.DB
 	auth := m.NewAuthMiddleware(deps)
+	canWriteEvents := auth.Require(httpio.EventsWritePermission)
+	canWriteQuestions := auth.Require(httpio.QuestionsWritePermission)
+	canManageUsers := auth.Require(httpio.UsersManagePermission)
+	canManageRoles := auth.Require(httpio.RolesManagePermission)
+	canReadAudit := auth.Require(httpio.AuditReadPermission)
 
 	mux.HandleFunc("GET /api/healthz", func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) })
 	mux.HandleFunc("GET /api/readyz", func(w http.ResponseWriter, r *http.Request) {
@@ -26,23 +32,63 @@ func addRoutes(mux *http.ServeMux, deps *config.Dependencies) {
 		w.WriteHeader(http.StatusOK)
 	})
 
+	mux.Handle("GET /.well-known/jwks.json", m.ErrorsMiddleware(controllers.GetJWKS(deps)))
+
+	// files of the local storage are served by the app, S3 compatible storage serves them itself
+	if fileServer, ok := deps.Storage.(http.Handler); ok {
+		mux.Handle("GET "+storage.LocalFilesPath, fileServer)
+	}
+
 	mux.Handle("GET /api/v1/pokemons", m.ErrorsMiddleware(auth.UserOnly(controllers.GetAll[models.Pokemon](deps))))
 	mux.Handle("GET /api/v1/pokemons/{id}", m.ErrorsMiddleware(controllers.Get[models.Pokemon](deps)))
 	mux.Handle("POST /api/v1/pokemons", m.ErrorsMiddleware(auth.UserOnly(controllers.Create[models.Pokemon](deps))))
 	mux.Handle("PUT /api/v1/pokemons/{id}", m.ErrorsMiddleware(controllers.Update[models.Pokemon](deps)))
+	mux.Handle("PATCH /api/v1/pokemons/{id}", m.ErrorsMiddleware(auth.UserOnly(controllers.Patch[models.Pokemon](deps))))
 	mux.Handle("DELETE /api/v1/pokemons/{id}", m.ErrorsMiddleware(controllers.Delete[models.Pokemon](deps)))
 
 	mux.Handle("POST /api/v1/auth/register/request-verification", m.ErrorsMiddleware(controllers.RequestVerification(deps)))
+	mux.Handle("POST /api/v1/auth/register/resend", m.ErrorsMiddleware(controllers.ResendVerification(deps)))
+	mux.Handle("GET /api/v1/auth/username-availability", m.ErrorsMiddleware(controllers.CheckUsernameAvailability(deps)))
 	mux.Handle("POST /api/v1/auth/verify-email", m.ErrorsMiddleware(controllers.VerifyEmail(deps)))
 	mux.Handle("POST /api/v1/login", m.ErrorsMiddleware(controllers.Login(deps)))
+	mux.Handle("POST /api/v1/auth/2fa/verify", m.ErrorsMiddleware(controllers.VerifyLoginChallenge(deps)))
 	mux.Handle("POST /api/v1/auth/refresh", m.ErrorsMiddleware(controllers.RefreshToken(deps)))
-	mux.Handle("POST /api/v1/auth/logout", m.ErrorsMiddleware(auth.UserOnly(controllers.Logout(deps))))
-
-	mux.Handle("GET /api/v1/users", m.ErrorsMiddleware(auth.AdminOnly(controllers.GetAll[models.User](deps))))
-	mux.Handle("GET /api/v1/users/{id}", m.ErrorsMiddleware(auth.AdminOnly(controllers.Get[models.User](deps))))
-	mux.Handle("POST /api/v1/users", m.ErrorsMiddleware(auth.AdminOnly(controllers.CreateUser(deps))))
-	mux.Handle("PUT /api/v1/users/{id}", m.ErrorsMiddleware(auth.AdminOnly(controllers.Update[models.User](deps))))
-	mux.Handle("DELETE /api/v1/users/{id}", m.ErrorsMiddleware(auth.AdminOnly(controllers.Delete[models.User](deps))))
+	mux.Handle("POST /api/v1/auth/logout", m.ErrorsMiddleware(auth.SessionOnly(controllers.Logout(deps))))
+	mux.Handle("GET /api/v1/auth/oidc/providers", m.ErrorsMiddleware(controllers.GetOidcProviders(deps)))
+	mux.Handle("POST /api/v1/auth/oidc/{provider}/authorize", m.ErrorsMiddleware(controllers.StartOidcLogin(deps)))
+	mux.Handle("POST /api/v1/auth/oidc/{provider}/callback", m.ErrorsMiddleware(controllers.CompleteOidcLogin(deps)))
+	mux.Handle("POST /api/v1/auth/password-reset/request", m.ErrorsMiddleware(controllers.RequestPasswordReset(deps)))
+	mux.Handle("POST /api/v1/auth/password-reset/confirm", m.ErrorsMiddleware(controllers.ConfirmPasswordReset(deps)))
+	mux.Handle("POST /api/v1/auth/magic-link", m.ErrorsMiddleware(controllers.RequestMagicLink(deps)))
+	mux.Handle("POST /api/v1/auth/magic-link/consume", m.ErrorsMiddleware(controllers.ConsumeMagicLink(deps)))
+	mux.Handle("POST /api/v1/auth/email-change/confirm", m.ErrorsMiddleware(controllers.ConfirmEmailChange(deps)))
+
+	mux.Handle("GET /api/v1/users", m.ErrorsMiddleware(canManageUsers(controllers.GetAll[models.User](deps))))
+	mux.Handle("GET /api/v1/users/{id}", m.ErrorsMiddleware(canManageUsers(controllers.Get[models.User](deps))))
+	mux.Handle("POST /api/v1/users", m.ErrorsMiddleware(canManageUsers(controllers.CreateUser(deps))))
+	mux.Handle("PUT /api/v1/users/{id}", m.ErrorsMiddleware(canManageUsers(controllers.UpdateUser(deps))))
+	mux.Handle("PATCH /api/v1/users/{id}", m.ErrorsMiddleware(canManageUsers(controllers.PatchUser(deps))))
+	mux.Handle("DELETE /api/v1/users/{id}", m.ErrorsMiddleware(canManageUsers(controllers.Delete[models.User](deps))))
+	mux.Handle("DELETE /api/v1/users/{id}/sessions", m.ErrorsMiddleware(canManageUsers(controllers.RevokeUserSessions(deps))))
+	mux.Handle("POST /api/v1/users/{id}/unlock", m.ErrorsMiddleware(canManageUsers(controllers.UnlockUserLogin(deps))))
+	mux.Handle("PUT /api/v1/users/{id}/roles", m.ErrorsMiddleware(canManageRoles(controllers.AssignUserRoles(deps))))
+	mux.Handle("POST /api/v1/users/{id}/roles/{role}", m.ErrorsMiddleware(canManageRoles(controllers.GrantUserRole(deps))))
+	mux.Handle("DELETE /api/v1/users/{id}/roles/{role}", m.ErrorsMiddleware(canManageRoles(controllers.RevokeUserRole(deps))))
+	mux.Handle("POST /api/v1/users/{id}/suspend", m.ErrorsMiddleware(canManageUsers(controllers.SuspendUser(deps))))
+	mux.Handle("POST /api/v1/users/{id}/unsuspend", m.ErrorsMiddleware(canManageUsers(controllers.UnsuspendUser(deps))))
+	mux.Handle("POST /api/v1/users/{id}/username/reset", m.ErrorsMiddleware(canManageUsers(controllers.ResetUsername(deps))))
+	mux.Handle("GET /api/v1/users/{id}/moderation-actions", m.ErrorsMiddleware(canManageUsers(controllers.GetAll[models.ModerationAction](deps))))
+
+	mux.Handle("GET /api/v1/permissions", m.ErrorsMiddleware(canManageRoles(controllers.GetPermissions(deps))))
+	mux.Handle("GET /api/v1/roles", m.ErrorsMiddleware(canManageRoles(controllers.GetAll[models.Role](deps))))
+	mux.Handle("GET /api/v1/roles/{id}", m.ErrorsMiddleware(canManageRoles(controllers.Get[models.Role](deps))))
+	mux.Handle("POST /api/v1/roles", m.ErrorsMiddleware(canManageRoles(controllers.CreateRole(deps))))
+	mux.Handle("PUT /api/v1/roles/{id}", m.ErrorsMiddleware(canManageRoles(controllers.UpdateRole(deps))))
+	mux.Handle("PATCH /api/v1/roles/{id}", m.ErrorsMiddleware(canManageRoles(controllers.PatchRole(deps))))
+	mux.Handle("DELETE /api/v1/roles/{id}", m.ErrorsMiddleware(canManageRoles(controllers.DeleteRole(deps))))
+	mux.Handle("PUT /api/v1/roles/{name}/two-factor", m.ErrorsMiddleware(canManageRoles(controllers.SetRoleTwoFactorRequirement(deps))))
+
+	mux.Handle("GET /api/v1/audit-logs", m.ErrorsMiddleware(canReadAudit(controllers.GetAll[models.AuditLog](deps))))
 
 	mux.Handle("GET /api/v1/athletes", m.ErrorsMiddleware(auth.UserOnly(controllers.GetAll[models.Athlete](deps))))
 	mux.Handle("GET /api/v1/athletes/{id}", m.ErrorsMiddleware(auth.UserOnly(controllers.Get[models.Athlete](deps))))
@@ -52,26 +98,56 @@ func addRoutes(mux *http.ServeMux, deps *config.Dependencies) {
 
 	mux.Handle("GET /api/v1/events", m.ErrorsMiddleware(auth.UserOnly(controllers.GetAll[models.Event](deps))))
 	mux.Handle("GET /api/v1/events/{id}", m.ErrorsMiddleware(auth.UserOnly(controllers.Get[models.Event](deps))))
-	mux.Handle("POST /api/v1/events", m.ErrorsMiddleware(auth.OrganizerOnly(controllers.Create[models.Event](deps))))
-	mux.Handle("PUT /api/v1/events/{id}", m.ErrorsMiddleware(auth.OrganizerOnly(controllers.Update[models.Event](deps))))
-	mux.Handle("DELETE /api/v1/events/{id}", m.ErrorsMiddleware(auth.OrganizerOnly(controllers.Delete[models.Event](deps))))
-	mux.Handle("POST /api/v1/events/{id}/publish", m.ErrorsMiddleware(auth.OrganizerOnly(controllers.PublishEvent(deps))))
-	mux.Handle("POST /api/v1/events/{id}/unpublish", m.ErrorsMiddleware(auth.OrganizerOnly(controllers.UnpublishEvent(deps))))
+	mux.Handle("POST /api/v1/events", m.ErrorsMiddleware(canWriteEvents(controllers.Create[models.Event](deps))))
+	mux.Handle("PUT /api/v1/events/{id}", m.ErrorsMiddleware(canWriteEvents(controllers.Update[models.Event](deps))))
+	mux.Handle("PATCH /api/v1/events/{id}", m.ErrorsMiddleware(canWriteEvents(controllers.Patch[models.Event](deps))))
+	mux.Handle("DELETE /api/v1/events/{id}", m.ErrorsMiddleware(canWriteEvents(controllers.Delete[models.Event](deps))))
+	mux.Handle("POST /api/v1/events/{id}/restore", m.ErrorsMiddleware(canWriteEvents(controllers.Restore[models.Event](deps))))
+	mux.Handle("POST /api/v1/events/{id}/publish", m.ErrorsMiddleware(canWriteEvents(controllers.PublishEvent(deps))))
+	mux.Handle("POST /api/v1/events/{id}/unpublish", m.ErrorsMiddleware(canWriteEvents(controllers.UnpublishEvent(deps))))
+	mux.Handle("GET /api/v1/events/{id}/stats", m.ErrorsMiddleware(auth.UserOnly(controllers.GetEventStats(deps))))
 
 	mux.Handle("GET /api/v1/questions", m.ErrorsMiddleware(auth.UserOnly(controllers.GetAll[models.Question](deps))))
 	mux.Handle("GET /api/v1/questions/{id}", m.ErrorsMiddleware(auth.UserOnly(controllers.Get[models.Question](deps))))
-	mux.Handle("POST /api/v1/questions", m.ErrorsMiddleware(auth.OrganizerOnly(controllers.CreateQuestion(deps))))
-	mux.Handle("PUT /api/v1/questions/{id}", m.ErrorsMiddleware(auth.OrganizerOnly(controllers.UpdateQuestion(deps))))
-	mux.Handle("DELETE /api/v1/questions/{id}", m.ErrorsMiddleware(auth.OrganizerOnly(controllers.Delete[models.Question](deps))))
+	mux.Handle("POST /api/v1/questions", m.ErrorsMiddleware(canWriteQuestions(controllers.CreateQuestion(deps))))
+	mux.Handle("PUT /api/v1/questions/{id}", m.ErrorsMiddleware(canWriteQuestions(controllers.UpdateQuestion(deps))))
+	mux.Handle("PATCH /api/v1/questions/{id}", m.ErrorsMiddleware(canWriteQuestions(controllers.PatchQuestion(deps))))
+	mux.Handle("DELETE /api/v1/questions/{id}", m.ErrorsMiddleware(canWriteQuestions(controllers.Delete[models.Question](deps))))
+	mux.Handle("POST /api/v1/questions/{id}/restore", m.ErrorsMiddleware(canWriteQuestions(controllers.Restore[models.Question](deps))))
+	mux.Handle("GET /api/v1/questions/{id}/stats", m.ErrorsMiddleware(auth.UserOnly(controllers.GetQuestionStats(deps))))
 
 	mux.Handle("GET /api/v1/users/me/answers", m.ErrorsMiddleware(auth.UserOnly(controllers.GetAll[models.Answer](deps))))
 	mux.Handle("GET /api/v1/users/me/answers/{id}", m.ErrorsMiddleware(auth.UserOnly(controllers.Get[models.Answer](deps))))
 	mux.Handle("POST /api/v1/users/me/answers", m.ErrorsMiddleware(auth.UserOnly(controllers.CreateAnswer(deps))))
 	mux.Handle("PUT /api/v1/users/me/answers/{id}", m.ErrorsMiddleware(auth.UserOnly(controllers.UpdateAnswer(deps))))
+	mux.Handle("PATCH /api/v1/users/me/answers/{id}", m.ErrorsMiddleware(auth.UserOnly(controllers.PatchAnswer(deps))))
 	mux.Handle("DELETE /api/v1/users/me/answers/{id}", m.ErrorsMiddleware(auth.UserOnly(controllers.Delete[models.Answer](deps))))
 
 	mux.Handle("GET /api/v1/users/me", m.ErrorsMiddleware(auth.UserOnly(controllers.Get[models.User](deps))))
 	mux.Handle("GET /api/v1/users/me/ranking", m.ErrorsMiddleware(auth.UserOnly(controllers.GetMyRanking(deps))))
+	mux.Handle("GET /api/v1/users/me/ranking/around", m.ErrorsMiddleware(auth.UserOnly(controllers.GetMyRankingAround(deps))))
+	mux.Handle("GET /api/v1/users/me/profile", m.ErrorsMiddleware(auth.UserOnly(controllers.GetMyProfile(deps))))
+	mux.Handle("PUT /api/v1/users/me/profile", m.ErrorsMiddleware(auth.UserOnly(controllers.UpdateProfile(deps))))
+	mux.Handle("PUT /api/v1/users/me/profile/avatar", m.ErrorsMiddleware(auth.UserOnly(controllers.UploadAvatar(deps))))
+	mux.Handle("DELETE /api/v1/users/me/profile/avatar", m.ErrorsMiddleware(auth.UserOnly(controllers.DeleteAvatar(deps))))
+	mux.Handle("GET /api/v1/users/{id}/profile", m.ErrorsMiddleware(controllers.GetUserProfile(deps)))
+	mux.Handle("PUT /api/v1/users/me/password", m.ErrorsMiddleware(auth.SessionOnly(controllers.ChangePassword(deps))))
+	mux.Handle("POST /api/v1/users/me/email", m.ErrorsMiddleware(auth.SessionOnly(controllers.RequestEmailChange(deps))))
+	mux.Handle("GET /api/v1/users/me/2fa", m.ErrorsMiddleware(auth.SessionOnly(controllers.GetMyTwoFactorStatus(deps))))
+	mux.Handle("POST /api/v1/users/me/2fa/enroll", m.ErrorsMiddleware(auth.SessionOnly(controllers.EnrollTwoFactor(deps))))
+	mux.Handle("POST /api/v1/users/me/2fa/confirm", m.ErrorsMiddleware(auth.SessionOnly(controllers.ConfirmTwoFactor(deps))))
+	mux.Handle("POST /api/v1/users/me/2fa/recovery-codes", m.ErrorsMiddleware(auth.SessionOnly(controllers.RegenerateRecoveryCodes(deps))))
+	mux.Handle("DELETE /api/v1/users/me/2fa", m.ErrorsMiddleware(auth.SessionOnly(controllers.DisableTwoFactor(deps))))
+	mux.Handle("GET /api/v1/users/me/sessions", m.ErrorsMiddleware(auth.SessionOnly(controllers.GetMySessions(deps))))
+	mux.Handle("DELETE /api/v1/users/me/sessions", m.ErrorsMiddleware(auth.SessionOnly(controllers.RevokeMyOtherSessions(deps))))
+	mux.Handle("DELETE /api/v1/users/me/sessions/{session_id}", m.ErrorsMiddleware(auth.SessionOnly(controllers.RevokeMySession(deps))))
+	mux.Handle("GET /api/v1/users/me/tokens", m.ErrorsMiddleware(auth.SessionOnly(controllers.GetAll[models.PersonalAccessToken](deps))))
+	mux.Handle("POST /api/v1/users/me/tokens", m.ErrorsMiddleware(auth.SessionOnly(controllers.CreatePersonalAccessToken(deps))))
+	mux.Handle("DELETE /api/v1/users/me/tokens/{id}", m.ErrorsMiddleware(auth.SessionOnly(controllers.RevokePersonalAccessToken(deps))))
+	mux.Handle("POST /api/v1/users/me/export", m.ErrorsMiddleware(auth.SessionOnly(controllers.RequestDataExport(deps))))
+	mux.Handle("GET /api/v1/users/me/export", m.ErrorsMiddleware(auth.SessionOnly(controllers.GetDataExport(deps))))
+	mux.Handle("GET /api/v1/users/me/export/download", m.ErrorsMiddleware(auth.SessionOnly(controllers.DownloadDataExport(deps))))
+	mux.Handle("DELETE /api/v1/users/me", m.ErrorsMiddleware(auth.SessionOnly(controllers.DeleteMyAccount(deps))))
 
 	mux.Handle("GET /api/v1/answers", m.ErrorsMiddleware(auth.UserOnly(controllers.GetAll[models.Answer](deps))))
 	mux.Handle("GET /api/v1/answers/{id}", m.ErrorsMiddleware(auth.UserOnly(controllers.Get[models.Answer](deps))))
@@ -13,6 +13,8 @@ import (
 	"github.com/filipio/athletics-backend/pkg/config"
 	"github.com/filipio/athletics-backend/internal/email"
 	m "github.com/filipio/athletics-backend/internal/middleware"
+	"github.com/filipio/athletics-backend/internal/oidc"
+	"github.com/filipio/athletics-backend/internal/storage"
 	"github.com/filipio/athletics-backend/internal/models"
 	"github.com/filipio/athletics-backend/pkg/httpio"
 	"github.com/filipio/athletics-backend/internal/workers"
@@ -25,13 +27,9 @@ import (
 const shutdownTimeout = 10 * time.Second
 
 func seed(db *gorm.DB) {
-	adminRole := models.Role{Name: httpio.AdminRole}
-	userRole := models.Role{Name: httpio.UserRole}
-	organizerRole := models.Role{Name: httpio.OrganizerRole}
-
-	db.FirstOrCreate(&adminRole, adminRole)
-	db.FirstOrCreate(&userRole, userRole)
-	db.FirstOrCreate(&organizerRole, organizerRole)
+	adminRole := seedRole(db, httpio.AdminRole)
+	seedRole(db, httpio.UserRole)
+	seedRole(db, httpio.OrganizerRole)
 
 	adminEmail := os.Getenv("ADMIN_EMAIL")
 	adminPassword := os.Getenv("ADMIN_PASSWORD")
@@ -49,6 +47,19 @@ func seed(db *gorm.DB) {
 	}
 }
 
+// default permissions are granted only to newly created roles, so that changes made by admins are kept
+func seedRole(db *gorm.DB, name string) models.Role {
+	role := models.Role{Name: name}
+	result := db.FirstOrCreate(&role, role)
+	if result.Error == nil && result.RowsAffected > 0 {
+		for _, permission := range httpio.DefaultRolePermissions[name] {
+			db.Create(&models.RolePermission{RoleID: role.ID, Permission: permission})
+		}
+	}
+
+	return role
+}
+
 // the only function to be used in order to add new workers
 func appWorkers(deps *config.Dependencies) *river.Workers {
 	riverWorkers := river.NewWorkers()
@@ -56,10 +67,19 @@ func appWorkers(deps *config.Dependencies) *river.Workers {
 	river.AddWorker(riverWorkers, workers.NewSortWorker(deps))
 	river.AddWorker(riverWorkers, workers.NewPokemonWorker(deps))
 	river.AddWorker(riverWorkers, workers.NewPointsGranterWorker(deps))
+	river.AddWorker(riverWorkers, workers.NewCleanupWorker(deps))
+	river.AddWorker(riverWorkers, workers.NewDataExportWorker(deps))
 
 	return riverWorkers
 }
 
+// jobs scheduled by the workers client itself, their workers must be added in appWorkers
+func appPeriodicJobs() []*river.PeriodicJob {
+	return []*river.PeriodicJob{
+		workers.CleanupPeriodicJob(),
+	}
+}
+
 func Run(ctx context.Context, envPath string) error {
 	ctx, cancel := signal.NotifyContext(ctx, os.Interrupt)
 	defer cancel()
@@ -87,11 +107,23 @@ func Run(ctx context.Context, envPath string) error {
 	// Initialize email sender
 	emailSender := email.GetDefaultEmailSender()
 
+	jwtKeys := config.JwtKeys()
+	slog.Info("loaded jwt keys")
+
+	oidcProviders := oidc.LoadProviders()
+	slog.Info("loaded oidc providers", "count", len(oidcProviders))
+
+	fileStorage, err := storage.LoadStorage()
+	if err != nil {
+		return err
+	}
+	slog.Info("configured file storage")
+
 	// Create dependencies container (workers set to nil temporarily)
-	deps := config.NewDependencies(db, nil, emailSender)
+	deps := config.NewDependencies(db, nil, emailSender, jwtKeys, oidcProviders, fileStorage)
 
 	// Create workers with dependencies
-	workersClient := config.SetupWorkersClient(ctx, db, appWorkers(deps))
+	workersClient := config.SetupWorkersClient(ctx, db, appWorkers(deps), appPeriodicJobs())
 	slog.Info("started workers client")
 
 	// Update Workers in deps
@@ -150,8 +182,9 @@ func newServerHandler(deps *config.Dependencies) http.Handler {
 
 	c := cors.New(cors.Options{
 		AllowedOrigins:   []string{"http://localhost:3000"},
-		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowedHeaders:   []string{"Content-Type", "Authorization"},
+		AllowedMethods:   []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
+		AllowedHeaders:   []string{"Content-Type", "Authorization", "X-CSRF-Token", "X-Auth-Mode", "If-Match", "If-None-Match"},
+		ExposedHeaders:   []string{"ETag"},
 		AllowCredentials: true,
 	})
 	var handler http.Handler = c.Handler(mux)
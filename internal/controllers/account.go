@@ -0,0 +1,222 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/filipio/athletics-backend/internal/email"
+	"github.com/filipio/athletics-backend/internal/models"
+	"github.com/filipio/athletics-backend/pkg/config"
+	"github.com/filipio/athletics-backend/pkg/httpio"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+type ChangePasswordPayload struct {
+	CurrentPassword string `json:"current_password" validate:"required"`
+	NewPassword     string `json:"new_password" validate:"required,min=6"`
+}
+
+func (payload ChangePasswordPayload) Validate(db *gorm.DB) error {
+	return nil
+}
+
+type RequestEmailChangePayload struct {
+	NewEmail string `json:"new_email" validate:"required,email"`
+	Password string `json:"password" validate:"required"`
+}
+
+func (payload RequestEmailChangePayload) Validate(db *gorm.DB) error {
+	return nil
+}
+
+type ConfirmEmailChangePayload struct {
+	Token string `json:"token" validate:"required"`
+}
+
+func (payload ConfirmEmailChangePayload) Validate(db *gorm.DB) error {
+	return nil
+}
+
+// ChangePassword sets new password of the current user, all sessions except the current one are revoked
+func ChangePassword(deps *config.Dependencies) httpio.HandlerWithError {
+	return httpio.HandlerWithError(
+		func(w http.ResponseWriter, r *http.Request) error {
+			db := deps.DB
+			payload, err := httpio.DecodeAndValidate[ChangePasswordPayload](r, db)
+			if err != nil {
+				return err
+			}
+
+			currentUser := r.Context().Value(httpio.UserContextKey).(models.User)
+			if err := verifyPassword(currentUser, payload.CurrentPassword, "current_password"); err != nil {
+				return err
+			}
+
+			hashedPassword, err := bcrypt.GenerateFromPassword([]byte(payload.NewPassword), 10)
+			if err != nil {
+				return err
+			}
+
+			sessionID, _ := r.Context().Value(httpio.SessionIDContextKey).(string)
+
+			err = db.Transaction(func(tx *gorm.DB) error {
+				if err := tx.Model(&currentUser).Update("password", string(hashedPassword)).Error; err != nil {
+					return err
+				}
+
+				return revokeUserSessions(tx, currentUser.ID, sessionID)
+			})
+			if err != nil {
+				return err
+			}
+
+			if err := httpio.Encode(w, r, http.StatusOK, httpio.AnyMap{
+				"message": "password changed successfully",
+			}); err != nil {
+				return err
+			}
+
+			return nil
+		})
+}
+
+// RequestEmailChange sends confirmation to the new address, email of the user is changed only after confirmation
+func RequestEmailChange(deps *config.Dependencies) httpio.HandlerWithError {
+	return httpio.HandlerWithError(
+		func(w http.ResponseWriter, r *http.Request) error {
+			db := deps.DB
+			payload, err := httpio.DecodeAndValidate[RequestEmailChangePayload](r, db)
+			if err != nil {
+				return err
+			}
+
+			currentUser := r.Context().Value(httpio.UserContextKey).(models.User)
+			if err := verifyPassword(currentUser, payload.Password, "password"); err != nil {
+				return err
+			}
+
+			var existingUser models.User
+			db.Where("email = ?", payload.NewEmail).First(&existingUser)
+			if existingUser.ID != 0 {
+				return httpio.EmailAlreadyExistsError{}
+			}
+
+			var rateLimit models.EmailVerificationRateLimit
+			db.FirstOrCreate(&rateLimit, models.EmailVerificationRateLimit{Email: payload.NewEmail})
+
+			if !rateLimit.CanRequestVerification() {
+				return httpio.EmailVerificationRateLimitError{
+					BlockedUntil: rateLimit.BlockedUntilString(),
+				}
+			}
+
+			pendingChange := models.PendingEmailChange{
+				UserID:   currentUser.ID,
+				NewEmail: payload.NewEmail,
+			}
+
+			if err := pendingChange.GenerateVerificationToken(); err != nil {
+				return err
+			}
+
+			err = db.Transaction(func(tx *gorm.DB) error {
+				// only the latest request of the user is valid
+				if err := tx.Where("user_id = ?", currentUser.ID).Delete(&models.PendingEmailChange{}).Error; err != nil {
+					return err
+				}
+
+				if err := tx.Create(&pendingChange).Error; err != nil {
+					return err
+				}
+
+				rateLimit.IncrementRequestCount()
+				return tx.Save(&rateLimit).Error
+			})
+			if err != nil {
+				return err
+			}
+
+			emailErr := deps.EmailSender.SendEmailChangeConfirmationEmail(r.Context(), email.EmailChangeConfirmationEmailParams{
+				To:                payload.NewEmail,
+				ConfirmationToken: pendingChange.VerificationToken,
+			})
+
+			if emailErr != nil {
+				return httpio.EmailSendError{
+					OriginalError: emailErr,
+				}
+			}
+
+			if err := httpio.Encode(w, r, http.StatusOK, httpio.AnyMap{
+				"message": "Confirmation email sent to the new address. Please check your inbox.",
+			}); err != nil {
+				return err
+			}
+
+			return nil
+		})
+}
+
+func ConfirmEmailChange(deps *config.Dependencies) httpio.HandlerWithError {
+	return httpio.HandlerWithError(
+		func(w http.ResponseWriter, r *http.Request) error {
+			db := deps.DB
+			payload, err := httpio.DecodeAndValidate[ConfirmEmailChangePayload](r, db)
+			if err != nil {
+				return err
+			}
+
+			var pendingChange models.PendingEmailChange
+			db.Where("verification_token = ?", payload.Token).First(&pendingChange)
+
+			if pendingChange.ID == 0 || pendingChange.IsExpired() {
+				return httpio.InvalidVerificationTokenError{}
+			}
+
+			// the address could have been registered by someone else in the meantime
+			var existingUser models.User
+			db.Where("email = ?", pendingChange.NewEmail).First(&existingUser)
+			if existingUser.ID != 0 {
+				return httpio.EmailAlreadyExistsError{}
+			}
+
+			var user models.User
+			err = db.Transaction(func(tx *gorm.DB) error {
+				if err := tx.First(&user, pendingChange.UserID).Error; err != nil {
+					return httpio.InvalidVerificationTokenError{}
+				}
+
+				if err := tx.Model(&user).Update("email", pendingChange.NewEmail).Error; err != nil {
+					return err
+				}
+
+				if err := tx.Delete(&pendingChange).Error; err != nil {
+					return err
+				}
+
+				return tx.Where("email = ?", pendingChange.NewEmail).Delete(&models.EmailVerificationRateLimit{}).Error
+			})
+			if err != nil {
+				return err
+			}
+
+			db.Preload("Roles.Permissions").First(&user, user.ID)
+
+			if err := httpio.Encode(w, r, http.StatusOK, user.BuildResponse()); err != nil {
+				return err
+			}
+
+			return nil
+		})
+}
+
+func verifyPassword(user models.User, password string, fieldPath string) error {
+	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)); err != nil {
+		return httpio.AppValidationError{
+			FieldPath: fieldPath,
+			AppError:  httpio.AppError{Message: "is invalid"},
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,191 @@
+package controllers
+
+import (
+	"crypto/rand"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/filipio/athletics-backend/internal/models"
+	"github.com/filipio/athletics-backend/pkg/config"
+	"github.com/filipio/athletics-backend/pkg/httpio"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// DeleteAccountPayload re-authenticates the user with the password or, for users with two factor authentication,
+// with a fresh code - users signed in only with OIDC don't know their password
+type DeleteAccountPayload struct {
+	Password string `json:"password"`
+	Code     string `json:"code"`
+}
+
+func (payload DeleteAccountPayload) Validate(db *gorm.DB) error {
+	if payload.Password == "" && payload.Code == "" {
+		return httpio.AppValidationError{
+			FieldPath: "password",
+			AppError:  httpio.AppError{Message: "password or two factor code is required"},
+		}
+	}
+
+	return nil
+}
+
+// DeleteMyAccount removes the current user. Users with answers are anonymized instead of deleted,
+// so rankings and statistics of past events do not change
+func DeleteMyAccount(deps *config.Dependencies) httpio.HandlerWithError {
+	return httpio.HandlerWithError(
+		func(w http.ResponseWriter, r *http.Request) error {
+			db := deps.DB
+			payload, err := httpio.DecodeAndValidate[DeleteAccountPayload](r, db)
+			if err != nil {
+				return err
+			}
+
+			currentUser := r.Context().Value(httpio.UserContextKey).(models.User)
+			if err := reauthenticateAccountDeletion(db, currentUser, payload); err != nil {
+				return err
+			}
+
+			// files are removed only after commit, so they are kept if the deletion fails
+			fileKeys := []string{}
+			anonymized := false
+
+			err = db.Transaction(func(tx *gorm.DB) error {
+				var profile models.UserProfile
+				tx.Where("user_id = ?", currentUser.ID).First(&profile)
+				if profile.AvatarKey != nil {
+					fileKeys = append(fileKeys, *profile.AvatarKey)
+				}
+
+				var exportKeys []string
+				if err := tx.Model(&models.DataExport{}).
+					Where("user_id = ? AND file_key IS NOT NULL", currentUser.ID).
+					Pluck("file_key", &exportKeys).Error; err != nil {
+					return err
+				}
+				fileKeys = append(fileKeys, exportKeys...)
+
+				if err := deleteUserData(tx, currentUser.ID); err != nil {
+					return err
+				}
+
+				var answersCount int64
+				if err := tx.Model(&models.Answer{}).Where("user_id = ?", currentUser.ID).Count(&answersCount).Error; err != nil {
+					return err
+				}
+
+				if answersCount == 0 {
+					if err := tx.Select("Roles").Delete(&currentUser).Error; err != nil {
+						return err
+					}
+				} else {
+					anonymized = true
+					if err := anonymizeUser(tx, currentUser); err != nil {
+						return err
+					}
+				}
+
+				return ensureRolesManagerExists(tx, "roles")
+			})
+			if err != nil {
+				return err
+			}
+
+			for _, key := range fileKeys {
+				if err := deps.Storage.Delete(r.Context(), key); err != nil {
+					slog.Error("error deleting file of deleted user", "key", key, "error", err)
+				}
+			}
+
+			httpio.ClearAuthCookies(w)
+			if err := httpio.Encode(w, r, http.StatusOK, httpio.AnyMap{
+				"message":    "account deleted successfully",
+				"anonymized": anonymized,
+			}); err != nil {
+				return err
+			}
+
+			return nil
+		})
+}
+
+func reauthenticateAccountDeletion(db *gorm.DB, user models.User, payload DeleteAccountPayload) error {
+	if payload.Code == "" {
+		return verifyPassword(user, payload.Password, "password")
+	}
+
+	twoFactor, err := findConfirmedTwoFactor(db, user.ID)
+	if err != nil {
+		return err
+	}
+	if twoFactor == nil {
+		return httpio.TwoFactorNotEnabledError{}
+	}
+
+	codeValid, err := useTwoFactorCode(db, twoFactor, payload.Code)
+	if err != nil {
+		return err
+	}
+	if !codeValid {
+		return invalidTwoFactorCodeError()
+	}
+
+	return nil
+}
+
+// deleteUserData removes everything what belongs to the user except answers, it also ends all sessions
+func deleteUserData(tx *gorm.DB, userID uint) error {
+	var profileIDs []uint
+	if err := tx.Model(&models.UserProfile{}).Where("user_id = ?", userID).Pluck("id", &profileIDs).Error; err != nil {
+		return err
+	}
+	for _, profileID := range profileIDs {
+		profile := models.UserProfile{AppModel: models.AppModel{ID: profileID}}
+		if err := tx.Select("FavouriteDisciplines", "FavouriteAthletes").Delete(&profile).Error; err != nil {
+			return err
+		}
+	}
+
+	for _, model := range []any{
+		&models.RefreshToken{},
+		&models.PersonalAccessToken{},
+		&models.UserIdentity{},
+		&models.UserTwoFactor{},
+		&models.TwoFactorRecoveryCode{},
+		&models.LoginChallenge{},
+		&models.PasswordResetToken{},
+		&models.MagicLinkToken{},
+		&models.PendingEmailChange{},
+		&models.DataExport{},
+	} {
+		if err := tx.Where("user_id = ?", userID).Delete(model).Error; err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// anonymizeUser keeps the row (and so answers and points) but nothing which could identify the person,
+// random password makes login impossible
+func anonymizeUser(tx *gorm.DB, user models.User) error {
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(rand.Text()), 10)
+	if err != nil {
+		return err
+	}
+
+	if err := tx.Model(&user).Association("Roles").Clear(); err != nil {
+		return err
+	}
+
+	return tx.Model(&user).Updates(map[string]any{
+		"username":        fmt.Sprintf("deleted_%d", user.ID),
+		"email":           fmt.Sprintf("deleted+%d@deleted.invalid", user.ID),
+		"password":        string(hashedPassword),
+		"suspended_at":    nil,
+		"suspended_until": nil,
+		"anonymized_at":   time.Now(),
+	}).Error
+}
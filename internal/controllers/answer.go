@@ -114,3 +114,57 @@ func UpdateAnswer(deps *config.Dependencies) httpio.HandlerWithError {
 		return nil
 	}
 }
+
+// PatchAnswer applies JSON merge patch to the answer of the current user, the rules of UpdateAnswer apply
+func PatchAnswer(deps *config.Dependencies) httpio.HandlerWithError {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		db := deps.DB
+		currentUser := r.Context().Value(httpio.UserContextKey).(models.User)
+		id := uint(httpio.IntPathValue(r, "id"))
+
+		if err := db.Transaction(func(tx *gorm.DB) error {
+			var existingAnswer models.Answer
+			result := tx.Where("user_id = ?", currentUser.ID).Limit(1).Find(&existingAnswer, id)
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected == 0 {
+				return httpio.RecordNotFoundError{}
+			}
+
+			if existingAnswer.PointsGrantedAt != nil {
+				return httpio.AppValidationError{
+					FieldPath: "question_id",
+					AppError:  httpio.AppError{Message: "points already granted"},
+				}
+			}
+
+			if _, err := mergePatch[models.Answer](tx, r, id); err != nil {
+				return err
+			}
+
+			// the transaction is rolled back, so the answer can't be handed over to another user
+			var patchedAnswer models.Answer
+			if err := tx.First(&patchedAnswer, id).Error; err != nil {
+				return err
+			}
+			if patchedAnswer.UserID != currentUser.ID {
+				return httpio.InvalidUserError{}
+			}
+
+			return nil
+		}); err != nil {
+			return err
+		}
+
+		var answer models.Answer
+		db.First(&answer, id)
+		response := answer.BuildResponse()
+
+		if err := httpio.Encode(w, r, http.StatusOK, response); err != nil {
+			return err
+		}
+
+		return nil
+	}
+}
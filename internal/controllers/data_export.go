@@ -0,0 +1,109 @@
+package controllers
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+
+	"github.com/filipio/athletics-backend/internal/models"
+	args "github.com/filipio/athletics-backend/internal/workers/args"
+	"github.com/filipio/athletics-backend/pkg/config"
+	"github.com/filipio/athletics-backend/pkg/httpio"
+	"gorm.io/gorm"
+)
+
+const dataExportDownloadPath = "/api/v1/users/me/export/download"
+
+// RequestDataExport schedules building of the archive with personal data, pending export is returned instead of a new one
+func RequestDataExport(deps *config.Dependencies) httpio.HandlerWithError {
+	return httpio.HandlerWithError(
+		func(w http.ResponseWriter, r *http.Request) error {
+			db := deps.DB
+			currentUser := r.Context().Value(httpio.UserContextKey).(models.User)
+
+			var export models.DataExport
+			db.Where("user_id = ? AND status = ?", currentUser.ID, models.DataExportStatusPending).First(&export)
+
+			if export.ID == 0 {
+				export = models.DataExport{UserID: currentUser.ID, Status: models.DataExportStatusPending}
+				err := db.Transaction(func(tx *gorm.DB) error {
+					if err := tx.Create(&export).Error; err != nil {
+						return err
+					}
+
+					_, err := deps.Workers.InsertTx(tx, args.DataExportArgs{ExportID: export.ID})
+					return err
+				})
+				if err != nil {
+					return err
+				}
+			}
+
+			if err := httpio.Encode(w, r, http.StatusAccepted, export.BuildExportResponse(dataExportDownloadPath)); err != nil {
+				return err
+			}
+
+			return nil
+		})
+}
+
+// GetDataExport returns the latest export of the current user with the download link when it is ready
+func GetDataExport(deps *config.Dependencies) httpio.HandlerWithError {
+	return httpio.HandlerWithError(
+		func(w http.ResponseWriter, r *http.Request) error {
+			export, err := latestDataExport(deps.DB, r)
+			if err != nil {
+				return err
+			}
+
+			if err := httpio.Encode(w, r, http.StatusOK, export.BuildExportResponse(dataExportDownloadPath)); err != nil {
+				return err
+			}
+
+			return nil
+		})
+}
+
+// DownloadDataExport streams the archive from the storage, exports are private so they are never served publicly
+func DownloadDataExport(deps *config.Dependencies) httpio.HandlerWithError {
+	return httpio.HandlerWithError(
+		func(w http.ResponseWriter, r *http.Request) error {
+			export, err := latestDataExport(deps.DB, r)
+			if err != nil {
+				return err
+			}
+
+			if !export.IsDownloadable() {
+				return httpio.RecordNotFoundError{}
+			}
+
+			file, err := deps.Storage.Open(r.Context(), *export.FileKey)
+			if err != nil {
+				return err
+			}
+			defer file.Close()
+
+			w.Header().Set("Content-Type", "application/zip")
+			w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="athletics-data-export-%d.zip"`, export.ID))
+			w.WriteHeader(http.StatusOK)
+			// headers are already sent, so the error can only be logged
+			if _, err := io.Copy(w, file); err != nil {
+				slog.Error("error streaming data export", "export_id", export.ID, "error", err)
+			}
+
+			return nil
+		})
+}
+
+func latestDataExport(db *gorm.DB, r *http.Request) (models.DataExport, error) {
+	currentUser := r.Context().Value(httpio.UserContextKey).(models.User)
+
+	var export models.DataExport
+	db.Where("user_id = ?", currentUser.ID).Order("id DESC").First(&export)
+	if export.ID == 0 {
+		return export, httpio.RecordNotFoundError{}
+	}
+
+	return export, nil
+}
@@ -19,6 +19,14 @@ type RequestVerificationPayload struct {
 }
 
 func (payload RequestVerificationPayload) Validate(db *gorm.DB) error {
+	return models.ValidateUsernameAvailable(db, payload.Username, 0, payload.Email)
+}
+
+type ResendVerificationPayload struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+func (payload ResendVerificationPayload) Validate(db *gorm.DB) error {
 	return nil
 }
 
@@ -111,6 +119,76 @@ func RequestVerification(deps *config.Dependencies) httpio.HandlerWithError {
 		})
 }
 
+// ResendVerification sends a new verification token for the existing pending registration, the previous token stops working.
+// Expired registration can be resent as well until it is purged by the cleanup job.
+func ResendVerification(deps *config.Dependencies) httpio.HandlerWithError {
+	return httpio.HandlerWithError(
+		func(w http.ResponseWriter, r *http.Request) error {
+			db := deps.DB
+			payload, err := httpio.DecodeAndValidate[ResendVerificationPayload](r, db)
+			if err != nil {
+				return err
+			}
+
+			var rateLimit models.EmailVerificationRateLimit
+			db.FirstOrCreate(&rateLimit, models.EmailVerificationRateLimit{Email: payload.Email})
+
+			if !rateLimit.CanRequestVerification() {
+				return httpio.RateLimitError{BlockedUntil: rateLimit.BlockedUntilString(), RetryAfterSeconds: rateLimit.RetryAfterSeconds()}
+			}
+
+			var pendingReg models.PendingRegistration
+			db.Where("email = ? AND verified = ?", payload.Email, false).Order("id DESC").First(&pendingReg)
+			if pendingReg.ID == 0 {
+				return httpio.AppValidationError{
+					FieldPath: "email",
+					AppError:  httpio.AppError{Message: "there is no pending registration for this email"},
+				}
+			}
+
+			// the email could have been taken in the meantime, e.g. by OIDC login
+			var existingUser models.User
+			db.Where("email = ?", payload.Email).First(&existingUser)
+			if existingUser.ID != 0 {
+				return httpio.EmailAlreadyExistsError{}
+			}
+
+			if err := pendingReg.GenerateVerificationToken(); err != nil {
+				return err
+			}
+
+			err = db.Transaction(func(tx *gorm.DB) error {
+				if err := tx.Model(&pendingReg).Updates(map[string]any{
+					"verification_token": pendingReg.VerificationToken,
+					"expires_at":         pendingReg.ExpiresAt,
+				}).Error; err != nil {
+					return err
+				}
+
+				rateLimit.IncrementRequestCount()
+				return tx.Save(&rateLimit).Error
+			})
+			if err != nil {
+				return err
+			}
+
+			emailErr := deps.EmailSender.SendVerificationEmail(r.Context(), email.VerificationEmailParams{
+				To:                pendingReg.Email,
+				VerificationToken: pendingReg.VerificationToken,
+			})
+
+			if emailErr != nil {
+				return httpio.EmailSendError{
+					OriginalError: emailErr,
+				}
+			}
+
+			return httpio.Encode(w, r, http.StatusOK, httpio.AnyMap{
+				"message": "Verification email sent. Please check your inbox.",
+			})
+		})
+}
+
 func VerifyEmail(deps *config.Dependencies) httpio.HandlerWithError {
 	return httpio.HandlerWithError(
 		func(w http.ResponseWriter, r *http.Request) error {
@@ -168,13 +246,11 @@ func VerifyEmail(deps *config.Dependencies) httpio.HandlerWithError {
 				return err
 			}
 
-			tokenPair, err := generateTokenPair(user, db, nil)
+			tokenPair, err := generateTokenPair(deps.JwtKeys, user, db, r, nil)
 			if err != nil {
 				return err
 			}
 
-			httpio.Encode(w, r, http.StatusOK, tokenPair)
-
-			return nil
+			return encodeTokenPair(w, r, tokenPair)
 		})
 }
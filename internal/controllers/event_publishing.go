@@ -7,6 +7,7 @@ import (
 	"github.com/filipio/athletics-backend/pkg/config"
 	"github.com/filipio/athletics-backend/internal/models"
 	"github.com/filipio/athletics-backend/pkg/httpio"
+	"gorm.io/gorm"
 )
 
 func PublishEvent(deps *config.Dependencies) httpio.HandlerWithError {
@@ -31,8 +32,15 @@ func PublishEvent(deps *config.Dependencies) httpio.HandlerWithError {
 			return nil
 		}
 
+		before := event.BuildResponse()
 		event.Status = "published"
-		if err := db.Save(&event).Error; err != nil {
+		if err := db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Save(&event).Error; err != nil {
+				return err
+			}
+
+			return models.RecordAudit(tx, r, models.AuditActionPublish, models.AuditResourceEvent, event.ID, before, event.BuildResponse())
+		}); err != nil {
 			return err
 		}
 
@@ -63,8 +71,15 @@ func UnpublishEvent(deps *config.Dependencies) httpio.HandlerWithError {
 			return nil
 		}
 
+		before := event.BuildResponse()
 		event.Status = "draft"
-		if err := db.Save(&event).Error; err != nil {
+		if err := db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Save(&event).Error; err != nil {
+				return err
+			}
+
+			return models.RecordAudit(tx, r, models.AuditActionUnpublish, models.AuditResourceEvent, event.ID, before, event.BuildResponse())
+		}); err != nil {
 			return err
 		}
 
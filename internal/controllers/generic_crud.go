@@ -1,21 +1,42 @@
 package controllers
 
 import (
+	"errors"
 	"net/http"
+	"slices"
+	"strings"
 
 	"github.com/filipio/athletics-backend/pkg/config"
 	"github.com/filipio/athletics-backend/internal/models"
 	"github.com/filipio/athletics-backend/pkg/httpio"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 func GetAll[T httpio.DbModel](deps *config.Dependencies) httpio.HandlerWithError {
 	return httpio.HandlerWithError(
 		func(w http.ResponseWriter, r *http.Request) error {
-			db := deps.DB
 			var instance T
+			db, err := models.WithDeleted(deps.DB, r, instance)
+			if err != nil {
+				return err
+			}
+			listFields := instance.ListFields()
+
+			query, err := models.FilterQuery(instance.GetAllQuery(db, r), r, listFields)
+			if err != nil {
+				return err
+			}
 
-			query := instance.GetAllQuery(db, r)
+			order, err := models.ListOrder(r, listFields)
+			if err != nil {
+				return err
+			}
+
+			fieldset, err := models.ListFieldset(r, instance.BuildResponse())
+			if err != nil {
+				return err
+			}
 
 			var totalCount int64
 
@@ -26,8 +47,9 @@ func GetAll[T httpio.DbModel](deps *config.Dependencies) httpio.HandlerWithError
 
 			paginationParams := httpio.BuildPaginationParams(r)
 
+			// ordering is added only after counting, postgres doesn't allow it in the count query
 			var records []T
-			queryResult := models.PaginateQuery(query, paginationParams).Find(&records)
+			queryResult := models.PageQuery(query, paginationParams).Order(order).Find(&records)
 			if queryResult.Error != nil {
 				return queryResult.Error
 			}
@@ -35,6 +57,11 @@ func GetAll[T httpio.DbModel](deps *config.Dependencies) httpio.HandlerWithError
 			var responseRecords []any = make([]any, len(records))
 			for i, record := range records {
 				responseRecords[i] = record.BuildResponse()
+				if fieldset != nil {
+					if responseRecords[i], err = httpio.SelectFields(responseRecords[i], fieldset); err != nil {
+						return err
+					}
+				}
 			}
 
 			paginationResponse := httpio.BuildPaginatedResponse(responseRecords, totalCount, paginationParams)
@@ -49,21 +76,22 @@ func GetAll[T httpio.DbModel](deps *config.Dependencies) httpio.HandlerWithError
 func Get[T httpio.DbModel](deps *config.Dependencies) httpio.HandlerWithError {
 	return httpio.HandlerWithError(
 		func(w http.ResponseWriter, r *http.Request) error {
-			db := deps.DB
 			var record T
+			db, err := models.WithDeleted(deps.DB, r, record)
+			if err != nil {
+				return err
+			}
 
-			query := record.GetQuery(db, r)
-			queryResult := query.First(&record)
-
-			if queryResult.Error != nil {
-				if queryResult.Error.Error() != "record not found" {
-					return queryResult.Error
-				} else {
-					return httpio.RecordNotFoundError{}
-				}
+			response, etag, err := findRepresentation[T](db, r)
+			if err != nil {
+				return err
 			}
 
-			response := record.BuildResponse()
+			w.Header().Set("ETag", etag)
+			if httpio.IfNoneMatchHit(r, etag) {
+				w.WriteHeader(http.StatusNotModified)
+				return nil
+			}
 
 			if err := httpio.Encode(w, r, http.StatusOK, response); err != nil {
 				return err
@@ -89,7 +117,7 @@ func Create[T httpio.DbModel](deps *config.Dependencies) httpio.HandlerWithError
 					return err
 				}
 
-				return nil
+				return auditChange[T](tx, r, models.AuditActionCreate, record.GetID(), nil)
 			}); err != nil {
 				return err
 			}
@@ -115,11 +143,19 @@ func Update[T httpio.DbModel](deps *config.Dependencies) httpio.HandlerWithError
 				return err
 			}
 
-			baseQuery := db.Model(&record)
-			query := record.UpdateQuery(baseQuery, r)
 			id := httpio.IntPathValue(r, "id")
 
 			if err := db.Transaction(func(tx *gorm.DB) error {
+				if err := checkIfMatch[T](tx, r, uint(id)); err != nil {
+					return err
+				}
+
+				before, err := auditSnapshot[T](tx, uint(id))
+				if err != nil {
+					return err
+				}
+
+				query := record.UpdateQuery(tx.Model(&record), r)
 				queryResult := query.Updates(&record)
 
 				if queryResult.Error != nil {
@@ -130,13 +166,56 @@ func Update[T httpio.DbModel](deps *config.Dependencies) httpio.HandlerWithError
 					return httpio.RecordNotFoundError{}
 				}
 
-				return nil
+				return auditChange[T](tx, r, models.AuditActionUpdate, uint(id), before)
 			}); err != nil {
 				return err
 			}
 
-			db.First(&record, id)
-			response := record.BuildResponse()
+			response, etag, err := findRepresentation[T](db, r)
+			if err != nil {
+				return err
+			}
+			w.Header().Set("ETag", etag)
+
+			if err := httpio.Encode(w, r, http.StatusOK, response); err != nil {
+				return err
+			}
+
+			return nil
+		})
+}
+
+// Patch applies JSON merge patch (RFC 7396), only the sent fields are changed and null clears the field
+func Patch[T httpio.DbModel](deps *config.Dependencies) httpio.HandlerWithError {
+	return httpio.HandlerWithError(
+		func(w http.ResponseWriter, r *http.Request) error {
+			db := deps.DB
+			id := uint(httpio.IntPathValue(r, "id"))
+
+			if err := db.Transaction(func(tx *gorm.DB) error {
+				if err := checkIfMatch[T](tx, r, id); err != nil {
+					return err
+				}
+
+				before, err := auditSnapshot[T](tx, id)
+				if err != nil {
+					return err
+				}
+
+				if _, err := mergePatch[T](tx, r, id); err != nil {
+					return err
+				}
+
+				return auditChange[T](tx, r, models.AuditActionUpdate, id, before)
+			}); err != nil {
+				return err
+			}
+
+			response, etag, err := findRepresentation[T](db, r)
+			if err != nil {
+				return err
+			}
+			w.Header().Set("ETag", etag)
 
 			if err := httpio.Encode(w, r, http.StatusOK, response); err != nil {
 				return err
@@ -152,10 +231,15 @@ func Delete[T httpio.DbModel](deps *config.Dependencies) httpio.HandlerWithError
 			db := deps.DB
 			var record T
 
-			query := record.DeleteQuery(db, r)
+			id := uint(httpio.IntPathValue(r, "id"))
 
 			if err := db.Transaction(func(tx *gorm.DB) error {
-				queryResult := query.Delete(&record)
+				before, err := auditSnapshot[T](tx, id)
+				if err != nil {
+					return err
+				}
+
+				queryResult := record.DeleteQuery(tx, r).Delete(&record)
 
 				if queryResult.Error != nil {
 					return queryResult.Error
@@ -165,7 +249,7 @@ func Delete[T httpio.DbModel](deps *config.Dependencies) httpio.HandlerWithError
 					return httpio.RecordNotFoundError{}
 				}
 
-				return nil
+				return auditChange[T](tx, r, models.AuditActionDelete, id, before)
 			}); err != nil {
 				return err
 			}
@@ -177,3 +261,186 @@ func Delete[T httpio.DbModel](deps *config.Dependencies) httpio.HandlerWithError
 			return nil
 		})
 }
+
+// Restore brings back soft deleted record, records which are not deleted are not found
+func Restore[T httpio.SoftDeletableModel](deps *config.Dependencies) httpio.HandlerWithError {
+	return httpio.HandlerWithError(
+		func(w http.ResponseWriter, r *http.Request) error {
+			db := deps.DB
+			var record T
+
+			id := uint(httpio.IntPathValue(r, "id"))
+
+			if err := db.Transaction(func(tx *gorm.DB) error {
+				before, err := auditSnapshot[T](tx, id)
+				if err != nil {
+					return err
+				}
+
+				queryResult := record.RestoreQuery(tx.Model(&record), r).Update("deleted_at", nil)
+
+				if queryResult.Error != nil {
+					return queryResult.Error
+				}
+
+				if queryResult.RowsAffected == 0 {
+					return httpio.RecordNotFoundError{}
+				}
+
+				return auditChange[T](tx, r, models.AuditActionRestore, id, before)
+			}); err != nil {
+				return err
+			}
+
+			db.First(&record, id)
+			response := record.BuildResponse()
+
+			if err := httpio.Encode(w, r, http.StatusOK, response); err != nil {
+				return err
+			}
+
+			return nil
+		})
+}
+
+// mergePatch applies the merge patch from the request to the stored record and writes the patched columns.
+// Validation runs on the merged record, the names of the written columns are returned
+func mergePatch[T httpio.DbModel](tx *gorm.DB, r *http.Request, id uint) ([]string, error) {
+	var current T
+	result := tx.Limit(1).Find(&current, id)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, httpio.RecordNotFoundError{}
+	}
+
+	patched, keys, err := httpio.DecodeMergePatchAndValidate(r, tx, current)
+	if err != nil {
+		return nil, err
+	}
+
+	columns, err := patchedColumns(tx, &patched, keys)
+	if err != nil {
+		return nil, err
+	}
+	if len(columns) == 0 {
+		return columns, nil
+	}
+
+	// the patched columns are selected explicitly, so zero values and nulls are written as well
+	queryResult := patched.UpdateQuery(tx.Model(&current), r).Select(columns).Updates(&patched)
+	if queryResult.Error != nil {
+		return nil, queryResult.Error
+	}
+	if queryResult.RowsAffected == 0 {
+		return nil, httpio.RecordNotFoundError{}
+	}
+
+	return columns, nil
+}
+
+// patchedColumns maps the JSON keys of the patch to the columns of the model, keys of associations,
+// unknown and read-only fields are skipped
+func patchedColumns(tx *gorm.DB, record any, keys []string) ([]string, error) {
+	statement := &gorm.Statement{DB: tx}
+	if err := statement.Parse(record); err != nil {
+		return nil, err
+	}
+
+	columns := []string{}
+	for _, field := range statement.Schema.Fields {
+		jsonName, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if field.DBName == "" || !field.Updatable || !slices.Contains(keys, jsonName) {
+			continue
+		}
+		if slices.Contains([]string{"id", "created_at", "updated_at", "deleted_at"}, field.DBName) {
+			continue
+		}
+
+		columns = append(columns, field.DBName)
+	}
+
+	return columns, nil
+}
+
+// findRepresentation loads the record the same way as Get does, returns its response and ETag of the response
+func findRepresentation[T httpio.DbModel](db *gorm.DB, r *http.Request) (any, string, error) {
+	var record T
+	if err := record.GetQuery(db, r).First(&record).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, "", httpio.RecordNotFoundError{}
+		}
+		return nil, "", err
+	}
+
+	response := record.BuildResponse()
+	etag, err := httpio.ResponseETag(response)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return response, etag, nil
+}
+
+// checkIfMatch rejects the update when If-Match doesn't match the current version of the record.
+// The row stays locked until the end of the transaction, so nobody can change it between the check and the update
+func checkIfMatch[T httpio.DbModel](tx *gorm.DB, r *http.Request, id uint) error {
+	if r.Header.Get("If-Match") == "" {
+		return nil
+	}
+
+	var current T
+	result := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Limit(1).Find(&current, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return httpio.RecordNotFoundError{}
+	}
+
+	_, etag, err := findRepresentation[T](tx, r)
+	if err != nil {
+		return err
+	}
+
+	if httpio.IfMatchFailed(r, etag) {
+		return httpio.PreconditionFailedError{}
+	}
+
+	return nil
+}
+
+// auditSnapshot returns the record as it is stored (also when soft deleted), nil for not audited models and missing records
+func auditSnapshot[T httpio.DbModel](tx *gorm.DB, id uint) (any, error) {
+	var record T
+	if _, ok := any(record).(httpio.AuditedModel); !ok {
+		return nil, nil
+	}
+
+	result := tx.Unscoped().Limit(1).Find(&record, id)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, nil
+	}
+
+	return record.BuildResponse(), nil
+}
+
+// auditChange records the change of audited model, before is the snapshot taken before the change
+func auditChange[T httpio.DbModel](tx *gorm.DB, r *http.Request, action string, id uint, before any) error {
+	var record T
+	audited, ok := any(record).(httpio.AuditedModel)
+	if !ok {
+		return nil
+	}
+
+	after, err := auditSnapshot[T](tx, id)
+	if err != nil {
+		return err
+	}
+
+	return models.RecordAudit(tx, r, action, audited.AuditResourceType(), id, before, after)
+}
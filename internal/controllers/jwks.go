@@ -0,0 +1,16 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/filipio/athletics-backend/pkg/config"
+	"github.com/filipio/athletics-backend/pkg/httpio"
+)
+
+// GetJWKS returns public keys which can be used to verify access tokens
+func GetJWKS(deps *config.Dependencies) httpio.HandlerWithError {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		w.Header().Set("Cache-Control", "public, max-age=300")
+		return httpio.Encode(w, r, http.StatusOK, deps.JwtKeys.JWKS())
+	}
+}
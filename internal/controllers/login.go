@@ -1,10 +1,14 @@
 package controllers
 
 import (
+	"errors"
+	"log/slog"
 	"net/http"
+	"strings"
 
-	"github.com/filipio/athletics-backend/pkg/config"
+	"github.com/filipio/athletics-backend/internal/email"
 	"github.com/filipio/athletics-backend/internal/models"
+	"github.com/filipio/athletics-backend/pkg/config"
 	"github.com/filipio/athletics-backend/pkg/httpio"
 	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
@@ -19,6 +23,7 @@ func (payload LoginPayload) Validate(db *gorm.DB) error {
 	return nil
 }
 
+// Login is throttled per account and per IP address, failed attempts above the limit lock the login with exponential backoff
 func Login(deps *config.Dependencies) httpio.HandlerWithError {
 	return httpio.HandlerWithError(
 		func(w http.ResponseWriter, r *http.Request) error {
@@ -29,27 +34,148 @@ func Login(deps *config.Dependencies) httpio.HandlerWithError {
 				return decodeErr
 			}
 
+			accountLimit, ipLimit, err := findUnlockedLoginAttemptLimits(db, r, loginPayload.Email)
+			if err != nil {
+				return err
+			}
+
 			var user models.User
 			db.First(&user, "email = ?", loginPayload.Email)
 
-			if user.GetID() == 0 {
+			if user.GetID() == 0 || bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(loginPayload.Password)) != nil {
+				if err := registerFailedLogin(deps, r, user, &accountLimit, &ipLimit); err != nil {
+					return err
+				}
 				return httpio.LoginError{}
 			}
 
-			if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(loginPayload.Password)); err != nil {
-				return httpio.LoginError{}
-			}
-
-			tokenPair, err := generateTokenPair(user, db, nil)
+			twoFactor, err := findConfirmedTwoFactor(db, user.ID)
 			if err != nil {
 				return err
 			}
 
-			if err := httpio.Encode(w, r, http.StatusOK, tokenPair); err != nil {
+			// with 2FA the counter is reset once the second factor is verified, otherwise codes could be guessed without limit
+			if twoFactor == nil {
+				if err := resetAccountLoginAttempts(db, accountLimit); err != nil {
+					return err
+				}
+			}
+
+			return completeLogin(deps, w, r, user)
+
+		})
+}
+
+// UnlockUserLogin removes login lockout and failed attempts of the user account
+func UnlockUserLogin(deps *config.Dependencies) httpio.HandlerWithError {
+	return httpio.HandlerWithError(
+		func(w http.ResponseWriter, r *http.Request) error {
+			db := deps.DB
+
+			var user models.User
+			if err := db.First(&user, httpio.IntPathValue(r, "id")).Error; err != nil {
+				if errors.Is(err, gorm.ErrRecordNotFound) {
+					return httpio.RecordNotFoundError{}
+				}
 				return err
 			}
 
-			return nil
+			err := db.Transaction(func(tx *gorm.DB) error {
+				if err := tx.Where("scope = ? AND identifier = ?", models.LoginAttemptScopeAccount, strings.ToLower(user.Email)).
+					Delete(&models.LoginAttemptLimit{}).Error; err != nil {
+					return err
+				}
+
+				return models.RecordAudit(tx, r, models.AuditActionUnlockLogin, models.AuditResourceUser, user.ID, nil, nil)
+			})
+			if err != nil {
+				return err
+			}
 
+			return httpio.Encode(w, r, http.StatusOK, httpio.AnyMap{
+				"message": "account unlocked",
+			})
 		})
 }
+
+// findUnlockedLoginAttemptLimits returns limits of the account and the IP address, or RateLimitError when any of them is locked
+func findUnlockedLoginAttemptLimits(db *gorm.DB, r *http.Request, email string) (models.LoginAttemptLimit, models.LoginAttemptLimit, error) {
+	accountLimit, err := findLoginAttemptLimit(db, models.LoginAttemptScopeAccount, strings.ToLower(email))
+	if err != nil {
+		return accountLimit, models.LoginAttemptLimit{}, err
+	}
+	ipLimit, err := findLoginAttemptLimit(db, models.LoginAttemptScopeIP, httpio.ClientIP(r))
+	if err != nil {
+		return accountLimit, ipLimit, err
+	}
+
+	for _, limit := range []models.LoginAttemptLimit{accountLimit, ipLimit} {
+		if limit.IsLocked() {
+			return accountLimit, ipLimit, httpio.RateLimitError{BlockedUntil: limit.LockedUntilString(), RetryAfterSeconds: limit.RetryAfterSeconds()}
+		}
+	}
+
+	return accountLimit, ipLimit, nil
+}
+
+// only the account counter is reset, otherwise an attacker could reset the IP counter with own account
+func resetAccountLoginAttempts(db *gorm.DB, accountLimit models.LoginAttemptLimit) error {
+	if accountLimit.ID == 0 {
+		return nil
+	}
+
+	return db.Delete(&accountLimit).Error
+}
+
+func findLoginAttemptLimit(db *gorm.DB, scope string, identifier string) (models.LoginAttemptLimit, error) {
+	limit := models.LoginAttemptLimit{Scope: scope, Identifier: identifier}
+	err := db.Where("scope = ? AND identifier = ?", scope, identifier).First(&limit).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return limit, err
+	}
+
+	return limit, nil
+}
+
+// registerFailedLogin counts the failure for the account (also for not existing ones, so responses don't reveal which accounts exist) and the IP address
+func registerFailedLogin(deps *config.Dependencies, r *http.Request, user models.User, accountLimit *models.LoginAttemptLimit, ipLimit *models.LoginAttemptLimit) error {
+	accountLocked := accountLimit.RegisterFailure()
+	ipLocked := ipLimit.RegisterFailure()
+
+	err := deps.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(accountLimit).Error; err != nil {
+			return err
+		}
+
+		return tx.Save(ipLimit).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	ipAddress := httpio.ClientIP(r)
+	if ipLocked {
+		slog.Warn("security event: login locked for ip address", "event", "login_ip_lockout", "ip_address", ipAddress)
+	}
+
+	if !accountLocked {
+		return nil
+	}
+
+	slog.Warn("security event: login locked for account", "event", "login_account_lockout", "email", accountLimit.Identifier, "ip_address", ipAddress)
+	if user.ID == 0 {
+		return nil
+	}
+
+	// notification is best-effort, the account is already locked
+	if err := deps.EmailSender.SendSecurityAlertEmail(r.Context(), email.SecurityAlertEmailParams{
+		To:        user.Email,
+		Alert:     "Your account has been temporarily locked after too many failed login attempts.",
+		IPAddress: ipAddress,
+		UserAgent: httpio.ClientUserAgent(r),
+	}); err != nil {
+		slog.Error("failed to send security alert email", "user_id", user.ID, "error", err)
+	}
+
+	return nil
+}
@@ -26,6 +26,10 @@ func Logout(deps *config.Dependencies) httpio.HandlerWithError {
 				return err
 			}
 
+			if _, err := r.Cookie(httpio.AccessTokenCookieName); err == nil {
+				httpio.ClearAuthCookies(w)
+			}
+
 			if err := httpio.Encode(w, r, http.StatusOK, httpio.AnyMap{
 				"message": "logged out successfully",
 			}); err != nil {
@@ -0,0 +1,141 @@
+package controllers
+
+import (
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/filipio/athletics-backend/internal/email"
+	"github.com/filipio/athletics-backend/internal/models"
+	"github.com/filipio/athletics-backend/pkg/config"
+	"github.com/filipio/athletics-backend/pkg/httpio"
+	"gorm.io/gorm"
+)
+
+type MagicLinkRequestPayload struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+func (payload MagicLinkRequestPayload) Validate(db *gorm.DB) error {
+	return nil
+}
+
+type MagicLinkConsumePayload struct {
+	Token string `json:"token" validate:"required"`
+}
+
+func (payload MagicLinkConsumePayload) Validate(db *gorm.DB) error {
+	return nil
+}
+
+// RequestMagicLink responds the same way whether the account exists or not, so it can't be used to enumerate users
+func RequestMagicLink(deps *config.Dependencies) httpio.HandlerWithError {
+	return httpio.HandlerWithError(
+		func(w http.ResponseWriter, r *http.Request) error {
+			db := deps.DB
+			payload, err := httpio.DecodeAndValidate[MagicLinkRequestPayload](r, db)
+			if err != nil {
+				return err
+			}
+
+			// the same address in another case shares the rate limit and finds the same user
+			normalizedEmail := strings.ToLower(payload.Email)
+
+			var rateLimit models.MagicLinkRateLimit
+			db.FirstOrCreate(&rateLimit, models.MagicLinkRateLimit{Email: normalizedEmail})
+
+			if !rateLimit.CanRequestMagicLink() {
+				return httpio.RateLimitError{BlockedUntil: rateLimit.BlockedUntilString(), RetryAfterSeconds: rateLimit.RetryAfterSeconds()}
+			}
+
+			var user models.User
+			db.Where("LOWER(email) = ?", normalizedEmail).First(&user)
+
+			var plainToken string
+			err = db.Transaction(func(tx *gorm.DB) error {
+				rateLimit.IncrementRequestCount()
+				if err := tx.Save(&rateLimit).Error; err != nil {
+					return err
+				}
+
+				if user.ID == 0 {
+					return nil
+				}
+
+				// only the most recently requested link is valid
+				if err := tx.Where("user_id = ? AND used_at IS NULL", user.ID).Delete(&models.MagicLinkToken{}).Error; err != nil {
+					return err
+				}
+
+				magicLinkToken := models.MagicLinkToken{UserID: user.ID}
+				plainToken = magicLinkToken.GenerateToken()
+
+				return tx.Create(&magicLinkToken).Error
+			})
+			if err != nil {
+				return err
+			}
+
+			if user.ID != 0 {
+				emailErr := deps.EmailSender.SendMagicLinkEmail(r.Context(), email.MagicLinkEmailParams{
+					To:         user.Email,
+					LoginToken: plainToken,
+				})
+
+				// response is the same as for not existing accounts, so failures don't reveal which accounts exist
+				if emailErr != nil {
+					slog.Error("failed to send magic link email", "user_id", user.ID, "error", emailErr)
+				}
+			}
+
+			return httpio.Encode(w, r, http.StatusOK, httpio.AnyMap{
+				"message": "If the account exists, login link has been sent.",
+			})
+		})
+}
+
+// ConsumeMagicLink logs the user in like Login does, so users with 2FA enabled still get a login challenge
+func ConsumeMagicLink(deps *config.Dependencies) httpio.HandlerWithError {
+	return httpio.HandlerWithError(
+		func(w http.ResponseWriter, r *http.Request) error {
+			db := deps.DB
+			payload, err := httpio.DecodeAndValidate[MagicLinkConsumePayload](r, db)
+			if err != nil {
+				return err
+			}
+
+			var magicLinkToken models.MagicLinkToken
+			db.Where("token_hash = ?", models.HashLookupToken(payload.Token)).First(&magicLinkToken)
+
+			if magicLinkToken.ID == 0 || !magicLinkToken.IsValid() {
+				return httpio.InvalidMagicLinkTokenError{}
+			}
+
+			var user models.User
+			db.First(&user, magicLinkToken.UserID)
+			if user.ID == 0 {
+				return httpio.InvalidMagicLinkTokenError{}
+			}
+
+			err = db.Transaction(func(tx *gorm.DB) error {
+				// marking token as used is conditional, so two concurrent requests can't both use the same link
+				markResult := tx.Model(&magicLinkToken).
+					Where("used_at IS NULL").
+					Update("used_at", time.Now())
+				if markResult.Error != nil {
+					return markResult.Error
+				}
+				if markResult.RowsAffected == 0 {
+					return httpio.InvalidMagicLinkTokenError{}
+				}
+
+				return tx.Where("email = ?", strings.ToLower(user.Email)).Delete(&models.MagicLinkRateLimit{}).Error
+			})
+			if err != nil {
+				return err
+			}
+
+			return completeLogin(deps, w, r, user)
+		})
+}
@@ -0,0 +1,295 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/filipio/athletics-backend/internal/models"
+	"github.com/filipio/athletics-backend/pkg/config"
+	"github.com/filipio/athletics-backend/pkg/httpio"
+	"gorm.io/gorm"
+)
+
+type ModerationPayload struct {
+	Reason string `json:"reason" validate:"required,max=500"`
+}
+
+func (payload ModerationPayload) Validate(db *gorm.DB) error {
+	return nil
+}
+
+type SuspendUserPayload struct {
+	Reason string `json:"reason" validate:"required,max=500"`
+	// user is banned when the end of the suspension is not given
+	Until *time.Time `json:"until"`
+}
+
+func (payload SuspendUserPayload) Validate(db *gorm.DB) error {
+	if payload.Until != nil && payload.Until.Before(time.Now()) {
+		return httpio.AppValidationError{
+			FieldPath: "until",
+			AppError:  httpio.AppError{Message: "must be in the future"},
+		}
+	}
+
+	return nil
+}
+
+func GrantUserRole(deps *config.Dependencies) httpio.HandlerWithError {
+	return httpio.HandlerWithError(
+		func(w http.ResponseWriter, r *http.Request) error {
+			db := deps.DB
+			payload, err := httpio.DecodeAndValidate[ModerationPayload](r, db)
+			if err != nil {
+				return err
+			}
+
+			user, role, err := findUserAndRole(db, r)
+			if err != nil {
+				return err
+			}
+
+			if user.HasAnyRole(role.Name) {
+				return httpio.AppValidationError{
+					FieldPath: "role",
+					AppError:  httpio.AppError{Message: "is already granted"},
+				}
+			}
+
+			before := user.BuildResponse()
+			err = db.Transaction(func(tx *gorm.DB) error {
+				if err := tx.Model(&user).Association("Roles").Append(&role); err != nil {
+					return err
+				}
+
+				return recordModerationAction(tx, r, user.ID, before, models.ModerationActionGrantRole, payload.Reason, role.Name)
+			})
+			if err != nil {
+				return err
+			}
+
+			return encodeModeratedUser(db, w, r, user.ID)
+		})
+}
+
+func RevokeUserRole(deps *config.Dependencies) httpio.HandlerWithError {
+	return httpio.HandlerWithError(
+		func(w http.ResponseWriter, r *http.Request) error {
+			db := deps.DB
+			payload, err := httpio.DecodeAndValidate[ModerationPayload](r, db)
+			if err != nil {
+				return err
+			}
+
+			user, role, err := findUserAndRole(db, r)
+			if err != nil {
+				return err
+			}
+
+			if !user.HasAnyRole(role.Name) {
+				return httpio.AppValidationError{
+					FieldPath: "role",
+					AppError:  httpio.AppError{Message: "is not granted"},
+				}
+			}
+
+			before := user.BuildResponse()
+			err = db.Transaction(func(tx *gorm.DB) error {
+				if err := tx.Model(&user).Association("Roles").Delete(&role); err != nil {
+					return err
+				}
+
+				if err := ensureRolesManagerExists(tx, "role"); err != nil {
+					return err
+				}
+
+				return recordModerationAction(tx, r, user.ID, before, models.ModerationActionRevokeRole, payload.Reason, role.Name)
+			})
+			if err != nil {
+				return err
+			}
+
+			return encodeModeratedUser(db, w, r, user.ID)
+		})
+}
+
+// SuspendUser blocks the user until the given time, or bans when no time is given, all sessions are revoked
+func SuspendUser(deps *config.Dependencies) httpio.HandlerWithError {
+	return httpio.HandlerWithError(
+		func(w http.ResponseWriter, r *http.Request) error {
+			db := deps.DB
+			payload, err := httpio.DecodeAndValidate[SuspendUserPayload](r, db)
+			if err != nil {
+				return err
+			}
+
+			user, err := findModeratedUser(db, r)
+			if err != nil {
+				return err
+			}
+
+			currentUser := r.Context().Value(httpio.UserContextKey).(models.User)
+			if user.ID == currentUser.ID {
+				return httpio.AppValidationError{
+					FieldPath: "id",
+					AppError:  httpio.AppError{Message: "can't suspend yourself"},
+				}
+			}
+
+			details := "permanent"
+			if payload.Until != nil {
+				details = payload.Until.UTC().Format(time.RFC3339)
+			}
+
+			before := user.BuildResponse()
+			err = db.Transaction(func(tx *gorm.DB) error {
+				if err := tx.Model(&user).Updates(map[string]any{
+					"suspended_at":    time.Now(),
+					"suspended_until": payload.Until,
+				}).Error; err != nil {
+					return err
+				}
+
+				if err := revokeUserSessions(tx, user.ID); err != nil {
+					return err
+				}
+
+				return recordModerationAction(tx, r, user.ID, before, models.ModerationActionSuspend, payload.Reason, details)
+			})
+			if err != nil {
+				return err
+			}
+
+			return encodeModeratedUser(db, w, r, user.ID)
+		})
+}
+
+func UnsuspendUser(deps *config.Dependencies) httpio.HandlerWithError {
+	return httpio.HandlerWithError(
+		func(w http.ResponseWriter, r *http.Request) error {
+			db := deps.DB
+			payload, err := httpio.DecodeAndValidate[ModerationPayload](r, db)
+			if err != nil {
+				return err
+			}
+
+			user, err := findModeratedUser(db, r)
+			if err != nil {
+				return err
+			}
+
+			if !user.IsSuspended() {
+				return httpio.AppValidationError{
+					FieldPath: "id",
+					AppError:  httpio.AppError{Message: "user is not suspended"},
+				}
+			}
+
+			before := user.BuildResponse()
+			err = db.Transaction(func(tx *gorm.DB) error {
+				if err := tx.Model(&user).Updates(map[string]any{
+					"suspended_at":    nil,
+					"suspended_until": nil,
+				}).Error; err != nil {
+					return err
+				}
+
+				return recordModerationAction(tx, r, user.ID, before, models.ModerationActionUnsuspend, payload.Reason, "")
+			})
+			if err != nil {
+				return err
+			}
+
+			return encodeModeratedUser(db, w, r, user.ID)
+		})
+}
+
+// ResetUsername replaces offensive username with a generated one, previous username is kept in the moderation log
+func ResetUsername(deps *config.Dependencies) httpio.HandlerWithError {
+	return httpio.HandlerWithError(
+		func(w http.ResponseWriter, r *http.Request) error {
+			db := deps.DB
+			payload, err := httpio.DecodeAndValidate[ModerationPayload](r, db)
+			if err != nil {
+				return err
+			}
+
+			user, err := findModeratedUser(db, r)
+			if err != nil {
+				return err
+			}
+
+			before := user.BuildResponse()
+			err = db.Transaction(func(tx *gorm.DB) error {
+				if err := tx.Model(&user).Update("username", fmt.Sprintf("user_%d", user.ID)).Error; err != nil {
+					return err
+				}
+
+				return recordModerationAction(tx, r, user.ID, before, models.ModerationActionResetUsername, payload.Reason, user.Username)
+			})
+			if err != nil {
+				return err
+			}
+
+			return encodeModeratedUser(db, w, r, user.ID)
+		})
+}
+
+func findModeratedUser(db *gorm.DB, r *http.Request) (models.User, error) {
+	var user models.User
+	if err := db.Preload("Roles").First(&user, httpio.IntPathValue(r, "id")).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return user, httpio.RecordNotFoundError{}
+		}
+		return user, err
+	}
+
+	return user, nil
+}
+
+func findUserAndRole(db *gorm.DB, r *http.Request) (models.User, models.Role, error) {
+	var role models.Role
+	user, err := findModeratedUser(db, r)
+	if err != nil {
+		return user, role, err
+	}
+
+	if err := db.Where("name = ?", r.PathValue("role")).First(&role).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return user, role, httpio.RecordNotFoundError{}
+		}
+		return user, role, err
+	}
+
+	return user, role, nil
+}
+
+// recordModerationAction writes the moderation log entry and the audit log entry, before is the response of the user
+// before the action
+func recordModerationAction(tx *gorm.DB, r *http.Request, targetUserID uint, before any, action string, reason string, details string) error {
+	currentUser := r.Context().Value(httpio.UserContextKey).(models.User)
+	if err := tx.Create(&models.ModerationAction{
+		ActorID:      &currentUser.ID,
+		TargetUserID: targetUserID,
+		Action:       action,
+		Reason:       reason,
+		Details:      details,
+	}).Error; err != nil {
+		return err
+	}
+
+	var user models.User
+	if err := tx.Preload("Roles").First(&user, targetUserID).Error; err != nil {
+		return err
+	}
+
+	return models.RecordAudit(tx, r, action, models.AuditResourceUser, targetUserID, before, user.BuildResponse())
+}
+
+func encodeModeratedUser(db *gorm.DB, w http.ResponseWriter, r *http.Request, userID uint) error {
+	var user models.User
+	db.Preload("Roles.Permissions").First(&user, userID)
+	return httpio.Encode(w, r, http.StatusOK, user.BuildResponse())
+}
@@ -0,0 +1,226 @@
+package controllers
+
+import (
+	"crypto/rand"
+	"errors"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/filipio/athletics-backend/internal/models"
+	"github.com/filipio/athletics-backend/internal/oidc"
+	"github.com/filipio/athletics-backend/pkg/config"
+	"github.com/filipio/athletics-backend/pkg/httpio"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+type OidcCallbackPayload struct {
+	Code  string `json:"code" validate:"required"`
+	State string `json:"state" validate:"required"`
+}
+
+func (payload OidcCallbackPayload) Validate(db *gorm.DB) error {
+	return nil
+}
+
+type OidcAuthorizationResponse struct {
+	AuthorizationURL string `json:"authorization_url"`
+}
+
+type OidcProvidersResponse struct {
+	Providers []string `json:"providers"`
+}
+
+func GetOidcProviders(deps *config.Dependencies) httpio.HandlerWithError {
+	return httpio.HandlerWithError(
+		func(w http.ResponseWriter, r *http.Request) error {
+			providers := make([]string, 0, len(deps.OidcProviders))
+			for name := range deps.OidcProviders {
+				providers = append(providers, name)
+			}
+			slices.Sort(providers)
+
+			return httpio.Encode(w, r, http.StatusOK, OidcProvidersResponse{Providers: providers})
+		})
+}
+
+// StartOidcLogin returns URL of the provider to which the user has to be redirected.
+// State, nonce and PKCE code verifier are kept in the database until the callback.
+func StartOidcLogin(deps *config.Dependencies) httpio.HandlerWithError {
+	return httpio.HandlerWithError(
+		func(w http.ResponseWriter, r *http.Request) error {
+			provider, err := findOidcProvider(deps, r)
+			if err != nil {
+				return err
+			}
+
+			authRequest, err := provider.NewAuthorizationRequest(r.Context())
+			if err != nil {
+				return err
+			}
+
+			loginRequest := models.OidcLoginRequest{
+				Provider:     provider.Name(),
+				Nonce:        authRequest.Nonce,
+				CodeVerifier: authRequest.CodeVerifier,
+			}
+			loginRequest.SetState(authRequest.State)
+
+			if err := deps.DB.Create(&loginRequest).Error; err != nil {
+				return err
+			}
+
+			return httpio.Encode(w, r, http.StatusOK, OidcAuthorizationResponse{AuthorizationURL: authRequest.URL})
+		})
+}
+
+// CompleteOidcLogin exchanges authorization code received by the client, signs in the linked user
+// (linking or creating the account by verified email on first login) and returns the token pair or login challenge
+func CompleteOidcLogin(deps *config.Dependencies) httpio.HandlerWithError {
+	return httpio.HandlerWithError(
+		func(w http.ResponseWriter, r *http.Request) error {
+			db := deps.DB
+			provider, err := findOidcProvider(deps, r)
+			if err != nil {
+				return err
+			}
+
+			payload, err := httpio.DecodeAndValidate[OidcCallbackPayload](r, db)
+			if err != nil {
+				return err
+			}
+
+			loginRequest, err := consumeOidcLoginRequest(db, provider.Name(), payload.State)
+			if err != nil {
+				return err
+			}
+
+			identity, err := provider.Exchange(r.Context(), payload.Code, loginRequest.CodeVerifier, loginRequest.Nonce)
+			if err != nil {
+				slog.Warn("oidc login failed", "provider", provider.Name(), "error", err)
+				return httpio.OidcLoginError{AppError: httpio.AppError{Message: err.Error()}}
+			}
+
+			var user models.User
+			err = db.Transaction(func(tx *gorm.DB) error {
+				user, err = findOrLinkOidcUser(tx, provider.Name(), identity)
+				return err
+			})
+			if err != nil {
+				return err
+			}
+
+			return completeLogin(deps, w, r, user)
+		})
+}
+
+func findOidcProvider(deps *config.Dependencies, r *http.Request) (*oidc.Provider, error) {
+	provider, ok := deps.OidcProviders[r.PathValue("provider")]
+	if !ok {
+		return nil, httpio.OidcProviderNotFoundError{}
+	}
+
+	return provider, nil
+}
+
+// consumeOidcLoginRequest deletes the login request, so the same state can't be used twice
+func consumeOidcLoginRequest(db *gorm.DB, providerName string, state string) (models.OidcLoginRequest, error) {
+	var loginRequest models.OidcLoginRequest
+	if err := db.Where("state_hash = ? AND provider = ?", models.HashLookupToken(state), providerName).First(&loginRequest).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return loginRequest, httpio.InvalidOidcStateError{}
+		}
+		return loginRequest, err
+	}
+
+	result := db.Where("id = ?", loginRequest.ID).Delete(&models.OidcLoginRequest{})
+	if result.Error != nil {
+		return loginRequest, result.Error
+	}
+
+	if result.RowsAffected == 0 || loginRequest.IsExpired() {
+		return loginRequest, httpio.InvalidOidcStateError{}
+	}
+
+	return loginRequest, nil
+}
+
+func findOrLinkOidcUser(tx *gorm.DB, providerName string, identity oidc.Identity) (models.User, error) {
+	var user models.User
+
+	var userIdentity models.UserIdentity
+	err := tx.Where("provider = ? AND subject = ?", providerName, identity.Subject).First(&userIdentity).Error
+	if err == nil {
+		return user, tx.First(&user, userIdentity.UserID).Error
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return user, err
+	}
+
+	// identities are linked only by email which the provider has verified, otherwise anyone could take over the account
+	if identity.Email == "" || !identity.EmailVerified {
+		return user, httpio.OidcEmailNotVerifiedError{}
+	}
+
+	tx.Where("LOWER(email) = LOWER(?)", identity.Email).First(&user)
+	if user.ID == 0 {
+		createdUser, err := createOidcUser(tx, identity)
+		if err != nil {
+			return user, err
+		}
+		user = createdUser
+	}
+
+	userIdentity = models.UserIdentity{
+		UserID:   user.ID,
+		Provider: providerName,
+		Subject:  identity.Subject,
+		Email:    identity.Email,
+	}
+	if err := tx.Create(&userIdentity).Error; err != nil {
+		return user, err
+	}
+
+	return user, nil
+}
+
+// createOidcUser creates account with random password, it can be set later using password reset
+func createOidcUser(tx *gorm.DB, identity oidc.Identity) (models.User, error) {
+	candidate := identity.Name
+	if candidate == "" {
+		candidate, _, _ = strings.Cut(identity.Email, "@")
+	}
+
+	username, err := models.AvailableUsername(tx, candidate)
+	if err != nil {
+		return models.User{}, err
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(rand.Text()), 10)
+	if err != nil {
+		return models.User{}, err
+	}
+
+	user := models.User{
+		Email:               identity.Email,
+		Username:            username,
+		Password:            string(hashedPassword),
+		SkipPasswordHashing: true,
+	}
+	if err := tx.Create(&user).Error; err != nil {
+		return user, err
+	}
+
+	var role models.Role
+	if err := tx.Where("name = ?", httpio.UserRole).First(&role).Error; err != nil {
+		return user, err
+	}
+
+	if err := tx.Model(&user).Association("Roles").Append(&role); err != nil {
+		return user, err
+	}
+
+	return user, nil
+}
@@ -0,0 +1,161 @@
+package controllers
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/filipio/athletics-backend/internal/email"
+	"github.com/filipio/athletics-backend/internal/models"
+	"github.com/filipio/athletics-backend/pkg/config"
+	"github.com/filipio/athletics-backend/pkg/httpio"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+type PasswordResetRequestPayload struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+func (payload PasswordResetRequestPayload) Validate(db *gorm.DB) error {
+	return nil
+}
+
+type PasswordResetConfirmPayload struct {
+	Token    string `json:"token" validate:"required"`
+	Password string `json:"password" validate:"required,min=6"`
+}
+
+func (payload PasswordResetConfirmPayload) Validate(db *gorm.DB) error {
+	return nil
+}
+
+// RequestPasswordReset responds the same way whether the account exists or not, so it can't be used to enumerate users
+func RequestPasswordReset(deps *config.Dependencies) httpio.HandlerWithError {
+	return httpio.HandlerWithError(
+		func(w http.ResponseWriter, r *http.Request) error {
+			db := deps.DB
+			payload, err := httpio.DecodeAndValidate[PasswordResetRequestPayload](r, db)
+			if err != nil {
+				return err
+			}
+
+			var rateLimit models.PasswordResetRateLimit
+			db.FirstOrCreate(&rateLimit, models.PasswordResetRateLimit{Email: payload.Email})
+
+			if !rateLimit.CanRequestPasswordReset() {
+				return httpio.RateLimitError{BlockedUntil: rateLimit.BlockedUntilString(), RetryAfterSeconds: rateLimit.RetryAfterSeconds()}
+			}
+
+			var user models.User
+			db.Where("email = ?", payload.Email).First(&user)
+
+			var plainToken string
+			err = db.Transaction(func(tx *gorm.DB) error {
+				rateLimit.IncrementRequestCount()
+				if err := tx.Save(&rateLimit).Error; err != nil {
+					return err
+				}
+
+				if user.ID == 0 {
+					return nil
+				}
+
+				// only the most recently requested token is valid
+				if err := tx.Where("user_id = ? AND used_at IS NULL", user.ID).Delete(&models.PasswordResetToken{}).Error; err != nil {
+					return err
+				}
+
+				resetToken := models.PasswordResetToken{UserID: user.ID}
+				plainToken = resetToken.GenerateToken()
+
+				return tx.Create(&resetToken).Error
+			})
+			if err != nil {
+				return err
+			}
+
+			if user.ID != 0 {
+				emailErr := deps.EmailSender.SendPasswordResetEmail(r.Context(), email.PasswordResetEmailParams{
+					To:         user.Email,
+					ResetToken: plainToken,
+				})
+
+				// response is the same as for not existing accounts, so failures don't reveal which accounts exist
+				if emailErr != nil {
+					slog.Error("failed to send password reset email", "user_id", user.ID, "error", emailErr)
+				}
+			}
+
+			if err := httpio.Encode(w, r, http.StatusOK, httpio.AnyMap{
+				"message": "If the account exists, password reset email has been sent.",
+			}); err != nil {
+				return err
+			}
+
+			return nil
+		})
+}
+
+func ConfirmPasswordReset(deps *config.Dependencies) httpio.HandlerWithError {
+	return httpio.HandlerWithError(
+		func(w http.ResponseWriter, r *http.Request) error {
+			db := deps.DB
+			payload, err := httpio.DecodeAndValidate[PasswordResetConfirmPayload](r, db)
+			if err != nil {
+				return err
+			}
+
+			var resetToken models.PasswordResetToken
+			db.Where("token_hash = ?", models.HashLookupToken(payload.Token)).First(&resetToken)
+
+			if resetToken.ID == 0 || !resetToken.IsValid() {
+				return httpio.InvalidPasswordResetTokenError{}
+			}
+
+			var user models.User
+			db.First(&user, resetToken.UserID)
+			if user.ID == 0 {
+				return httpio.InvalidPasswordResetTokenError{}
+			}
+
+			hashedPassword, err := bcrypt.GenerateFromPassword([]byte(payload.Password), 10)
+			if err != nil {
+				return err
+			}
+
+			err = db.Transaction(func(tx *gorm.DB) error {
+				// marking token as used is conditional, so two concurrent requests can't both use the same token
+				markResult := tx.Model(&resetToken).
+					Where("used_at IS NULL").
+					Update("used_at", time.Now())
+				if markResult.Error != nil {
+					return markResult.Error
+				}
+				if markResult.RowsAffected == 0 {
+					return httpio.InvalidPasswordResetTokenError{}
+				}
+
+				if err := tx.Model(&user).Update("password", string(hashedPassword)).Error; err != nil {
+					return err
+				}
+
+				if err := revokeUserSessions(tx, user.ID); err != nil {
+					return err
+				}
+
+				return tx.Where("email = ?", user.Email).Delete(&models.PasswordResetRateLimit{}).Error
+			})
+			if err != nil {
+				return err
+			}
+
+			if err := httpio.Encode(w, r, http.StatusOK, httpio.AnyMap{
+				"message": "password has been reset successfully",
+			}); err != nil {
+				return err
+			}
+
+			return nil
+		})
+}
@@ -0,0 +1,118 @@
+package controllers
+
+import (
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/filipio/athletics-backend/internal/models"
+	"github.com/filipio/athletics-backend/pkg/config"
+	"github.com/filipio/athletics-backend/pkg/httpio"
+	"gorm.io/gorm"
+)
+
+type PersonalAccessTokenPayload struct {
+	Name      string    `json:"name" validate:"required,max=100"`
+	Scopes    []string  `json:"scopes" validate:"required"`
+	ExpiresAt time.Time `json:"expires_at" validate:"required"`
+}
+
+func (payload PersonalAccessTokenPayload) Validate(db *gorm.DB) error {
+	for _, scope := range payload.Scopes {
+		if !slices.Contains(httpio.AllPermissions, scope) {
+			return httpio.AppValidationError{
+				FieldPath: "scopes",
+				AppError:  httpio.AppError{Message: "contains unknown permission " + scope},
+			}
+		}
+	}
+
+	if payload.ExpiresAt.Before(time.Now()) {
+		return httpio.AppValidationError{
+			FieldPath: "expires_at",
+			AppError:  httpio.AppError{Message: "must be in the future"},
+		}
+	}
+
+	if payload.ExpiresAt.After(time.Now().Add(models.MaxPersonalAccessTokenLifetime)) {
+		return httpio.AppValidationError{
+			FieldPath: "expires_at",
+			AppError:  httpio.AppError{Message: "must be within a year"},
+		}
+	}
+
+	return nil
+}
+
+// CreatePersonalAccessToken returns the plain token only in this response, only its hash is stored
+func CreatePersonalAccessToken(deps *config.Dependencies) httpio.HandlerWithError {
+	return httpio.HandlerWithError(
+		func(w http.ResponseWriter, r *http.Request) error {
+			db := deps.DB
+			payload, err := httpio.DecodeAndValidate[PersonalAccessTokenPayload](r, db)
+			if err != nil {
+				return err
+			}
+
+			currentUser := r.Context().Value(httpio.UserContextKey).(models.User)
+			for _, scope := range payload.Scopes {
+				if !currentUser.HasPermission(scope) {
+					return httpio.AppValidationError{
+						FieldPath: "scopes",
+						AppError:  httpio.AppError{Message: "contains permission you don't have: " + scope},
+					}
+				}
+			}
+
+			var activeCount int64
+			if err := db.Model(&models.PersonalAccessToken{}).
+				Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", currentUser.ID, time.Now()).
+				Count(&activeCount).Error; err != nil {
+				return err
+			}
+			if activeCount >= models.MaxPersonalAccessTokensPerUser {
+				return httpio.AppValidationError{
+					FieldPath: "name",
+					AppError:  httpio.AppError{Message: "too many active tokens, revoke some of them first"},
+				}
+			}
+
+			personalAccessToken := models.PersonalAccessToken{
+				UserID:    currentUser.ID,
+				Name:      payload.Name,
+				Scopes:    payload.Scopes,
+				ExpiresAt: payload.ExpiresAt,
+			}
+			plainToken := personalAccessToken.GenerateToken()
+			if err := db.Create(&personalAccessToken).Error; err != nil {
+				return err
+			}
+
+			response := personalAccessToken.BuildResponse().(models.PersonalAccessTokenResponse)
+			response.Token = plainToken
+
+			return httpio.Encode(w, r, http.StatusCreated, response)
+		})
+}
+
+func RevokePersonalAccessToken(deps *config.Dependencies) httpio.HandlerWithError {
+	return httpio.HandlerWithError(
+		func(w http.ResponseWriter, r *http.Request) error {
+			currentUser := r.Context().Value(httpio.UserContextKey).(models.User)
+
+			result := deps.DB.Model(&models.PersonalAccessToken{}).
+				Where("id = ? AND user_id = ? AND revoked_at IS NULL", httpio.IntPathValue(r, "id"), currentUser.ID).
+				Update("revoked_at", time.Now())
+			if result.Error != nil {
+				return result.Error
+			}
+
+			if result.RowsAffected == 0 {
+				return httpio.RecordNotFoundError{}
+			}
+
+			return httpio.Encode(w, r, http.StatusOK, httpio.AnyMap{
+				"message": "token revoked successfully",
+			})
+		})
+}
@@ -0,0 +1,225 @@
+package controllers
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/filipio/athletics-backend/internal/models"
+	"github.com/filipio/athletics-backend/pkg/config"
+	"github.com/filipio/athletics-backend/pkg/httpio"
+	"github.com/filipio/athletics-backend/pkg/imageutil"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const MaxAvatarUploadBytes = 5 << 20
+
+type ProfilePayload struct {
+	DisplayName            string  `json:"display_name" validate:"max=50"`
+	Country                *string `json:"country" validate:"omitempty,iso3166_1_alpha2"`
+	Bio                    string  `json:"bio" validate:"max=500"`
+	FavouriteDisciplineIDs []uint  `json:"favourite_discipline_ids" validate:"max=10,dive,id_of=discipline"`
+	FavouriteAthleteIDs    []uint  `json:"favourite_athlete_ids" validate:"max=10,dive,id_of=athlete"`
+}
+
+func (payload ProfilePayload) Validate(db *gorm.DB) error {
+	if models.ContainsProfanity(payload.DisplayName) {
+		return httpio.AppValidationError{
+			FieldPath: "display_name",
+			AppError:  httpio.AppError{Message: "is not allowed"},
+		}
+	}
+
+	return nil
+}
+
+// GetUserProfile is public, users who never edited their profile get the empty one
+func GetUserProfile(deps *config.Dependencies) httpio.HandlerWithError {
+	return httpio.HandlerWithError(
+		func(w http.ResponseWriter, r *http.Request) error {
+			return encodeProfile(deps, w, r, uint(httpio.IntPathValue(r, "id")))
+		})
+}
+
+func GetMyProfile(deps *config.Dependencies) httpio.HandlerWithError {
+	return httpio.HandlerWithError(
+		func(w http.ResponseWriter, r *http.Request) error {
+			currentUser := r.Context().Value(httpio.UserContextKey).(models.User)
+			return encodeProfile(deps, w, r, currentUser.ID)
+		})
+}
+
+func UpdateProfile(deps *config.Dependencies) httpio.HandlerWithError {
+	return httpio.HandlerWithError(
+		func(w http.ResponseWriter, r *http.Request) error {
+			db := deps.DB
+			payload, err := httpio.DecodeAndValidate[ProfilePayload](r, db)
+			if err != nil {
+				return err
+			}
+
+			currentUser := r.Context().Value(httpio.UserContextKey).(models.User)
+			err = db.Transaction(func(tx *gorm.DB) error {
+				profile, err := findOrInitProfile(tx, currentUser.ID)
+				if err != nil {
+					return err
+				}
+
+				profile.DisplayName = payload.DisplayName
+				profile.Country = payload.Country
+				profile.Bio = payload.Bio
+				if err := tx.Omit(clause.Associations).Save(&profile).Error; err != nil {
+					return err
+				}
+
+				disciplines := []models.Discipline{}
+				if len(payload.FavouriteDisciplineIDs) > 0 {
+					if err := tx.Find(&disciplines, payload.FavouriteDisciplineIDs).Error; err != nil {
+						return err
+					}
+				}
+				if err := tx.Model(&profile).Association("FavouriteDisciplines").Replace(disciplines); err != nil {
+					return err
+				}
+
+				athletes := []models.Athlete{}
+				if len(payload.FavouriteAthleteIDs) > 0 {
+					if err := tx.Find(&athletes, payload.FavouriteAthleteIDs).Error; err != nil {
+						return err
+					}
+				}
+				return tx.Model(&profile).Association("FavouriteAthletes").Replace(athletes)
+			})
+			if err != nil {
+				return err
+			}
+
+			return encodeProfile(deps, w, r, currentUser.ID)
+		})
+}
+
+// UploadAvatar expects multipart form with "avatar" file, the image is cropped to square and resized before it is stored
+func UploadAvatar(deps *config.Dependencies) httpio.HandlerWithError {
+	return httpio.HandlerWithError(
+		func(w http.ResponseWriter, r *http.Request) error {
+			db := deps.DB
+			currentUser := r.Context().Value(httpio.UserContextKey).(models.User)
+
+			// some space is left for the multipart envelope
+			r.Body = http.MaxBytesReader(w, r.Body, MaxAvatarUploadBytes+64<<10)
+			file, header, err := r.FormFile("avatar")
+			if err != nil {
+				return avatarError("is required and must be at most 5 MB")
+			}
+			defer file.Close()
+
+			if header.Size > MaxAvatarUploadBytes {
+				return avatarError("must be at most 5 MB")
+			}
+
+			avatar, err := imageutil.ResizeAvatar(file)
+			if err != nil {
+				if errors.Is(err, imageutil.ErrUnsupportedImage) {
+					return avatarError("must be a JPEG, PNG, GIF or WebP image")
+				}
+				return err
+			}
+
+			// every upload gets a new key, so cached old avatar is never served under the new url
+			key := fmt.Sprintf("avatars/%d/%s.jpg", currentUser.ID, rand.Text())
+			if err := deps.Storage.Save(r.Context(), key, bytes.NewReader(avatar), int64(len(avatar)), "image/jpeg"); err != nil {
+				return err
+			}
+
+			previousKey, err := replaceAvatarKey(db, currentUser.ID, &key)
+			if err != nil {
+				deleteAvatarFile(deps, r, &key)
+				return err
+			}
+			deleteAvatarFile(deps, r, previousKey)
+
+			return encodeProfile(deps, w, r, currentUser.ID)
+		})
+}
+
+func DeleteAvatar(deps *config.Dependencies) httpio.HandlerWithError {
+	return httpio.HandlerWithError(
+		func(w http.ResponseWriter, r *http.Request) error {
+			currentUser := r.Context().Value(httpio.UserContextKey).(models.User)
+
+			previousKey, err := replaceAvatarKey(deps.DB, currentUser.ID, nil)
+			if err != nil {
+				return err
+			}
+			deleteAvatarFile(deps, r, previousKey)
+
+			return encodeProfile(deps, w, r, currentUser.ID)
+		})
+}
+
+func encodeProfile(deps *config.Dependencies, w http.ResponseWriter, r *http.Request, userID uint) error {
+	db := deps.DB
+
+	var user models.User
+	if err := db.First(&user, userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return httpio.RecordNotFoundError{}
+		}
+		return err
+	}
+
+	var profile models.UserProfile
+	if err := db.Preload("FavouriteDisciplines").Preload("FavouriteAthletes.Disciplines").
+		Where("user_id = ?", userID).Limit(1).Find(&profile).Error; err != nil {
+		return err
+	}
+
+	return httpio.Encode(w, r, http.StatusOK, profile.BuildProfileResponse(user, deps.Storage.URL))
+}
+
+func findOrInitProfile(tx *gorm.DB, userID uint) (models.UserProfile, error) {
+	profile := models.UserProfile{UserID: userID}
+	err := tx.Where("user_id = ?", userID).Limit(1).Find(&profile).Error
+
+	return profile, err
+}
+
+// replaceAvatarKey sets the new avatar of the user and returns the previous one, which should be removed from the storage
+func replaceAvatarKey(db *gorm.DB, userID uint, key *string) (*string, error) {
+	var previousKey *string
+	err := db.Transaction(func(tx *gorm.DB) error {
+		profile, err := findOrInitProfile(tx.Clauses(clause.Locking{Strength: "UPDATE"}), userID)
+		if err != nil {
+			return err
+		}
+
+		previousKey = profile.AvatarKey
+		profile.AvatarKey = key
+
+		return tx.Omit(clause.Associations).Save(&profile).Error
+	})
+
+	return previousKey, err
+}
+
+// deleteAvatarFile only logs failures, the file left in the storage is not referenced anymore
+func deleteAvatarFile(deps *config.Dependencies, r *http.Request, key *string) {
+	if key == nil {
+		return
+	}
+
+	if err := deps.Storage.Delete(r.Context(), *key); err != nil {
+		slog.Error("error deleting avatar from storage", "key", *key, "error", err)
+	}
+}
+
+func avatarError(message string) httpio.AppValidationError {
+	return httpio.AppValidationError{
+		FieldPath: "avatar",
+		AppError:  httpio.AppError{Message: message},
+	}
+}
@@ -2,6 +2,7 @@ package controllers
 
 import (
 	"net/http"
+	"slices"
 
 	"github.com/filipio/athletics-backend/pkg/config"
 	"github.com/filipio/athletics-backend/internal/models"
@@ -30,7 +31,7 @@ func CreateQuestion(deps *config.Dependencies) httpio.HandlerWithError {
 			if err := tx.Create(&question).Error; err != nil {
 				return err
 			}
-			return nil
+			return auditChange[models.Question](tx, r, models.AuditActionCreate, question.ID, nil)
 		}); err != nil {
 			return err
 		}
@@ -54,12 +55,25 @@ func UpdateQuestion(deps *config.Dependencies) httpio.HandlerWithError {
 			return err
 		}
 
-		baseQuery := db.Model(&question)
-		query := question.UpdateQuery(baseQuery, r)
+		// setting the correct answer grades all the answers, which is a separate permission
+		currentUser := r.Context().Value(httpio.UserContextKey).(models.User)
+		if question.CorrectAnswer != nil && !currentUser.HasPermission(httpio.QuestionsGradePermission) {
+			return httpio.ActionForbiddenError{}
+		}
+
 		id := httpio.IntPathValue(r, "id")
 
 		if err := db.Transaction(func(tx *gorm.DB) error {
-			queryResult := query.Updates(&question)
+			if err := checkIfMatch[models.Question](tx, r, uint(id)); err != nil {
+				return err
+			}
+
+			before, err := auditSnapshot[models.Question](tx, uint(id))
+			if err != nil {
+				return err
+			}
+
+			queryResult := question.UpdateQuery(tx.Model(&question), r).Updates(&question)
 			if queryResult.Error != nil {
 				return queryResult.Error
 			}
@@ -74,13 +88,68 @@ func UpdateQuestion(deps *config.Dependencies) httpio.HandlerWithError {
 				return err
 			}
 
-			return nil
+			return auditChange[models.Question](tx, r, models.AuditActionUpdate, uint(id), before)
 		}); err != nil {
 			return err
 		}
 
-		db.First(&question, id)
-		response := question.BuildResponse()
+		response, etag, err := findRepresentation[models.Question](db, r)
+		if err != nil {
+			return err
+		}
+		w.Header().Set("ETag", etag)
+
+		if err := httpio.Encode(w, r, http.StatusOK, response); err != nil {
+			return err
+		}
+
+		return nil
+	})
+}
+
+// PatchQuestion applies JSON merge patch to the question, changing the correct answer requires the grading permission
+func PatchQuestion(deps *config.Dependencies) httpio.HandlerWithError {
+	return httpio.HandlerWithError(func(w http.ResponseWriter, r *http.Request) error {
+		db := deps.DB
+		currentUser := r.Context().Value(httpio.UserContextKey).(models.User)
+		id := uint(httpio.IntPathValue(r, "id"))
+
+		if err := db.Transaction(func(tx *gorm.DB) error {
+			if err := checkIfMatch[models.Question](tx, r, id); err != nil {
+				return err
+			}
+
+			before, err := auditSnapshot[models.Question](tx, id)
+			if err != nil {
+				return err
+			}
+
+			columns, err := mergePatch[models.Question](tx, r, id)
+			if err != nil {
+				return err
+			}
+
+			// the transaction is rolled back, so the patch is not applied
+			if slices.Contains(columns, "correct_answer") && !currentUser.HasPermission(httpio.QuestionsGradePermission) {
+				return httpio.ActionForbiddenError{}
+			}
+
+			if _, err := deps.Workers.InsertTx(tx, args.PointsGranterArgs{
+				QuestionID: id,
+			}); err != nil {
+				return err
+			}
+
+			return auditChange[models.Question](tx, r, models.AuditActionUpdate, id, before)
+		}); err != nil {
+			return err
+		}
+
+		response, etag, err := findRepresentation[models.Question](db, r)
+		if err != nil {
+			return err
+		}
+		w.Header().Set("ETag", etag)
 
 		if err := httpio.Encode(w, r, http.StatusOK, response); err != nil {
 			return err
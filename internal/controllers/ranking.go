@@ -1,6 +1,7 @@
 package controllers
 
 import (
+	"fmt"
 	"net/http"
 
 	"github.com/filipio/athletics-backend/pkg/config"
@@ -25,6 +26,7 @@ func GetRanking(deps *config.Dependencies) httpio.HandlerWithError {
 		func(w http.ResponseWriter, r *http.Request) error {
 			query := deps.DB.
 				Model(&models.Answer{}).
+				Scopes(models.ActiveAnswers).
 				Joins("JOIN users ON answers.user_id = users.id")
 
 			queryParams := r.URL.Query()
@@ -67,6 +69,7 @@ func GetMyRanking(deps *config.Dependencies) httpio.HandlerWithError {
 
 			userPointsQuery := deps.DB.
 				Model(&models.Answer{}).
+				Scopes(models.ActiveAnswers).
 				Select("COALESCE(SUM(answers.points), 0) as total_points").
 				Where("user_id = ?", currentUser.ID)
 
@@ -86,6 +89,7 @@ func GetMyRanking(deps *config.Dependencies) httpio.HandlerWithError {
 
 				countSubquery := deps.DB.
 					Model(&models.Answer{}).
+					Scopes(models.ActiveAnswers).
 					Select("user_id")
 
 				if queryParams.Has("event_id") {
@@ -110,6 +114,7 @@ func GetMyRanking(deps *config.Dependencies) httpio.HandlerWithError {
 
 			totalPlacesQuery := deps.DB.
 				Model(&models.Answer{}).
+				Scopes(models.ActiveAnswers).
 				Distinct("user_id")
 
 			if queryParams.Has("event_id") {
@@ -128,6 +133,7 @@ func GetMyRanking(deps *config.Dependencies) httpio.HandlerWithError {
 
 			totalPointsQuery := deps.DB.
 				Model(&models.Question{}).
+				Scopes(models.QuestionsOfActiveEvents).
 				Select("COALESCE(SUM(questions.points), 0) as total_points")
 
 			if queryParams.Has("event_id") {
@@ -153,3 +159,107 @@ func GetMyRanking(deps *config.Dependencies) httpio.HandlerWithError {
 			return nil
 		})
 }
+
+const DefaultRankingRadius = 5
+const MaxRankingRadius = 50
+
+type RankingAroundItem struct {
+	Place         int    `json:"place"`
+	Username      string `json:"username"`
+	TotalPoints   int    `json:"total_points"`
+	IsCurrentUser bool   `json:"is_current_user"`
+}
+
+type RankingAroundResponse struct {
+	Place *int                `json:"place"`
+	Data  []RankingAroundItem `json:"data"`
+}
+
+// returns the slice of the leaderboard surrounding the current user - 'radius' rows above and below
+func GetMyRankingAround(deps *config.Dependencies) httpio.HandlerWithError {
+	return httpio.HandlerWithError(
+		func(w http.ResponseWriter, r *http.Request) error {
+			currentUser := r.Context().Value(httpio.UserContextKey).(models.User)
+			queryParams := r.URL.Query()
+
+			radius := DefaultRankingRadius
+			if queryParams.Has("radius") {
+				radius = httpio.IntQueryValue(r, "radius")
+				if radius < 1 || radius > MaxRankingRadius {
+					return httpio.AppValidationError{
+						FieldPath: "radius",
+						AppError:  httpio.AppError{Message: fmt.Sprintf("must be an integer between 1 and %d", MaxRankingRadius)},
+					}
+				}
+			}
+
+			// position is unique per row (ties are broken by user id), place is shared between users with equal points
+			rankingQuery := deps.DB.
+				Model(&models.Answer{}).
+				Scopes(models.ActiveAnswers).
+				Joins("JOIN users ON answers.user_id = users.id")
+
+			if queryParams.Has("event_id") {
+				rankingQuery = rankingQuery.Joins("JOIN questions ON answers.question_id = questions.id").
+					Where("questions.event_id = ?", queryParams.Get("event_id"))
+			}
+
+			rankingQuery = rankingQuery.Group("users.id, users.username").
+				Select(`users.id as user_id, users.username, sum(answers.points) as total_points,
+					RANK() OVER (ORDER BY sum(answers.points) DESC) as place,
+					ROW_NUMBER() OVER (ORDER BY sum(answers.points) DESC, users.id) as position`)
+
+			var currentUserRow struct {
+				Place    int
+				Position int
+			}
+
+			result := deps.DB.Table("(?) as ranking", rankingQuery).
+				Select("place, position").
+				Where("user_id = ?", currentUser.ID).
+				Scan(&currentUserRow)
+			if result.Error != nil {
+				return result.Error
+			}
+
+			response := RankingAroundResponse{Data: []RankingAroundItem{}}
+
+			if result.RowsAffected == 0 {
+				if err := httpio.Encode(w, r, http.StatusOK, response); err != nil {
+					return err
+				}
+				return nil
+			}
+
+			var rows []struct {
+				UserID      uint
+				Username    string
+				TotalPoints int
+				Place       int
+			}
+
+			if err := deps.DB.Table("(?) as ranking", rankingQuery).
+				Select("user_id, username, total_points, place").
+				Where("position BETWEEN ? AND ?", currentUserRow.Position-radius, currentUserRow.Position+radius).
+				Order("position").
+				Scan(&rows).Error; err != nil {
+				return err
+			}
+
+			for _, row := range rows {
+				response.Data = append(response.Data, RankingAroundItem{
+					Place:         row.Place,
+					Username:      row.Username,
+					TotalPoints:   row.TotalPoints,
+					IsCurrentUser: row.UserID == currentUser.ID,
+				})
+			}
+			response.Place = &currentUserRow.Place
+
+			if err := httpio.Encode(w, r, http.StatusOK, response); err != nil {
+				return err
+			}
+
+			return nil
+		})
+}
@@ -1,13 +1,13 @@
 package controllers
 
 import (
+	"log/slog"
 	"net/http"
-	"time"
 
 	"github.com/filipio/athletics-backend/pkg/config"
+	"github.com/filipio/athletics-backend/internal/email"
 	"github.com/filipio/athletics-backend/internal/models"
 	"github.com/filipio/athletics-backend/pkg/httpio"
-	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
 )
 
@@ -23,52 +23,117 @@ func RefreshToken(deps *config.Dependencies) httpio.HandlerWithError {
 	return httpio.HandlerWithError(
 		func(w http.ResponseWriter, r *http.Request) error {
 			db := deps.DB
-			payload, err := httpio.DecodeAndValidate[RefreshTokenPayload](r, db)
+			refreshToken, err := refreshTokenFromRequest(r, db)
 			if err != nil {
 				return err
 			}
 
-			var refreshTokens []models.RefreshToken
-			db.Where("expires_at > ? AND revoked_at IS NULL", time.Now()).Find(&refreshTokens)
-
-			var foundToken *models.RefreshToken
-			for i := range refreshTokens {
-				if err := bcrypt.CompareHashAndPassword([]byte(refreshTokens[i].TokenHash), []byte(payload.RefreshToken)); err == nil {
-					foundToken = &refreshTokens[i]
-					break
-				}
+			foundToken, err := models.FindActiveRefreshToken(db, refreshToken)
+			if err != nil {
+				return err
 			}
 
 			if foundToken == nil {
 				return httpio.InvalidRefreshTokenError{}
 			}
 
+			if foundToken.IsUsed() {
+				if err := revokeReusedSession(deps, r, foundToken); err != nil {
+					return err
+				}
+				return httpio.InvalidRefreshTokenError{}
+			}
+
 			var user models.User
 			db.First(&user, foundToken.UserID)
 			if user.GetID() == 0 {
 				return httpio.LoginError{}
 			}
 
-			sessionID := foundToken.SessionID
+			if user.IsSuspended() {
+				return user.SuspendedError()
+			}
 
 			var tokenPair TokenPair
+			tokenReused := false
 			err = db.Transaction(func(tx *gorm.DB) error {
-				if err := tx.Delete(foundToken).Error; err != nil {
+				marked, err := foundToken.MarkUsed(tx)
+				if err != nil {
 					return err
 				}
+				if !marked {
+					tokenReused = true
+					return nil
+				}
 
-				var err error
-				tokenPair, err = generateTokenPair(user, tx, &sessionID)
+				tokenPair, err = generateTokenPair(deps.JwtKeys, user, tx, r, foundToken)
 				return err
 			})
 			if err != nil {
 				return err
 			}
 
-			if err := httpio.Encode(w, r, http.StatusOK, tokenPair); err != nil {
-				return err
+			// token was rotated by a concurrent request between lookup and marking it as used
+			if tokenReused {
+				if err := revokeReusedSession(deps, r, foundToken); err != nil {
+					return err
+				}
+				return httpio.InvalidRefreshTokenError{}
 			}
 
-			return nil
+			return encodeTokenPair(w, r, tokenPair)
 		})
 }
+
+// refreshTokenFromRequest takes the token from the refresh token cookie in the cookie auth mode, otherwise from the body
+func refreshTokenFromRequest(r *http.Request, db *gorm.DB) (string, error) {
+	if cookie, err := r.Cookie(httpio.RefreshTokenCookieName); err == nil && cookie.Value != "" && httpio.CookieAuthRequested(r) {
+		if err := httpio.VerifyCsrfToken(r); err != nil {
+			return "", err
+		}
+		return cookie.Value, nil
+	}
+
+	payload, err := httpio.DecodeAndValidate[RefreshTokenPayload](r, db)
+	if err != nil {
+		return "", err
+	}
+
+	return payload.RefreshToken, nil
+}
+
+// revokeReusedSession is called when already rotated refresh token is presented again. Either the legitimate client
+// or an attacker holds a stolen token, so the whole session is revoked and the user is notified.
+func revokeReusedSession(deps *config.Dependencies, r *http.Request, reusedToken *models.RefreshToken) error {
+	db := deps.DB
+	if err := reusedToken.RevokeSession(db); err != nil {
+		return err
+	}
+
+	ipAddress := httpio.ClientIP(r)
+	userAgent := httpio.ClientUserAgent(r)
+	slog.Warn("security event: refresh token reuse detected, session revoked",
+		"event", "refresh_token_reuse",
+		"user_id", reusedToken.UserID,
+		"session_id", reusedToken.SessionID,
+		"ip_address", ipAddress,
+		"user_agent", userAgent)
+
+	var user models.User
+	db.First(&user, reusedToken.UserID)
+	if user.ID == 0 {
+		return nil
+	}
+
+	// notification is best-effort, the session is already revoked
+	if err := deps.EmailSender.SendSecurityAlertEmail(r.Context(), email.SecurityAlertEmailParams{
+		To:        user.Email,
+		Alert:     "A previously used refresh token was presented again. The affected session has been logged out.",
+		IPAddress: ipAddress,
+		UserAgent: userAgent,
+	}); err != nil {
+		slog.Error("failed to send security alert email", "user_id", user.ID, "error", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,352 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+
+	"github.com/filipio/athletics-backend/internal/models"
+	"github.com/filipio/athletics-backend/pkg/config"
+	"github.com/filipio/athletics-backend/pkg/httpio"
+	"gorm.io/gorm"
+)
+
+type RolePayload struct {
+	Name              string   `json:"name" validate:"required,min=2,max=50"`
+	Permissions       []string `json:"permissions" validate:"required"`
+	RequiresTwoFactor bool     `json:"requires_two_factor"`
+}
+
+func (payload RolePayload) Validate(db *gorm.DB) error {
+	for _, permission := range payload.Permissions {
+		if !slices.Contains(httpio.AllPermissions, permission) {
+			return httpio.AppValidationError{
+				FieldPath: "permissions",
+				AppError:  httpio.AppError{Message: "contains unknown permission " + permission},
+			}
+		}
+	}
+
+	return nil
+}
+
+type AssignUserRolesPayload struct {
+	Roles  []string `json:"roles" validate:"required"`
+	Reason string   `json:"reason" validate:"required,max=500"`
+}
+
+func (payload AssignUserRolesPayload) Validate(db *gorm.DB) error {
+	return nil
+}
+
+// GetPermissions lists all the permissions which can be granted to roles
+func GetPermissions(deps *config.Dependencies) httpio.HandlerWithError {
+	return httpio.HandlerWithError(
+		func(w http.ResponseWriter, r *http.Request) error {
+			return httpio.Encode(w, r, http.StatusOK, httpio.AnyMap{
+				"permissions": httpio.AllPermissions,
+			})
+		})
+}
+
+func CreateRole(deps *config.Dependencies) httpio.HandlerWithError {
+	return httpio.HandlerWithError(
+		func(w http.ResponseWriter, r *http.Request) error {
+			db := deps.DB
+			payload, err := httpio.DecodeAndValidate[RolePayload](r, db)
+			if err != nil {
+				return err
+			}
+
+			if err := validateRoleNameAvailable(db, payload.Name, 0); err != nil {
+				return err
+			}
+
+			role := models.Role{Name: payload.Name, RequiresTwoFactor: payload.RequiresTwoFactor}
+			err = db.Transaction(func(tx *gorm.DB) error {
+				if err := tx.Create(&role).Error; err != nil {
+					return err
+				}
+
+				if err := replaceRolePermissions(tx, role.ID, payload.Permissions); err != nil {
+					return err
+				}
+
+				return recordRoleAudit(tx, r, models.AuditActionCreate, role.ID, nil)
+			})
+			if err != nil {
+				return err
+			}
+
+			db.Preload("Permissions").First(&role, role.ID)
+			return httpio.Encode(w, r, http.StatusCreated, role.BuildResponse())
+		})
+}
+
+// UpdateRole replaces name and permissions of the role, built-in roles can't be renamed
+func UpdateRole(deps *config.Dependencies) httpio.HandlerWithError {
+	return httpio.HandlerWithError(
+		func(w http.ResponseWriter, r *http.Request) error {
+			db := deps.DB
+			payload, err := httpio.DecodeAndValidate[RolePayload](r, db)
+			if err != nil {
+				return err
+			}
+
+			role, err := findRole(db, httpio.IntPathValue(r, "id"))
+			if err != nil {
+				return err
+			}
+
+			if err := updateRole(db, r, role, payload); err != nil {
+				return err
+			}
+
+			db.Preload("Permissions").First(&role, role.ID)
+			return httpio.Encode(w, r, http.StatusOK, role.BuildResponse())
+		})
+}
+
+// PatchRole applies JSON merge patch to name, permissions and two factor requirement of the role,
+// the rules of UpdateRole apply to the result
+func PatchRole(deps *config.Dependencies) httpio.HandlerWithError {
+	return httpio.HandlerWithError(
+		func(w http.ResponseWriter, r *http.Request) error {
+			db := deps.DB
+			role, err := findRole(db, httpio.IntPathValue(r, "id"))
+			if err != nil {
+				return err
+			}
+
+			current := RolePayload{
+				Name:              role.Name,
+				Permissions:       role.PermissionNames(),
+				RequiresTwoFactor: role.RequiresTwoFactor,
+			}
+			payload, _, err := httpio.DecodeMergePatchAndValidate(r, db, current)
+			if err != nil {
+				return err
+			}
+
+			if err := updateRole(db, r, role, payload); err != nil {
+				return err
+			}
+
+			db.Preload("Permissions").First(&role, role.ID)
+			return httpio.Encode(w, r, http.StatusOK, role.BuildResponse())
+		})
+}
+
+func updateRole(db *gorm.DB, r *http.Request, role models.Role, payload RolePayload) error {
+	if role.IsBuiltIn() && role.Name != payload.Name {
+		return httpio.AppValidationError{
+			FieldPath: "name",
+			AppError:  httpio.AppError{Message: "built-in role can't be renamed"},
+		}
+	}
+
+	if err := validateRoleNameAvailable(db, payload.Name, role.ID); err != nil {
+		return err
+	}
+
+	before := role.BuildResponse()
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&role).Updates(map[string]any{
+			"name":                payload.Name,
+			"requires_two_factor": payload.RequiresTwoFactor,
+		}).Error; err != nil {
+			return err
+		}
+
+		if err := replaceRolePermissions(tx, role.ID, payload.Permissions); err != nil {
+			return err
+		}
+
+		if err := ensureRolesManagerExists(tx, "permissions"); err != nil {
+			return err
+		}
+
+		return recordRoleAudit(tx, r, models.AuditActionUpdate, role.ID, before)
+	})
+}
+
+func DeleteRole(deps *config.Dependencies) httpio.HandlerWithError {
+	return httpio.HandlerWithError(
+		func(w http.ResponseWriter, r *http.Request) error {
+			db := deps.DB
+			role, err := findRole(db, httpio.IntPathValue(r, "id"))
+			if err != nil {
+				return err
+			}
+
+			if role.IsBuiltIn() {
+				return httpio.AppValidationError{
+					FieldPath: "name",
+					AppError:  httpio.AppError{Message: "built-in role can't be deleted"},
+				}
+			}
+
+			before := role.BuildResponse()
+			err = db.Transaction(func(tx *gorm.DB) error {
+				if err := tx.Delete(&role).Error; err != nil {
+					return err
+				}
+
+				if err := ensureRolesManagerExists(tx, "name"); err != nil {
+					return err
+				}
+
+				return models.RecordAudit(tx, r, models.AuditActionDelete, models.AuditResourceRole, role.ID, before, nil)
+			})
+			if err != nil {
+				return err
+			}
+
+			w.WriteHeader(http.StatusNoContent)
+			return nil
+		})
+}
+
+// AssignUserRoles replaces all the roles of the user with the given ones
+func AssignUserRoles(deps *config.Dependencies) httpio.HandlerWithError {
+	return httpio.HandlerWithError(
+		func(w http.ResponseWriter, r *http.Request) error {
+			db := deps.DB
+			payload, err := httpio.DecodeAndValidate[AssignUserRolesPayload](r, db)
+			if err != nil {
+				return err
+			}
+
+			user, err := findModeratedUser(db, r)
+			if err != nil {
+				return err
+			}
+
+			var roles []models.Role
+			if err := db.Where("name IN ?", payload.Roles).Find(&roles).Error; err != nil {
+				return err
+			}
+			for _, roleName := range payload.Roles {
+				if !slices.ContainsFunc(roles, func(role models.Role) bool { return role.Name == roleName }) {
+					return httpio.AppValidationError{
+						FieldPath: "roles",
+						AppError:  httpio.AppError{Message: "contains unknown role " + roleName},
+					}
+				}
+			}
+
+			before := user.BuildResponse()
+			err = db.Transaction(func(tx *gorm.DB) error {
+				if err := tx.Model(&user).Association("Roles").Replace(roles); err != nil {
+					return err
+				}
+
+				if err := ensureRolesManagerExists(tx, "roles"); err != nil {
+					return err
+				}
+
+				return recordRolesChange(tx, r, user, before, payload.Roles, payload.Reason)
+			})
+			if err != nil {
+				return err
+			}
+
+			db.Preload("Roles.Permissions").First(&user, user.ID)
+			return httpio.Encode(w, r, http.StatusOK, user.BuildResponse())
+		})
+}
+
+// recordRolesChange logs every granted and revoked role as a separate moderation action
+func recordRolesChange(tx *gorm.DB, r *http.Request, user models.User, before any, roleNames []string, reason string) error {
+	for _, roleName := range roleNames {
+		if !user.HasAnyRole(roleName) {
+			if err := recordModerationAction(tx, r, user.ID, before, models.ModerationActionGrantRole, reason, roleName); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, role := range user.Roles {
+		if !slices.Contains(roleNames, role.Name) {
+			if err := recordModerationAction(tx, r, user.ID, before, models.ModerationActionRevokeRole, reason, role.Name); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// recordRoleAudit reloads the role with its permissions, so the entry contains the state after the change
+func recordRoleAudit(tx *gorm.DB, r *http.Request, action string, roleID uint, before any) error {
+	var role models.Role
+	if err := tx.Preload("Permissions").First(&role, roleID).Error; err != nil {
+		return err
+	}
+
+	return models.RecordAudit(tx, r, action, models.AuditResourceRole, roleID, before, role.BuildResponse())
+}
+
+func findRole(db *gorm.DB, id int) (models.Role, error) {
+	var role models.Role
+	if err := db.Preload("Permissions").First(&role, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return role, httpio.RecordNotFoundError{}
+		}
+		return role, err
+	}
+
+	return role, nil
+}
+
+func validateRoleNameAvailable(db *gorm.DB, name string, roleID uint) error {
+	var count int64
+	if err := db.Model(&models.Role{}).Where("name = ? AND id <> ?", name, roleID).Count(&count).Error; err != nil {
+		return err
+	}
+
+	if count > 0 {
+		return httpio.AppValidationError{
+			FieldPath: "name",
+			AppError:  httpio.AppError{Message: "is already taken"},
+		}
+	}
+
+	return nil
+}
+
+func replaceRolePermissions(tx *gorm.DB, roleID uint, permissions []string) error {
+	if err := tx.Where("role_id = ?", roleID).Delete(&models.RolePermission{}).Error; err != nil {
+		return err
+	}
+
+	for _, permission := range permissions {
+		rolePermission := models.RolePermission{RoleID: roleID, Permission: permission}
+		if err := tx.Where(rolePermission).FirstOrCreate(&rolePermission).Error; err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ensureRolesManagerExists prevents changes after which nobody could manage roles anymore
+func ensureRolesManagerExists(tx *gorm.DB, fieldPath string) error {
+	var count int64
+	if err := tx.Table("user_roles").
+		Joins("JOIN role_permissions ON role_permissions.role_id = user_roles.role_id").
+		Where("role_permissions.permission = ?", httpio.RolesManagePermission).
+		Distinct("user_roles.user_id").
+		Count(&count).Error; err != nil {
+		return err
+	}
+
+	if count == 0 {
+		return httpio.AppValidationError{
+			FieldPath: fieldPath,
+			AppError:  httpio.AppError{Message: "at least one user must be able to manage roles"},
+		}
+	}
+
+	return nil
+}
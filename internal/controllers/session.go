@@ -0,0 +1,129 @@
+package controllers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/filipio/athletics-backend/internal/models"
+	"github.com/filipio/athletics-backend/pkg/config"
+	"github.com/filipio/athletics-backend/pkg/httpio"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// GetMySessions lists active sessions (devices) of the current user
+func GetMySessions(deps *config.Dependencies) httpio.HandlerWithError {
+	return httpio.HandlerWithError(
+		func(w http.ResponseWriter, r *http.Request) error {
+			currentUser := r.Context().Value(httpio.UserContextKey).(models.User)
+			currentSessionID, _ := r.Context().Value(httpio.SessionIDContextKey).(string)
+
+			var refreshTokens []models.RefreshToken
+			if err := deps.DB.
+				Where("user_id = ? AND revoked_at IS NULL AND used_at IS NULL AND expires_at > ?", currentUser.ID, time.Now()).
+				Order("last_used_at DESC NULLS LAST").
+				Find(&refreshTokens).Error; err != nil {
+				return err
+			}
+
+			sessions := make([]models.SessionResponse, len(refreshTokens))
+			for i, refreshToken := range refreshTokens {
+				sessions[i] = refreshToken.BuildSessionResponse(currentSessionID)
+			}
+
+			if err := httpio.Encode(w, r, http.StatusOK, sessions); err != nil {
+				return err
+			}
+
+			return nil
+		})
+}
+
+func RevokeMySession(deps *config.Dependencies) httpio.HandlerWithError {
+	return httpio.HandlerWithError(
+		func(w http.ResponseWriter, r *http.Request) error {
+			currentUser := r.Context().Value(httpio.UserContextKey).(models.User)
+
+			// session_id is an uuid column, other values would fail the query
+			sessionID, err := uuid.Parse(r.PathValue("session_id"))
+			if err != nil {
+				return httpio.RecordNotFoundError{}
+			}
+
+			result := deps.DB.Model(&models.RefreshToken{}).
+				Where("user_id = ? AND session_id = ? AND revoked_at IS NULL", currentUser.ID, sessionID.String()).
+				Update("revoked_at", time.Now())
+			if result.Error != nil {
+				return result.Error
+			}
+
+			if result.RowsAffected == 0 {
+				return httpio.RecordNotFoundError{}
+			}
+
+			if err := httpio.Encode(w, r, http.StatusOK, httpio.AnyMap{
+				"message": "session revoked successfully",
+			}); err != nil {
+				return err
+			}
+
+			return nil
+		})
+}
+
+// RevokeMyOtherSessions logs the current user out everywhere except the current session
+func RevokeMyOtherSessions(deps *config.Dependencies) httpio.HandlerWithError {
+	return httpio.HandlerWithError(
+		func(w http.ResponseWriter, r *http.Request) error {
+			currentUser := r.Context().Value(httpio.UserContextKey).(models.User)
+
+			currentSessionID, ok := r.Context().Value(httpio.SessionIDContextKey).(string)
+			if !ok || currentSessionID == "" {
+				return httpio.SessionExpiredError{}
+			}
+
+			if err := revokeUserSessions(deps.DB, currentUser.ID, currentSessionID); err != nil {
+				return err
+			}
+
+			if err := httpio.Encode(w, r, http.StatusOK, httpio.AnyMap{
+				"message": "other sessions revoked successfully",
+			}); err != nil {
+				return err
+			}
+
+			return nil
+		})
+}
+
+func RevokeUserSessions(deps *config.Dependencies) httpio.HandlerWithError {
+	return httpio.HandlerWithError(
+		func(w http.ResponseWriter, r *http.Request) error {
+			db := deps.DB
+
+			var user models.User
+			db.First(&user, httpio.IntPathValue(r, "id"))
+			if user.ID == 0 {
+				return httpio.RecordNotFoundError{}
+			}
+
+			err := db.Transaction(func(tx *gorm.DB) error {
+				if err := revokeUserSessions(tx, user.ID); err != nil {
+					return err
+				}
+
+				return models.RecordAudit(tx, r, models.AuditActionRevokeSessions, models.AuditResourceUser, user.ID, nil, nil)
+			})
+			if err != nil {
+				return err
+			}
+
+			if err := httpio.Encode(w, r, http.StatusOK, httpio.AnyMap{
+				"message": "user sessions revoked successfully",
+			}); err != nil {
+				return err
+			}
+
+			return nil
+		})
+}
@@ -0,0 +1,366 @@
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"math"
+	"net/http"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/filipio/athletics-backend/internal/models"
+	"github.com/filipio/athletics-backend/pkg/config"
+	"github.com/filipio/athletics-backend/pkg/httpio"
+	"gorm.io/gorm"
+)
+
+const NumericHistogramBuckets = 10
+
+type AnswerDistributionItem struct {
+	Athlete    any      `json:"athlete,omitempty"`
+	Country    *string  `json:"country,omitempty"`
+	From       *float64 `json:"from,omitempty"`
+	To         *float64 `json:"to,omitempty"`
+	Count      int      `json:"count"`
+	Percentage float64  `json:"percentage"`
+}
+
+type QuestionStatsSummary struct {
+	QuestionID        uint     `json:"question_id"`
+	Content           string   `json:"content"`
+	AnswersCount      int      `json:"answers_count"`
+	GradedCount       int      `json:"graded_count"`
+	CorrectCount      int      `json:"correct_count"`
+	PercentageCorrect *float64 `json:"percentage_correct"`
+	AveragePoints     *float64 `json:"average_points"`
+}
+
+type QuestionStatsResponse struct {
+	QuestionStatsSummary
+	Type         string                   `json:"type"`
+	Distribution []AnswerDistributionItem `json:"distribution"`
+}
+
+type EventStatsResponse struct {
+	EventID                      uint                   `json:"event_id"`
+	QuestionsCount               int                    `json:"questions_count"`
+	ParticipantsCount            int                    `json:"participants_count"`
+	AnswersCount                 int                    `json:"answers_count"`
+	AverageAnswersPerParticipant float64                `json:"average_answers_per_participant"`
+	AveragePointsPerParticipant  float64                `json:"average_points_per_participant"`
+	Questions                    []QuestionStatsSummary `json:"questions"`
+}
+
+func GetQuestionStats(deps *config.Dependencies) httpio.HandlerWithError {
+	return httpio.HandlerWithError(func(w http.ResponseWriter, r *http.Request) error {
+		db := deps.DB
+
+		var question models.Question
+		if err := db.First(&question, httpio.IntPathValue(r, "id")).Error; err != nil {
+			return httpio.RecordNotFoundError{}
+		}
+
+		var event models.Event
+		if err := db.First(&event, question.EventID).Error; err != nil {
+			return httpio.RecordNotFoundError{}
+		}
+
+		if err := statsVisibleForCurrentUser(r, event); err != nil {
+			return err
+		}
+
+		var summaries []QuestionStatsSummary
+		if err := questionsSummaryQuery(db).Where("questions.id = ?", question.ID).Scan(&summaries).Error; err != nil {
+			return err
+		}
+		if len(summaries) == 0 {
+			return httpio.RecordNotFoundError{}
+		}
+
+		var answers []models.Answer
+		if err := db.Where("question_id = ?", question.ID).Find(&answers).Error; err != nil {
+			return err
+		}
+
+		distribution, err := answersDistribution(db, question.Type, answers)
+		if err != nil {
+			return err
+		}
+
+		response := QuestionStatsResponse{
+			QuestionStatsSummary: withPercentageCorrect(summaries[0]),
+			Type:                 question.Type,
+			Distribution:         distribution,
+		}
+
+		if err := httpio.Encode(w, r, http.StatusOK, response); err != nil {
+			return err
+		}
+
+		return nil
+	})
+}
+
+func GetEventStats(deps *config.Dependencies) httpio.HandlerWithError {
+	return httpio.HandlerWithError(func(w http.ResponseWriter, r *http.Request) error {
+		db := deps.DB
+
+		var event models.Event
+		if err := db.First(&event, httpio.IntPathValue(r, "id")).Error; err != nil {
+			return httpio.RecordNotFoundError{}
+		}
+
+		if err := statsVisibleForCurrentUser(r, event); err != nil {
+			return err
+		}
+
+		var summaries []QuestionStatsSummary
+		if err := questionsSummaryQuery(db).
+			Where("questions.event_id = ?", event.ID).
+			Order("questions.id").
+			Scan(&summaries).Error; err != nil {
+			return err
+		}
+
+		var participation struct {
+			ParticipantsCount int
+			AnswersCount      int
+			TotalPoints       int
+		}
+		if err := db.Model(&models.Answer{}).
+			Scopes(models.ActiveAnswers).
+			Joins("JOIN questions ON answers.question_id = questions.id").
+			Where("questions.event_id = ?", event.ID).
+			Select("COUNT(DISTINCT answers.user_id) as participants_count, COUNT(answers.id) as answers_count, COALESCE(SUM(answers.points), 0) as total_points").
+			Scan(&participation).Error; err != nil {
+			return err
+		}
+
+		response := EventStatsResponse{
+			EventID:           event.ID,
+			QuestionsCount:    len(summaries),
+			ParticipantsCount: participation.ParticipantsCount,
+			AnswersCount:      participation.AnswersCount,
+			Questions:         make([]QuestionStatsSummary, len(summaries)),
+		}
+
+		if participation.ParticipantsCount > 0 {
+			response.AverageAnswersPerParticipant = roundTwoDecimals(float64(participation.AnswersCount) / float64(participation.ParticipantsCount))
+			response.AveragePointsPerParticipant = roundTwoDecimals(float64(participation.TotalPoints) / float64(participation.ParticipantsCount))
+		}
+
+		for i, summary := range summaries {
+			response.Questions[i] = withPercentageCorrect(summary)
+		}
+
+		if err := httpio.Encode(w, r, http.StatusOK, response); err != nil {
+			return err
+		}
+
+		return nil
+	})
+}
+
+// users with stats:read permission can see stats at any time, others only for published events after the deadline
+func statsVisibleForCurrentUser(r *http.Request, event models.Event) error {
+	currentUser := r.Context().Value(httpio.UserContextKey).(models.User)
+	if currentUser.HasPermission(httpio.StatsReadPermission) {
+		return nil
+	}
+
+	if event.Status != "published" {
+		return httpio.RecordNotFoundError{}
+	}
+
+	if time.Now().Before(event.Deadline) {
+		return httpio.StatsNotAvailableError{}
+	}
+
+	return nil
+}
+
+// aggregates answers per question - answers which were not graded yet are not taken into account for points
+func questionsSummaryQuery(db *gorm.DB) *gorm.DB {
+	return db.Model(&models.Question{}).
+		Joins("LEFT JOIN answers ON answers.question_id = questions.id").
+		Group("questions.id, questions.content").
+		Select(`questions.id as question_id, questions.content,
+			COUNT(answers.id) as answers_count,
+			COUNT(answers.points_granted_at) as graded_count,
+			COUNT(answers.id) FILTER (WHERE answers.points_granted_at IS NOT NULL AND answers.points > 0) as correct_count,
+			AVG(answers.points) FILTER (WHERE answers.points_granted_at IS NOT NULL) as average_points`)
+}
+
+func withPercentageCorrect(summary QuestionStatsSummary) QuestionStatsSummary {
+	if summary.GradedCount > 0 {
+		percentageCorrect := roundTwoDecimals(100 * float64(summary.CorrectCount) / float64(summary.GradedCount))
+		summary.PercentageCorrect = &percentageCorrect
+	}
+
+	if summary.AveragePoints != nil {
+		averagePoints := roundTwoDecimals(*summary.AveragePoints)
+		summary.AveragePoints = &averagePoints
+	}
+
+	return summary
+}
+
+func answersDistribution(db *gorm.DB, questionType string, answers []models.Answer) ([]AnswerDistributionItem, error) {
+	switch questionType {
+	case "athlete", "athletes_three":
+		return athletesDistribution(db, questionType, answers)
+	case "country", "countries_three":
+		return countriesDistribution(questionType, answers)
+	case "numeric_value":
+		return numericDistribution(answers)
+	default:
+		return nil, fmt.Errorf("unsupported question type: %s", questionType)
+	}
+}
+
+func athletesDistribution(db *gorm.DB, questionType string, answers []models.Answer) ([]AnswerDistributionItem, error) {
+	counts := map[uint]int{}
+	for _, answer := range answers {
+		if questionType == "athlete" {
+			var content models.AthleteAnswer
+			if err := json.Unmarshal(answer.Content.JSON, &content); err != nil {
+				return nil, err
+			}
+			counts[content.AthleteId]++
+		} else {
+			var content models.AthletesThreeAnswer
+			if err := json.Unmarshal(answer.Content.JSON, &content); err != nil {
+				return nil, err
+			}
+			counts[content.AthleteIdOne]++
+			counts[content.AthleteIdTwo]++
+			counts[content.AthleteIdThree]++
+		}
+	}
+
+	athleteIds := slices.Sorted(maps.Keys(counts))
+
+	athletesById := map[uint]models.Athlete{}
+	if len(athleteIds) > 0 {
+		var athletes []models.Athlete
+		if err := db.Preload("Disciplines").Where("id IN (?)", athleteIds).Find(&athletes).Error; err != nil {
+			return nil, err
+		}
+		for _, athlete := range athletes {
+			athletesById[athlete.ID] = athlete
+		}
+	}
+
+	distribution := make([]AnswerDistributionItem, 0, len(counts))
+	for _, athleteId := range athleteIds {
+		athlete, ok := athletesById[athleteId]
+		if !ok {
+			continue
+		}
+		count := counts[athleteId]
+		distribution = append(distribution, AnswerDistributionItem{
+			Athlete:    athlete.BuildResponse(),
+			Count:      count,
+			Percentage: roundTwoDecimals(100 * float64(count) / float64(len(answers))),
+		})
+	}
+
+	sortDistribution(distribution)
+	return distribution, nil
+}
+
+func countriesDistribution(questionType string, answers []models.Answer) ([]AnswerDistributionItem, error) {
+	counts := map[string]int{}
+	for _, answer := range answers {
+		if questionType == "country" {
+			var content models.CountryAnswer
+			if err := json.Unmarshal(answer.Content.JSON, &content); err != nil {
+				return nil, err
+			}
+			counts[content.Country]++
+		} else {
+			var content models.CountriesThreeAnswer
+			if err := json.Unmarshal(answer.Content.JSON, &content); err != nil {
+				return nil, err
+			}
+			counts[content.CountryOne]++
+			counts[content.CountryTwo]++
+			counts[content.CountryThree]++
+		}
+	}
+
+	distribution := make([]AnswerDistributionItem, 0, len(counts))
+	for _, country := range slices.Sorted(maps.Keys(counts)) {
+		count := counts[country]
+		distribution = append(distribution, AnswerDistributionItem{
+			Country:    &country,
+			Count:      count,
+			Percentage: roundTwoDecimals(100 * float64(count) / float64(len(answers))),
+		})
+	}
+
+	sortDistribution(distribution)
+	return distribution, nil
+}
+
+// splits the range of given values into equal-width buckets, the last bucket includes its upper bound
+func numericDistribution(answers []models.Answer) ([]AnswerDistributionItem, error) {
+	if len(answers) == 0 {
+		return []AnswerDistributionItem{}, nil
+	}
+
+	values := make([]float64, len(answers))
+	for i, answer := range answers {
+		var content models.NumericValueAnswer
+		if err := json.Unmarshal(answer.Content.JSON, &content); err != nil {
+			return nil, err
+		}
+		values[i] = content.Value
+	}
+
+	minValue, maxValue := slices.Min(values), slices.Max(values)
+	bucketsCount := NumericHistogramBuckets
+	if minValue == maxValue {
+		bucketsCount = 1
+	}
+	bucketWidth := (maxValue - minValue) / float64(bucketsCount)
+
+	counts := make([]int, bucketsCount)
+	for _, value := range values {
+		bucket := bucketsCount - 1
+		if bucketWidth > 0 {
+			bucket = min(int((value-minValue)/bucketWidth), bucketsCount-1)
+		}
+		counts[bucket]++
+	}
+
+	distribution := make([]AnswerDistributionItem, bucketsCount)
+	for i, count := range counts {
+		from := minValue + float64(i)*bucketWidth
+		to := minValue + float64(i+1)*bucketWidth
+		if i == bucketsCount-1 {
+			to = maxValue
+		}
+		distribution[i] = AnswerDistributionItem{
+			From:       &from,
+			To:         &to,
+			Count:      count,
+			Percentage: roundTwoDecimals(100 * float64(count) / float64(len(values))),
+		}
+	}
+
+	return distribution, nil
+}
+
+// most popular answers go first, ties keep the order of insertion
+func sortDistribution(distribution []AnswerDistributionItem) {
+	sort.SliceStable(distribution, func(i, j int) bool {
+		return distribution[i].Count > distribution[j].Count
+	})
+}
+
+func roundTwoDecimals(value float64) float64 {
+	return math.Round(value*100) / 100
+}
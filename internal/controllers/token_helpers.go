@@ -1,10 +1,12 @@
 package controllers
 
 import (
-	"os"
+	"crypto/rand"
+	"net/http"
 	"time"
 
 	"github.com/filipio/athletics-backend/internal/models"
+	"github.com/filipio/athletics-backend/pkg/config"
 	"github.com/filipio/athletics-backend/pkg/httpio"
 	"github.com/golang-jwt/jwt/v5"
 	"gorm.io/gorm"
@@ -16,17 +18,26 @@ type TokenPair struct {
 	ExpiresIn    int64  `json:"expires_in"`
 }
 
-// generateAccessToken creates a short-lived JWT access token with session_id in claims
-func generateAccessToken(user models.User, roleNames []string, sessionID string) (string, error) {
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+// CookieSessionResponse is returned instead of TokenPair in the cookie auth mode, tokens are only in HttpOnly cookies.
+// CSRF token is returned also in the body, as the frontend served from another origin can't read the cookie.
+type CookieSessionResponse struct {
+	ExpiresIn int64  `json:"expires_in"`
+	CsrfToken string `json:"csrf_token"`
+}
+
+// generateAccessToken creates a short-lived JWT access token with session_id in claims, signed with the current signing key
+func generateAccessToken(keys *config.JwtKeySet, user models.User, roleNames []string, sessionID string) (string, error) {
+	now := time.Now()
+	tokenString, err := keys.Sign(jwt.MapClaims{
 		"sub":        user.ID,
-		"exp":        time.Now().Add(httpio.AccessTokenExpiration).Unix(),
+		"iss":        keys.Issuer,
+		"aud":        keys.Audience,
+		"iat":        now.Unix(),
+		"exp":        now.Add(httpio.AccessTokenExpiration).Unix(),
 		"roles":      roleNames,
 		"username":   user.Username,
 		"session_id": sessionID,
 	})
-
-	tokenString, err := token.SignedString([]byte(os.Getenv("JWT_SIGNING_SECRET")))
 	if err != nil {
 		return "", err
 	}
@@ -34,40 +45,38 @@ func generateAccessToken(user models.User, roleNames []string, sessionID string)
 	return tokenString, nil
 }
 
-// createRefreshToken creates a long-lived refresh token and stores it in the database
-func createRefreshToken(user models.User, db *gorm.DB, sessionID string) (string, error) {
+// createRefreshToken creates a long-lived refresh token and stores it in the database.
+// If previousToken is given, the session is continued (rotation), otherwise a new session is started.
+func createRefreshToken(user models.User, db *gorm.DB, r *http.Request, previousToken *models.RefreshToken) (string, string, error) {
+	now := time.Now()
 	refreshTokenModel := models.RefreshToken{
-		UserID:    user.ID,
-		SessionID: sessionID,
-		ExpiresAt: time.Now().Add(httpio.RefreshTokenExpiration),
+		UserID:           user.ID,
+		ExpiresAt:        now.Add(httpio.RefreshTokenExpiration),
+		SessionStartedAt: now,
+		LastUsedAt:       &now,
+		UserAgent:        httpio.ClientUserAgent(r),
+		IPAddress:        httpio.ClientIP(r),
 	}
 
-	plainToken := refreshTokenModel.GenerateToken()
-
-	hashedToken, err := refreshTokenModel.HashToken(plainToken)
-	if err != nil {
-		return "", err
+	if previousToken != nil {
+		refreshTokenModel.SessionID = previousToken.SessionID
+		refreshTokenModel.SessionStartedAt = previousToken.SessionStartedAt
+	} else {
+		refreshTokenModel.SessionID = refreshTokenModel.GenerateSessionID()
 	}
 
-	refreshTokenModel.TokenHash = hashedToken
+	plainToken := refreshTokenModel.GenerateToken()
 
 	if err := db.Create(&refreshTokenModel).Error; err != nil {
-		return "", err
+		return "", "", err
 	}
 
-	return plainToken, nil
+	return plainToken, refreshTokenModel.SessionID, nil
 }
 
 // generateTokenPair creates both access and refresh tokens with the same session_id (refresh token is stored in DB)
-// If sessionID is nil, a new one will be generated
-func generateTokenPair(user models.User, db *gorm.DB, sessionID *string) (TokenPair, error) {
-	var id string
-	if sessionID == nil {
-		id = (&models.RefreshToken{}).GenerateSessionID()
-	} else {
-		id = *sessionID
-	}
-
+// If previousToken is nil, a new session will be started
+func generateTokenPair(keys *config.JwtKeySet, user models.User, db *gorm.DB, r *http.Request, previousToken *models.RefreshToken) (TokenPair, error) {
 	var roles []models.Role
 	if err := db.Model(&user).Association("Roles").Find(&roles); err != nil {
 		return TokenPair{}, err
@@ -78,12 +87,12 @@ func generateTokenPair(user models.User, db *gorm.DB, sessionID *string) (TokenP
 		roleNames[i] = role.Name
 	}
 
-	accessToken, err := generateAccessToken(user, roleNames, id)
+	refreshToken, sessionID, err := createRefreshToken(user, db, r, previousToken)
 	if err != nil {
 		return TokenPair{}, err
 	}
 
-	refreshToken, err := createRefreshToken(user, db, id)
+	accessToken, err := generateAccessToken(keys, user, roleNames, sessionID)
 	if err != nil {
 		return TokenPair{}, err
 	}
@@ -94,3 +103,30 @@ func generateTokenPair(user models.User, db *gorm.DB, sessionID *string) (TokenP
 		ExpiresIn:    int64(httpio.AccessTokenExpiration.Seconds()),
 	}, nil
 }
+
+// encodeTokenPair responds with the token pair, or sets auth cookies when the client requested the cookie auth mode
+func encodeTokenPair(w http.ResponseWriter, r *http.Request, tokenPair TokenPair) error {
+	if !httpio.CookieAuthRequested(r) {
+		return httpio.Encode(w, r, http.StatusOK, tokenPair)
+	}
+
+	csrfToken := rand.Text()
+	httpio.SetAuthCookies(w, tokenPair.AccessToken, tokenPair.RefreshToken, csrfToken)
+
+	return httpio.Encode(w, r, http.StatusOK, CookieSessionResponse{
+		ExpiresIn: tokenPair.ExpiresIn,
+		CsrfToken: csrfToken,
+	})
+}
+
+// revokeUserSessions revokes all active refresh tokens of the user, sessions passed in exceptSessionIDs stay active
+func revokeUserSessions(db *gorm.DB, userID uint, exceptSessionIDs ...string) error {
+	query := db.Model(&models.RefreshToken{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID)
+
+	if len(exceptSessionIDs) > 0 {
+		query = query.Where("session_id NOT IN (?)", exceptSessionIDs)
+	}
+
+	return query.Update("revoked_at", time.Now()).Error
+}
@@ -0,0 +1,473 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/filipio/athletics-backend/internal/models"
+	"github.com/filipio/athletics-backend/pkg/config"
+	"github.com/filipio/athletics-backend/pkg/httpio"
+	"github.com/filipio/athletics-backend/pkg/totp"
+	"gorm.io/gorm"
+)
+
+const defaultTwoFactorIssuer = "Lekkoatletawka"
+
+type EnrollTwoFactorPayload struct {
+	Password string `json:"password" validate:"required"`
+}
+
+func (payload EnrollTwoFactorPayload) Validate(db *gorm.DB) error {
+	return nil
+}
+
+type ConfirmTwoFactorPayload struct {
+	Code string `json:"code" validate:"required"`
+}
+
+func (payload ConfirmTwoFactorPayload) Validate(db *gorm.DB) error {
+	return nil
+}
+
+// used by endpoints which change 2FA settings, code can be TOTP code or recovery code
+type TwoFactorProtectedPayload struct {
+	Password string `json:"password" validate:"required"`
+	Code     string `json:"code" validate:"required"`
+}
+
+func (payload TwoFactorProtectedPayload) Validate(db *gorm.DB) error {
+	return nil
+}
+
+type VerifyLoginChallengePayload struct {
+	ChallengeToken string `json:"challenge_token" validate:"required"`
+	Code           string `json:"code" validate:"required"`
+}
+
+func (payload VerifyLoginChallengePayload) Validate(db *gorm.DB) error {
+	return nil
+}
+
+type RoleTwoFactorRequirementPayload struct {
+	Required *bool `json:"required" validate:"required"`
+}
+
+func (payload RoleTwoFactorRequirementPayload) Validate(db *gorm.DB) error {
+	return nil
+}
+
+type TwoFactorStatusResponse struct {
+	Enabled                bool  `json:"enabled"`
+	Required               bool  `json:"required"`
+	RecoveryCodesRemaining int64 `json:"recovery_codes_remaining"`
+}
+
+type TwoFactorEnrollmentResponse struct {
+	Secret     string `json:"secret"`
+	OtpauthURI string `json:"otpauth_uri"`
+}
+
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+type LoginChallengeResponse struct {
+	TwoFactorRequired bool   `json:"two_factor_required"`
+	ChallengeToken    string `json:"challenge_token"`
+	ExpiresIn         int64  `json:"expires_in"`
+}
+
+func GetMyTwoFactorStatus(deps *config.Dependencies) httpio.HandlerWithError {
+	return httpio.HandlerWithError(
+		func(w http.ResponseWriter, r *http.Request) error {
+			db := deps.DB
+			currentUser := r.Context().Value(httpio.UserContextKey).(models.User)
+
+			twoFactor, err := findConfirmedTwoFactor(db, currentUser.ID)
+			if err != nil {
+				return err
+			}
+
+			var recoveryCodesRemaining int64
+			if twoFactor != nil {
+				if err := db.Model(&models.TwoFactorRecoveryCode{}).
+					Where("user_id = ? AND used_at IS NULL", currentUser.ID).
+					Count(&recoveryCodesRemaining).Error; err != nil {
+					return err
+				}
+			}
+
+			return httpio.Encode(w, r, http.StatusOK, TwoFactorStatusResponse{
+				Enabled:                twoFactor != nil,
+				Required:               currentUser.RequiresTwoFactor(),
+				RecoveryCodesRemaining: recoveryCodesRemaining,
+			})
+		})
+}
+
+// EnrollTwoFactor generates new secret, 2FA is not enabled until the first code is confirmed
+func EnrollTwoFactor(deps *config.Dependencies) httpio.HandlerWithError {
+	return httpio.HandlerWithError(
+		func(w http.ResponseWriter, r *http.Request) error {
+			db := deps.DB
+			payload, err := httpio.DecodeAndValidate[EnrollTwoFactorPayload](r, db)
+			if err != nil {
+				return err
+			}
+
+			currentUser := r.Context().Value(httpio.UserContextKey).(models.User)
+			if err := verifyPassword(currentUser, payload.Password, "password"); err != nil {
+				return err
+			}
+
+			var twoFactor models.UserTwoFactor
+			db.Where("user_id = ?", currentUser.ID).First(&twoFactor)
+			if twoFactor.IsConfirmed() {
+				return httpio.TwoFactorAlreadyEnabledError{}
+			}
+
+			twoFactor.UserID = currentUser.ID
+			twoFactor.Secret = totp.GenerateSecret()
+			twoFactor.LastUsedStep = 0
+			if err := db.Save(&twoFactor).Error; err != nil {
+				return err
+			}
+
+			return httpio.Encode(w, r, http.StatusOK, TwoFactorEnrollmentResponse{
+				Secret:     twoFactor.Secret,
+				OtpauthURI: totp.URI(twoFactorIssuer(), currentUser.Email, twoFactor.Secret),
+			})
+		})
+}
+
+// ConfirmTwoFactor enables 2FA and returns recovery codes, which are shown only once.
+// Other sessions of the user were created without the second factor, so they are revoked.
+func ConfirmTwoFactor(deps *config.Dependencies) httpio.HandlerWithError {
+	return httpio.HandlerWithError(
+		func(w http.ResponseWriter, r *http.Request) error {
+			db := deps.DB
+			payload, err := httpio.DecodeAndValidate[ConfirmTwoFactorPayload](r, db)
+			if err != nil {
+				return err
+			}
+
+			currentUser := r.Context().Value(httpio.UserContextKey).(models.User)
+
+			var twoFactor models.UserTwoFactor
+			db.Where("user_id = ?", currentUser.ID).First(&twoFactor)
+			if twoFactor.ID == 0 {
+				return httpio.TwoFactorNotEnabledError{}
+			}
+			if twoFactor.IsConfirmed() {
+				return httpio.TwoFactorAlreadyEnabledError{}
+			}
+
+			codeValid, err := twoFactor.UseCode(db, payload.Code)
+			if err != nil {
+				return err
+			}
+			if !codeValid {
+				return invalidTwoFactorCodeError()
+			}
+
+			sessionID, _ := r.Context().Value(httpio.SessionIDContextKey).(string)
+			var plainCodes []string
+			err = db.Transaction(func(tx *gorm.DB) error {
+				if err := tx.Model(&twoFactor).Update("confirmed_at", time.Now()).Error; err != nil {
+					return err
+				}
+
+				plainCodes, err = replaceRecoveryCodes(tx, currentUser.ID)
+				if err != nil {
+					return err
+				}
+
+				return revokeUserSessions(tx, currentUser.ID, sessionID)
+			})
+			if err != nil {
+				return err
+			}
+
+			return httpio.Encode(w, r, http.StatusOK, RecoveryCodesResponse{RecoveryCodes: plainCodes})
+		})
+}
+
+// RegenerateRecoveryCodes invalidates all previous recovery codes
+func RegenerateRecoveryCodes(deps *config.Dependencies) httpio.HandlerWithError {
+	return httpio.HandlerWithError(
+		func(w http.ResponseWriter, r *http.Request) error {
+			db := deps.DB
+			currentUser := r.Context().Value(httpio.UserContextKey).(models.User)
+			if err := verifyTwoFactorProtectedPayload(db, r, currentUser); err != nil {
+				return err
+			}
+
+			var plainCodes []string
+			err := db.Transaction(func(tx *gorm.DB) error {
+				var err error
+				plainCodes, err = replaceRecoveryCodes(tx, currentUser.ID)
+				return err
+			})
+			if err != nil {
+				return err
+			}
+
+			return httpio.Encode(w, r, http.StatusOK, RecoveryCodesResponse{RecoveryCodes: plainCodes})
+		})
+}
+
+func DisableTwoFactor(deps *config.Dependencies) httpio.HandlerWithError {
+	return httpio.HandlerWithError(
+		func(w http.ResponseWriter, r *http.Request) error {
+			db := deps.DB
+			currentUser := r.Context().Value(httpio.UserContextKey).(models.User)
+			if currentUser.RequiresTwoFactor() {
+				return httpio.TwoFactorRequiredError{}
+			}
+
+			if err := verifyTwoFactorProtectedPayload(db, r, currentUser); err != nil {
+				return err
+			}
+
+			err := db.Transaction(func(tx *gorm.DB) error {
+				if err := tx.Where("user_id = ?", currentUser.ID).Delete(&models.TwoFactorRecoveryCode{}).Error; err != nil {
+					return err
+				}
+
+				return tx.Where("user_id = ?", currentUser.ID).Delete(&models.UserTwoFactor{}).Error
+			})
+			if err != nil {
+				return err
+			}
+
+			return httpio.Encode(w, r, http.StatusOK, httpio.AnyMap{
+				"message": "two-factor authentication disabled",
+			})
+		})
+}
+
+// VerifyLoginChallenge is the second step of the login for users with 2FA enabled
+func VerifyLoginChallenge(deps *config.Dependencies) httpio.HandlerWithError {
+	return httpio.HandlerWithError(
+		func(w http.ResponseWriter, r *http.Request) error {
+			db := deps.DB
+			payload, err := httpio.DecodeAndValidate[VerifyLoginChallengePayload](r, db)
+			if err != nil {
+				return err
+			}
+
+			var challenge models.LoginChallenge
+			db.Where("token_hash = ?", models.HashLookupToken(payload.ChallengeToken)).First(&challenge)
+			if challenge.ID == 0 || !challenge.IsValid() {
+				return httpio.InvalidLoginChallengeError{}
+			}
+
+			twoFactor, err := findConfirmedTwoFactor(db, challenge.UserID)
+			if err != nil {
+				return err
+			}
+			if twoFactor == nil {
+				return httpio.InvalidLoginChallengeError{}
+			}
+
+			var user models.User
+			if err := db.First(&user, challenge.UserID).Error; err != nil {
+				return err
+			}
+
+			// codes are counted against the same limits as passwords, so new challenges don't allow further guessing
+			accountLimit, ipLimit, err := findUnlockedLoginAttemptLimits(db, r, user.Email)
+			if err != nil {
+				return err
+			}
+
+			codeValid, err := useTwoFactorCode(db, twoFactor, payload.Code)
+			if err != nil {
+				return err
+			}
+			if !codeValid {
+				if err := db.Model(&challenge).Update("failed_attempts", gorm.Expr("failed_attempts + 1")).Error; err != nil {
+					return err
+				}
+				if err := registerFailedLogin(deps, r, user, &accountLimit, &ipLimit); err != nil {
+					return err
+				}
+				return invalidTwoFactorCodeError()
+			}
+
+			var tokenPair TokenPair
+			err = db.Transaction(func(tx *gorm.DB) error {
+				// challenge is single-use, concurrent verification with the same challenge fails here
+				result := tx.Where("id = ?", challenge.ID).Delete(&models.LoginChallenge{})
+				if result.Error != nil {
+					return result.Error
+				}
+				if result.RowsAffected == 0 {
+					return httpio.InvalidLoginChallengeError{}
+				}
+
+				if err := resetAccountLoginAttempts(tx, accountLimit); err != nil {
+					return err
+				}
+
+				tokenPair, err = generateTokenPair(deps.JwtKeys, user, tx, r, nil)
+				return err
+			})
+			if err != nil {
+				return err
+			}
+
+			return encodeTokenPair(w, r, tokenPair)
+		})
+}
+
+// SetRoleTwoFactorRequirement allows admins to require 2FA for the role, users with such role can't access privileged endpoints without 2FA
+func SetRoleTwoFactorRequirement(deps *config.Dependencies) httpio.HandlerWithError {
+	return httpio.HandlerWithError(
+		func(w http.ResponseWriter, r *http.Request) error {
+			db := deps.DB
+			payload, err := httpio.DecodeAndValidate[RoleTwoFactorRequirementPayload](r, db)
+			if err != nil {
+				return err
+			}
+
+			var role models.Role
+			if err := db.Preload("Permissions").Where("name = ?", r.PathValue("name")).First(&role).Error; err != nil {
+				if errors.Is(err, gorm.ErrRecordNotFound) {
+					return httpio.RecordNotFoundError{}
+				}
+				return err
+			}
+
+			before := role.BuildResponse()
+			err = db.Transaction(func(tx *gorm.DB) error {
+				if err := tx.Model(&role).Update("requires_two_factor", *payload.Required).Error; err != nil {
+					return err
+				}
+
+				return recordRoleAudit(tx, r, models.AuditActionUpdate, role.ID, before)
+			})
+			if err != nil {
+				return err
+			}
+
+			db.Preload("Permissions").First(&role, role.ID)
+			return httpio.Encode(w, r, http.StatusOK, role.BuildResponse())
+		})
+}
+
+// completeLogin issues token pair, or login challenge if the user has 2FA enabled
+func completeLogin(deps *config.Dependencies, w http.ResponseWriter, r *http.Request, user models.User) error {
+	db := deps.DB
+	if user.IsSuspended() {
+		return user.SuspendedError()
+	}
+
+	twoFactor, err := findConfirmedTwoFactor(db, user.ID)
+	if err != nil {
+		return err
+	}
+
+	if twoFactor != nil {
+		challenge := models.LoginChallenge{UserID: user.ID}
+		plainToken := challenge.GenerateToken()
+		if err := db.Create(&challenge).Error; err != nil {
+			return err
+		}
+
+		return httpio.Encode(w, r, http.StatusOK, LoginChallengeResponse{
+			TwoFactorRequired: true,
+			ChallengeToken:    plainToken,
+			ExpiresIn:         int64(models.LoginChallengeExpirationMinutes * 60),
+		})
+	}
+
+	tokenPair, err := generateTokenPair(deps.JwtKeys, user, db, r, nil)
+	if err != nil {
+		return err
+	}
+
+	return encodeTokenPair(w, r, tokenPair)
+}
+
+func findConfirmedTwoFactor(db *gorm.DB, userID uint) (*models.UserTwoFactor, error) {
+	var twoFactor models.UserTwoFactor
+	err := db.Where("user_id = ? AND confirmed_at IS NOT NULL", userID).First(&twoFactor).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &twoFactor, nil
+}
+
+func verifyTwoFactorProtectedPayload(db *gorm.DB, r *http.Request, currentUser models.User) error {
+	payload, err := httpio.DecodeAndValidate[TwoFactorProtectedPayload](r, db)
+	if err != nil {
+		return err
+	}
+
+	if err := verifyPassword(currentUser, payload.Password, "password"); err != nil {
+		return err
+	}
+
+	twoFactor, err := findConfirmedTwoFactor(db, currentUser.ID)
+	if err != nil {
+		return err
+	}
+	if twoFactor == nil {
+		return httpio.TwoFactorNotEnabledError{}
+	}
+
+	codeValid, err := useTwoFactorCode(db, twoFactor, payload.Code)
+	if err != nil {
+		return err
+	}
+	if !codeValid {
+		return invalidTwoFactorCodeError()
+	}
+
+	return nil
+}
+
+// useTwoFactorCode accepts TOTP code or one of the recovery codes
+func useTwoFactorCode(db *gorm.DB, twoFactor *models.UserTwoFactor, code string) (bool, error) {
+	codeValid, err := twoFactor.UseCode(db, code)
+	if err != nil || codeValid {
+		return codeValid, err
+	}
+
+	return models.UseRecoveryCode(db, twoFactor.UserID, code)
+}
+
+func replaceRecoveryCodes(tx *gorm.DB, userID uint) ([]string, error) {
+	if err := tx.Where("user_id = ?", userID).Delete(&models.TwoFactorRecoveryCode{}).Error; err != nil {
+		return nil, err
+	}
+
+	plainCodes, recoveryCodes := models.GenerateRecoveryCodes(userID)
+	if err := tx.Create(&recoveryCodes).Error; err != nil {
+		return nil, err
+	}
+
+	return plainCodes, nil
+}
+
+func invalidTwoFactorCodeError() error {
+	return httpio.AppValidationError{
+		FieldPath: "code",
+		AppError:  httpio.AppError{Message: "is invalid"},
+	}
+}
+
+func twoFactorIssuer() string {
+	if issuer := os.Getenv("TOTP_ISSUER"); issuer != "" {
+		return issuer
+	}
+
+	return defaultTwoFactorIssuer
+}
@@ -2,12 +2,14 @@ package controllers
 
 import (
 	"net/http"
+	"slices"
 
 	"github.com/filipio/athletics-backend/pkg/config"
 	"github.com/filipio/athletics-backend/internal/models"
 	"github.com/filipio/athletics-backend/pkg/httpio"
 	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 func CreateUser(deps *config.Dependencies) httpio.HandlerWithError {
@@ -18,6 +20,10 @@ func CreateUser(deps *config.Dependencies) httpio.HandlerWithError {
 			return err
 		}
 
+		if err := models.ValidateUsernameAvailable(db, user.Username, 0, user.Email); err != nil {
+			return err
+		}
+
 		// Business logic: Hash password (moved from BeforeCreate hook)
 		if !user.SkipPasswordHashing {
 			hashedPasswordBytes, err := bcrypt.GenerateFromPassword([]byte(user.Password), 10)
@@ -29,10 +35,11 @@ func CreateUser(deps *config.Dependencies) httpio.HandlerWithError {
 
 		// Create user
 		if err := db.Transaction(func(tx *gorm.DB) error {
-			if err := tx.Create(&user).Error; err != nil {
+			// roles can be assigned only through the roles endpoints, which require roles:manage
+			if err := tx.Omit(clause.Associations).Create(&user).Error; err != nil {
 				return err
 			}
-			return nil
+			return models.RecordAudit(tx, r, models.AuditActionCreate, models.AuditResourceUser, user.ID, nil, user.BuildResponse())
 		}); err != nil {
 			return err
 		}
@@ -47,3 +54,165 @@ func CreateUser(deps *config.Dependencies) httpio.HandlerWithError {
 		return nil
 	})
 }
+
+func UpdateUser(deps *config.Dependencies) httpio.HandlerWithError {
+	return httpio.HandlerWithError(func(w http.ResponseWriter, r *http.Request) error {
+		db := deps.DB
+		user, err := httpio.DecodeAndValidate[models.User](r, db)
+		if err != nil {
+			return err
+		}
+
+		id := httpio.IntPathValue(r, "id")
+		var existingUser models.User
+		db.First(&existingUser, id)
+		if existingUser.ID == 0 {
+			return httpio.RecordNotFoundError{}
+		}
+
+		// generated username set by moderation is kept as it is, other changes must follow the username rules
+		if user.Username != existingUser.Username {
+			if err := models.ValidateUsernameAvailable(db, user.Username, existingUser.ID, existingUser.Email); err != nil {
+				return err
+			}
+		}
+
+		// sessions are revoked only when the password really changes
+		passwordChanged := bcrypt.CompareHashAndPassword([]byte(existingUser.Password), []byte(user.Password)) != nil
+
+		hashedPasswordBytes, err := bcrypt.GenerateFromPassword([]byte(user.Password), 10)
+		if err != nil {
+			return err
+		}
+		user.Password = string(hashedPasswordBytes)
+
+		if err := db.Transaction(func(tx *gorm.DB) error {
+			queryResult := user.UpdateQuery(tx.Model(&user), r).Omit(clause.Associations).Updates(&user)
+			if queryResult.Error != nil {
+				return queryResult.Error
+			}
+
+			if queryResult.RowsAffected == 0 {
+				return httpio.RecordNotFoundError{}
+			}
+
+			if passwordChanged {
+				if err := revokeUserSessions(tx, existingUser.ID); err != nil {
+					return err
+				}
+			}
+
+			var updatedUser models.User
+			if err := tx.First(&updatedUser, existingUser.ID).Error; err != nil {
+				return err
+			}
+
+			return models.RecordAudit(tx, r, models.AuditActionUpdate, models.AuditResourceUser, existingUser.ID,
+				userAuditSnapshot{UserResponse: existingUser.BuildResponse().(models.UserResponse)},
+				userAuditSnapshot{UserResponse: updatedUser.BuildResponse().(models.UserResponse), PasswordChanged: passwordChanged})
+		}); err != nil {
+			return err
+		}
+
+		db.Preload("Roles.Permissions").First(&user, id)
+		response := user.BuildResponse()
+
+		if err := httpio.Encode(w, r, http.StatusOK, response); err != nil {
+			return err
+		}
+
+		return nil
+	})
+}
+
+// PatchUser applies JSON merge patch to the user, the password is hashed and sessions are revoked
+// like in UpdateUser, roles are changed only through the role assignment endpoints
+func PatchUser(deps *config.Dependencies) httpio.HandlerWithError {
+	return httpio.HandlerWithError(func(w http.ResponseWriter, r *http.Request) error {
+		db := deps.DB
+		id := uint(httpio.IntPathValue(r, "id"))
+
+		if err := db.Transaction(func(tx *gorm.DB) error {
+			var existingUser models.User
+			result := tx.Limit(1).Find(&existingUser, id)
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected == 0 {
+				return httpio.RecordNotFoundError{}
+			}
+
+			user, keys, err := httpio.DecodeMergePatchAndValidate(r, tx, existingUser)
+			if err != nil {
+				return err
+			}
+
+			if user.Username != existingUser.Username {
+				if err := models.ValidateUsernameAvailable(tx, user.Username, existingUser.ID, existingUser.Email); err != nil {
+					return err
+				}
+			}
+
+			passwordChanged := false
+			if slices.Contains(keys, "password") {
+				passwordChanged = bcrypt.CompareHashAndPassword([]byte(existingUser.Password), []byte(user.Password)) != nil
+
+				hashedPasswordBytes, err := bcrypt.GenerateFromPassword([]byte(user.Password), 10)
+				if err != nil {
+					return err
+				}
+				user.Password = string(hashedPasswordBytes)
+			}
+
+			columns, err := patchedColumns(tx, &user, keys)
+			if err != nil {
+				return err
+			}
+			if len(columns) == 0 {
+				return nil
+			}
+
+			queryResult := user.UpdateQuery(tx.Model(&existingUser), r).Select(columns).Omit(clause.Associations).Updates(&user)
+			if queryResult.Error != nil {
+				return queryResult.Error
+			}
+
+			if queryResult.RowsAffected == 0 {
+				return httpio.RecordNotFoundError{}
+			}
+
+			if passwordChanged {
+				if err := revokeUserSessions(tx, existingUser.ID); err != nil {
+					return err
+				}
+			}
+
+			var updatedUser models.User
+			if err := tx.First(&updatedUser, existingUser.ID).Error; err != nil {
+				return err
+			}
+
+			return models.RecordAudit(tx, r, models.AuditActionUpdate, models.AuditResourceUser, existingUser.ID,
+				userAuditSnapshot{UserResponse: existingUser.BuildResponse().(models.UserResponse)},
+				userAuditSnapshot{UserResponse: updatedUser.BuildResponse().(models.UserResponse), PasswordChanged: passwordChanged})
+		}); err != nil {
+			return err
+		}
+
+		var user models.User
+		db.Preload("Roles.Permissions").First(&user, id)
+		response := user.BuildResponse()
+
+		if err := httpio.Encode(w, r, http.StatusOK, response); err != nil {
+			return err
+		}
+
+		return nil
+	})
+}
+
+// password itself is never recorded, only the fact that it was changed
+type userAuditSnapshot struct {
+	models.UserResponse
+	PasswordChanged bool `json:"password_changed"`
+}
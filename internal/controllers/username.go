@@ -0,0 +1,38 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/filipio/athletics-backend/internal/models"
+	"github.com/filipio/athletics-backend/pkg/config"
+	"github.com/filipio/athletics-backend/pkg/httpio"
+)
+
+type UsernameAvailabilityResponse struct {
+	Username  string  `json:"username"`
+	Available bool    `json:"available"`
+	Reason    *string `json:"reason"`
+}
+
+// CheckUsernameAvailability runs the same checks as registration, so the signup form can show the problem early
+func CheckUsernameAvailability(deps *config.Dependencies) httpio.HandlerWithError {
+	return httpio.HandlerWithError(
+		func(w http.ResponseWriter, r *http.Request) error {
+			username := r.URL.Query().Get("username")
+			email := r.URL.Query().Get("email")
+
+			response := UsernameAvailabilityResponse{Username: username, Available: true}
+
+			err := models.ValidateUsernameAvailable(deps.DB, username, 0, email)
+			var validationErr httpio.AppValidationError
+			if errors.As(err, &validationErr) {
+				response.Available = false
+				response.Reason = &validationErr.Message
+			} else if err != nil {
+				return err
+			}
+
+			return httpio.Encode(w, r, http.StatusOK, response)
+		})
+}
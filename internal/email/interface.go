@@ -10,6 +10,10 @@ import (
 
 type EmailSender interface {
 	SendVerificationEmail(ctx context.Context, params VerificationEmailParams) error
+	SendPasswordResetEmail(ctx context.Context, params PasswordResetEmailParams) error
+	SendEmailChangeConfirmationEmail(ctx context.Context, params EmailChangeConfirmationEmailParams) error
+	SendSecurityAlertEmail(ctx context.Context, params SecurityAlertEmailParams) error
+	SendMagicLinkEmail(ctx context.Context, params MagicLinkEmailParams) error
 }
 
 type ResendEmailSender struct {
@@ -34,6 +38,64 @@ func (s *ResendEmailSender) SendVerificationEmail(ctx context.Context, params Ve
 	return err
 }
 
+func (s *ResendEmailSender) SendPasswordResetEmail(ctx context.Context, params PasswordResetEmailParams) error {
+	emailParams := resend.SendEmailRequest{
+		To: []string{params.To},
+		Template: &resend.EmailTemplate{
+			Id: PasswordResetTemplateID,
+			Variables: map[string]any{
+				"token": params.ResetToken,
+			},
+		},
+	}
+	_, err := s.client.Emails.SendWithContext(ctx, &emailParams)
+	return err
+}
+
+func (s *ResendEmailSender) SendEmailChangeConfirmationEmail(ctx context.Context, params EmailChangeConfirmationEmailParams) error {
+	emailParams := resend.SendEmailRequest{
+		To: []string{params.To},
+		Template: &resend.EmailTemplate{
+			Id: EmailChangeConfirmationTemplateID,
+			Variables: map[string]any{
+				"token": params.ConfirmationToken,
+			},
+		},
+	}
+	_, err := s.client.Emails.SendWithContext(ctx, &emailParams)
+	return err
+}
+
+func (s *ResendEmailSender) SendSecurityAlertEmail(ctx context.Context, params SecurityAlertEmailParams) error {
+	emailParams := resend.SendEmailRequest{
+		To: []string{params.To},
+		Template: &resend.EmailTemplate{
+			Id: SecurityAlertTemplateID,
+			Variables: map[string]any{
+				"alert":      params.Alert,
+				"ip_address": params.IPAddress,
+				"user_agent": params.UserAgent,
+			},
+		},
+	}
+	_, err := s.client.Emails.SendWithContext(ctx, &emailParams)
+	return err
+}
+
+func (s *ResendEmailSender) SendMagicLinkEmail(ctx context.Context, params MagicLinkEmailParams) error {
+	emailParams := resend.SendEmailRequest{
+		To: []string{params.To},
+		Template: &resend.EmailTemplate{
+			Id: MagicLinkTemplateID,
+			Variables: map[string]any{
+				"token": params.LoginToken,
+			},
+		},
+	}
+	_, err := s.client.Emails.SendWithContext(ctx, &emailParams)
+	return err
+}
+
 func GetDefaultEmailSender() EmailSender {
 	return NewResendEmailSender(GetClient())
 }
@@ -11,6 +11,28 @@ type VerificationEmailParams struct {
 	VerificationToken string
 }
 
+type PasswordResetEmailParams struct {
+	To         string
+	ResetToken string
+}
+
+type EmailChangeConfirmationEmailParams struct {
+	To                string
+	ConfirmationToken string
+}
+
+type SecurityAlertEmailParams struct {
+	To        string
+	Alert     string
+	IPAddress string
+	UserAgent string
+}
+
+type MagicLinkEmailParams struct {
+	To         string
+	LoginToken string
+}
+
 func SendVerificationEmail(ctx context.Context, params VerificationEmailParams) error {
 	client := GetClient()
 
@@ -2,4 +2,9 @@ package email
 
 const (
 	EmailVerificationTemplateID = "d27326b1-3311-4ace-baa2-47fae6e40f5a"
+	// resend accepts either id or alias of the published template
+	PasswordResetTemplateID           = "password-reset"
+	EmailChangeConfirmationTemplateID = "email-change-confirmation"
+	SecurityAlertTemplateID           = "security-alert"
+	MagicLinkTemplateID               = "magic-link"
 )
@@ -4,8 +4,6 @@ import (
 	"context"
 	"errors"
 	"net/http"
-	"os"
-	"slices"
 	"strings"
 	"time"
 
@@ -24,77 +22,143 @@ func NewAuthMiddleware(deps *config.Dependencies) *AuthMiddleware {
 	return &AuthMiddleware{deps: deps}
 }
 
-func (a *AuthMiddleware) AdminOnly(next httpio.HandlerWithError) httpio.HandlerWithError {
-	return a.authMiddleware(next, httpio.AdminRole)
+// UserOnly allows any authenticated user
+func (a *AuthMiddleware) UserOnly(next httpio.HandlerWithError) httpio.HandlerWithError {
+	return a.authMiddleware(next, "", true)
 }
 
-func (a *AuthMiddleware) UserOnly(next httpio.HandlerWithError) httpio.HandlerWithError {
-	return a.authMiddleware(next, httpio.UserRole)
+// SessionOnly allows any user authenticated with a JWT, it protects account management which personal access tokens can't be used for
+func (a *AuthMiddleware) SessionOnly(next httpio.HandlerWithError) httpio.HandlerWithError {
+	return a.authMiddleware(next, "", false)
 }
 
-func (a *AuthMiddleware) OrganizerOnly(next httpio.HandlerWithError) httpio.HandlerWithError {
-	return a.authMiddleware(next, httpio.OrganizerRole)
+// Require allows only users who have the permission through any of their roles.
+// Personal access tokens must additionally have the permission in their scopes.
+func (a *AuthMiddleware) Require(permission string) func(httpio.HandlerWithError) httpio.HandlerWithError {
+	return func(next httpio.HandlerWithError) httpio.HandlerWithError {
+		return a.authMiddleware(next, permission, true)
+	}
 }
 
-func (a *AuthMiddleware) authMiddleware(next httpio.HandlerWithError, requiredRole string) httpio.HandlerWithError {
+// requiredPermission is empty for endpoints available to every authenticated user
+func (a *AuthMiddleware) authMiddleware(next httpio.HandlerWithError, requiredPermission string, allowPersonalAccessTokens bool) httpio.HandlerWithError {
 	return httpio.HandlerWithError(func(w http.ResponseWriter, r *http.Request) error {
 		tokenString, extractionError := extractToken(r)
 		if extractionError != nil {
 			return extractionError
 		}
 
-		token, parsingError := parseToken(tokenString)
-		if parsingError != nil {
-			return httpio.JwtTokenParsingError{AppError: httpio.AppError{Message: parsingError.Error()}}
+		var clientContext context.Context
+		var err error
+		if models.IsPersonalAccessToken(tokenString) {
+			if !allowPersonalAccessTokens {
+				return httpio.ActionForbiddenError{}
+			}
+			clientContext, err = a.personalAccessTokenContext(r, tokenString)
+		} else {
+			clientContext, err = a.sessionContext(r, tokenString)
 		}
-
-		claims, ok := token.Claims.(jwt.MapClaims)
-		if !ok || !token.Valid {
-			return httpio.InvalidJwtClaimsError{}
+		if err != nil {
+			return err
 		}
 
-		if float64(time.Now().Unix()) > claims["exp"].(float64) {
-			return httpio.JwtTokenExpiredError{}
+		user := clientContext.Value(httpio.UserContextKey).(models.User)
+		if user.IsSuspended() {
+			return user.SuspendedError()
 		}
 
-		if !requiredRoleFound(claims, requiredRole) {
-			return httpio.ActionForbiddenError{}
-		}
+		// permissions are read from the database, so changes of roles apply without waiting for a new token
+		if requiredPermission != "" {
+			if !user.HasPermission(requiredPermission) {
+				return httpio.ActionForbiddenError{}
+			}
 
-		// Validate session if session_id is present in claims
-		sessionID, hasSessionID := claims["session_id"].(string)
-		if hasSessionID && sessionID != "" {
-			db := a.deps.DB
-			var refreshToken models.RefreshToken
-			err := db.Where("session_id = ? AND revoked_at IS NULL AND expires_at > ?",
-				sessionID, time.Now()).First(&refreshToken).Error
-			if err != nil {
-				if errors.Is(err, gorm.ErrRecordNotFound) {
-					return httpio.SessionExpiredError{}
-				}
+			if err := a.checkTwoFactorRequirement(user); err != nil {
 				return err
 			}
 		}
 
-		clientContext, err := a.buildClientContext(r, claims)
+		return next.ServeHTTP(w, r.WithContext(clientContext))
+	})
+}
+
+func (a *AuthMiddleware) sessionContext(r *http.Request, tokenString string) (context.Context, error) {
+	token, parsingError := a.deps.JwtKeys.Parse(tokenString)
+	if errors.Is(parsingError, jwt.ErrTokenExpired) {
+		return nil, httpio.JwtTokenExpiredError{}
+	}
+	if parsingError != nil {
+		return nil, httpio.JwtTokenParsingError{AppError: httpio.AppError{Message: parsingError.Error()}}
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || !token.Valid {
+		return nil, httpio.InvalidJwtClaimsError{}
+	}
+
+	// Validate session if session_id is present in claims
+	sessionID, hasSessionID := claims["session_id"].(string)
+	if hasSessionID && sessionID != "" {
+		db := a.deps.DB
+		var refreshToken models.RefreshToken
+		err := db.Where("session_id = ? AND revoked_at IS NULL AND used_at IS NULL AND expires_at > ?",
+			sessionID, time.Now()).First(&refreshToken).Error
 		if err != nil {
-			return err
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, httpio.SessionExpiredError{}
+			}
+			return nil, err
 		}
 
-		// Add session_id to context for logout functionality
-		if sessionID != "" {
-			clientContext = context.WithValue(clientContext, httpio.SessionIDContextKey, sessionID)
+		if err := refreshToken.TouchLastUsed(db); err != nil {
+			return nil, err
 		}
+	}
 
-		return next.ServeHTTP(w, r.WithContext(clientContext))
-	})
+	clientContext, err := a.buildClientContext(r, claims)
+	if err != nil {
+		return nil, err
+	}
+
+	// Add session_id to context for logout functionality
+	if sessionID != "" {
+		clientContext = context.WithValue(clientContext, httpio.SessionIDContextKey, sessionID)
+	}
+
+	return clientContext, nil
+}
+
+// personalAccessTokenContext authenticates the owner of the token, whose permissions are limited to the token scopes
+func (a *AuthMiddleware) personalAccessTokenContext(r *http.Request, tokenString string) (context.Context, error) {
+	db := a.deps.DB
+	personalAccessToken, err := models.FindActivePersonalAccessToken(db, tokenString)
+	if err != nil {
+		return nil, err
+	}
+	if personalAccessToken == nil {
+		return nil, httpio.InvalidPersonalAccessTokenError{}
+	}
+
+	if err := personalAccessToken.TouchLastUsed(db, httpio.ClientIP(r)); err != nil {
+		return nil, err
+	}
+
+	var user models.User
+	db.Preload("Roles.Permissions").First(&user, personalAccessToken.UserID)
+	if user.ID == 0 {
+		return nil, httpio.UserNotFoundError{}
+	}
+	user.RestrictToScopes(personalAccessToken.Scopes)
+
+	clientContext := context.WithValue(r.Context(), httpio.UserContextKey, user)
+	return context.WithValue(clientContext, httpio.PersonalAccessTokenIDContextKey, personalAccessToken.ID), nil
 }
 
 func (a *AuthMiddleware) buildClientContext(r *http.Request, claims jwt.MapClaims) (context.Context, error) {
 	userID := claims["sub"]
 
 	var user models.User
-	a.deps.DB.Preload("Roles").First(&user, userID)
+	a.deps.DB.Preload("Roles.Permissions").First(&user, userID)
 	if user.ID == 0 {
 		return nil, httpio.UserNotFoundError{}
 	}
@@ -102,44 +166,45 @@ func (a *AuthMiddleware) buildClientContext(r *http.Request, claims jwt.MapClaim
 	return context.WithValue(r.Context(), httpio.UserContextKey, user), nil
 }
 
-func extractToken(r *http.Request) (string, error) {
-	authHeader := r.Header.Get("Authorization")
-	if authHeader == "" {
-		return "", httpio.AuthHeaderMissingError{}
+// checkTwoFactorRequirement blocks privileged actions of users whose role requires 2FA until they enable it
+func (a *AuthMiddleware) checkTwoFactorRequirement(user models.User) error {
+	if !user.RequiresTwoFactor() {
+		return nil
 	}
 
-	parts := strings.Split(authHeader, " ")
-	if len(parts) != 2 || strings.ToLower(parts[0]) != "bearer" {
-		return "", httpio.InvalidAuthHeaderError{}
+	var confirmedCount int64
+	if err := a.deps.DB.Model(&models.UserTwoFactor{}).
+		Where("user_id = ? AND confirmed_at IS NOT NULL", user.ID).
+		Count(&confirmedCount).Error; err != nil {
+		return err
 	}
 
-	return parts[1], nil
-}
-
-func parseToken(tokenString string) (*jwt.Token, error) {
-	return jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, errors.New("unexpected jwt signing method")
-		}
+	if confirmedCount == 0 {
+		return httpio.TwoFactorRequiredError{}
+	}
 
-		return []byte(os.Getenv("JWT_SIGNING_SECRET")), nil
-	})
+	return nil
 }
 
-func requiredRoleFound(claims jwt.MapClaims, requiredRole string) bool {
-	userRolesInterface, ok := claims["roles"].([]interface{})
-	if !ok || userRolesInterface == nil {
-		return false
-	}
+// extractToken reads the token from Authorization header, or from the access token cookie in the cookie auth mode.
+// Cookies are sent by the browser automatically, so state-changing requests authenticated with them must pass CSRF check.
+func extractToken(r *http.Request) (string, error) {
+	authHeader := r.Header.Get("Authorization")
+	if authHeader == "" {
+		if cookie, err := r.Cookie(httpio.AccessTokenCookieName); err == nil && cookie.Value != "" {
+			if err := httpio.VerifyCsrfToken(r); err != nil {
+				return "", err
+			}
+			return cookie.Value, nil
+		}
 
-	userRoles := make([]string, len(userRolesInterface))
-	for i, v := range userRolesInterface {
-		userRoles[i] = v.(string)
+		return "", httpio.AuthHeaderMissingError{}
 	}
 
-	if slices.Contains(userRoles, httpio.AdminRole) {
-		return true
+	parts := strings.Split(authHeader, " ")
+	if len(parts) != 2 || strings.ToLower(parts[0]) != "bearer" {
+		return "", httpio.InvalidAuthHeaderError{}
 	}
 
-	return slices.Contains(userRoles, requiredRole)
+	return parts[1], nil
 }
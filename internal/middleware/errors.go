@@ -3,6 +3,7 @@ package middleware
 import (
 	"fmt"
 	"net/http"
+	"strconv"
 
 	"github.com/filipio/athletics-backend/pkg/httpio"
 	"github.com/go-playground/validator/v10"
@@ -11,6 +12,10 @@ import (
 func ErrorsMiddleware(next httpio.HandlerWithError) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if err := next(w, r); err != nil {
+			if rateLimitErr, ok := err.(httpio.RateLimitError); ok && rateLimitErr.RetryAfterSeconds > 0 {
+				w.Header().Set("Retry-After", strconv.Itoa(rateLimitErr.RetryAfterSeconds))
+			}
+
 			httpStatus, httpError := errorResponse(err)
 			httpio.Encode(w, r, httpStatus, httpError)
 		}
@@ -121,6 +126,17 @@ func errorResponse(err error) (httpStatus int, errorResponse httpio.ErrorsRespon
 		}
 	}
 
+	if err, ok := err.(httpio.RateLimitError); ok {
+		details := "too many attempts, please try again later"
+		if err.BlockedUntil != nil {
+			details = fmt.Sprintf("too many attempts, blocked until %s", *err.BlockedUntil)
+		}
+		return http.StatusTooManyRequests, httpio.ErrorsResponse{
+			ErrorType: "rate_limit_error",
+			Details:   details,
+		}
+	}
+
 	if _, ok := err.(httpio.InvalidVerificationTokenError); ok {
 		return http.StatusBadRequest, httpio.ErrorsResponse{
 			ErrorType: "validation_error",
@@ -128,6 +144,13 @@ func errorResponse(err error) (httpStatus int, errorResponse httpio.ErrorsRespon
 		}
 	}
 
+	if _, ok := err.(httpio.InvalidPasswordResetTokenError); ok {
+		return http.StatusBadRequest, httpio.ErrorsResponse{
+			ErrorType: "validation_error",
+			Details:   "invalid or expired password reset token",
+		}
+	}
+
 	if err, ok := err.(httpio.EmailSendError); ok {
 		return http.StatusInternalServerError, httpio.ErrorsResponse{
 			ErrorType: "email_error",
@@ -156,6 +179,108 @@ func errorResponse(err error) (httpStatus int, errorResponse httpio.ErrorsRespon
 		}
 	}
 
+	if _, ok := err.(httpio.StatsNotAvailableError); ok {
+		return http.StatusForbidden, httpio.ErrorsResponse{
+			ErrorType: "forbidden_error",
+			Details:   "statistics are available after the event deadline",
+		}
+	}
+
+	if _, ok := err.(httpio.OidcProviderNotFoundError); ok {
+		return http.StatusNotFound, httpio.ErrorsResponse{
+			ErrorType: "not_found",
+			Details:   "login provider not found",
+		}
+	}
+
+	if _, ok := err.(httpio.InvalidOidcStateError); ok {
+		return http.StatusBadRequest, httpio.ErrorsResponse{
+			ErrorType: "validation_error",
+			Details:   "invalid or expired login state",
+		}
+	}
+
+	if _, ok := err.(httpio.OidcLoginError); ok {
+		return http.StatusUnauthorized, httpio.ErrorsResponse{
+			ErrorType: "login_error",
+			Details:   "login with external provider failed",
+		}
+	}
+
+	if _, ok := err.(httpio.OidcEmailNotVerifiedError); ok {
+		return http.StatusUnauthorized, httpio.ErrorsResponse{
+			ErrorType: "login_error",
+			Details:   "email is not verified by login provider",
+		}
+	}
+
+	if _, ok := err.(httpio.TwoFactorRequiredError); ok {
+		return http.StatusForbidden, httpio.ErrorsResponse{
+			ErrorType: "two_factor_required",
+			Details:   "two-factor authentication must be enabled to perform this action",
+		}
+	}
+
+	if _, ok := err.(httpio.TwoFactorAlreadyEnabledError); ok {
+		return http.StatusBadRequest, httpio.ErrorsResponse{
+			ErrorType: "validation_error",
+			Details:   "two-factor authentication is already enabled",
+		}
+	}
+
+	if _, ok := err.(httpio.TwoFactorNotEnabledError); ok {
+		return http.StatusBadRequest, httpio.ErrorsResponse{
+			ErrorType: "validation_error",
+			Details:   "two-factor authentication is not enabled",
+		}
+	}
+
+	if _, ok := err.(httpio.InvalidLoginChallengeError); ok {
+		return http.StatusUnauthorized, httpio.ErrorsResponse{
+			ErrorType: "auth_error",
+			Details:   "invalid or expired login challenge",
+		}
+	}
+
+	if err, ok := err.(httpio.UserSuspendedError); ok {
+		details := "account is banned"
+		if err.SuspendedUntil != nil {
+			details = fmt.Sprintf("account is suspended until %s", *err.SuspendedUntil)
+		}
+		return http.StatusForbidden, httpio.ErrorsResponse{
+			ErrorType: "account_suspended",
+			Details:   details,
+		}
+	}
+
+	if _, ok := err.(httpio.InvalidPersonalAccessTokenError); ok {
+		return http.StatusUnauthorized, httpio.ErrorsResponse{
+			ErrorType: "auth_error",
+			Details:   "invalid, expired or revoked personal access token",
+		}
+	}
+
+	if _, ok := err.(httpio.CsrfTokenMismatchError); ok {
+		return http.StatusForbidden, httpio.ErrorsResponse{
+			ErrorType: "csrf_error",
+			Details:   "header 'X-CSRF-Token' is missing or doesn't match the CSRF cookie",
+		}
+	}
+
+	if _, ok := err.(httpio.InvalidMagicLinkTokenError); ok {
+		return http.StatusUnauthorized, httpio.ErrorsResponse{
+			ErrorType: "auth_error",
+			Details:   "invalid, expired or already used login link",
+		}
+	}
+
+	if _, ok := err.(httpio.PreconditionFailedError); ok {
+		return http.StatusPreconditionFailed, httpio.ErrorsResponse{
+			ErrorType: "precondition_failed",
+			Details:   "resource was modified in the meantime, fetch it again and retry with its current ETag",
+		}
+	}
+
 	return http.StatusInternalServerError, httpio.ErrorsResponse{
 		ErrorType: "internal_server_error",
 		Details:   err.Error(),
@@ -4,7 +4,6 @@ import (
 	"encoding/json"
 	"errors"
 	"net/http"
-	"strings"
 	"time"
 
 	"github.com/filipio/athletics-backend/pkg/httpio"
@@ -94,25 +93,22 @@ func (m Answer) Validate(db *gorm.DB) error {
 	return event.IsPresent()
 }
 
-func (m Answer) GetAllQuery(db *gorm.DB, r *http.Request) *gorm.DB {
-	db = onlyCurrentUserRecords(db, r)
-	db = getByIds(db, r)
-
-	queryParams := r.URL.Query()
-	if queryParams.Has("question_ids") {
-		questionIds := strings.Split(queryParams.Get("question_ids"), ",")
-		db = db.Where("question_id IN (?)", questionIds)
-	}
-
-	if queryParams.Has("user_id") {
-		db = db.Where("user_id = ?", queryParams.Get("user_id"))
-	}
-	if queryParams.Has("event_id") {
-		db = db.Joins("JOIN questions ON questions.id = answers.question_id").
-			Where("questions.event_id = ?", queryParams.Get("event_id"))
-	}
+func (m Answer) ListFields() map[string]httpio.ListField {
+	return listFields(map[string]httpio.ListField{
+		"question_id": {Type: httpio.FieldTypeInt, Operators: []string{httpio.FilterEq, httpio.FilterIn},
+			LegacyParams: map[string]httpio.LegacyParam{"question_ids": {Operator: httpio.FilterIn}}},
+		"user_id": {Type: httpio.FieldTypeInt, Operators: []string{httpio.FilterEq, httpio.FilterIn},
+			LegacyParams: map[string]httpio.LegacyParam{"user_id": {Operator: httpio.FilterEq}}},
+		"points":            {Type: httpio.FieldTypeInt, Operators: []string{httpio.FilterEq, httpio.FilterGte, httpio.FilterLt}, Sortable: true},
+		"points_granted_at": {Type: httpio.FieldTypeTime, Operators: []string{httpio.FilterGte, httpio.FilterLt}, Sortable: true},
+		"event_id": {Type: httpio.FieldTypeInt, Operators: []string{httpio.FilterEq, httpio.FilterIn}, Query: func(db *gorm.DB, operator string, values []any) *gorm.DB {
+			return db.Where("answers.question_id IN (SELECT id FROM questions WHERE event_id IN ?)", values)
+		}, LegacyParams: map[string]httpio.LegacyParam{"event_id": {Operator: httpio.FilterEq}}},
+	})
+}
 
-	return db
+func (m Answer) GetAllQuery(db *gorm.DB, r *http.Request) *gorm.DB {
+	return onlyCurrentUserRecords(db, r)
 }
 
 func (m Answer) GetQuery(db *gorm.DB, r *http.Request) *gorm.DB {
@@ -125,6 +121,12 @@ func (m Answer) BuildResponse() any {
 	return m
 }
 
+// ActiveAnswers skips answers to deleted questions and to questions of deleted events, they don't count in rankings
+func ActiveAnswers(db *gorm.DB) *gorm.DB {
+	return db.Where(`answers.question_id IN (SELECT id FROM questions WHERE deleted_at IS NULL
+		AND event_id NOT IN (SELECT id FROM events WHERE deleted_at IS NOT NULL))`)
+}
+
 func onlyCurrentUserRecords(db *gorm.DB, r *http.Request) *gorm.DB {
 	onlyForCurrentUser := r.Context().Value(httpio.OnlyCurrentUserContextKey).(bool)
 	if onlyForCurrentUser {
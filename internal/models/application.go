@@ -8,6 +8,7 @@ import (
 
 	"github.com/filipio/athletics-backend/pkg/httpio"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // defines the base model for all models in the application
@@ -17,6 +18,22 @@ type AppModel struct {
 	UpdatedAt time.Time `json:"updated_at"`
 }
 
+// SoftDeleteModel is used instead of AppModel by models whose rows are only marked as deleted, so they can be restored.
+// Deleted rows are skipped by all gorm queries of the model unless the query is unscoped.
+// Table of the model belongs to softDeleteTables of the id_of validation, so deleted rows can't be referenced
+type SoftDeleteModel struct {
+	AppModel
+	DeletedAt gorm.DeletedAt `json:"deleted_at" gorm:"index"`
+}
+
+// IncludeDeletedParam is the query parameter which makes users with the write permission of the resource see also deleted rows
+const IncludeDeletedParam = "include_deleted"
+
+// SoftDeletable is implemented by models which embed SoftDeleteModel, the permission is required to see and restore deleted rows
+type SoftDeletable interface {
+	WritePermission() string
+}
+
 func (m AppModel) GetID() uint {
 	return m.ID
 }
@@ -58,6 +75,11 @@ func (m AppModel) GetQuery(db *gorm.DB, r *http.Request) *gorm.DB {
 	return GetByIdQuery(db, r)
 }
 
+// ListFields declares the fields which can be used to filter and sort the list of the model
+func (m AppModel) ListFields() map[string]httpio.ListField {
+	return listFields(nil)
+}
+
 func (m AppModel) UpdateQuery(db *gorm.DB, r *http.Request) *gorm.DB {
 	return baseUpdateQuery(m.GetQuery(db, r))
 }
@@ -66,6 +88,30 @@ func (m AppModel) DeleteQuery(db *gorm.DB, r *http.Request) *gorm.DB {
 	return m.GetQuery(db, r)
 }
 
+func (m SoftDeleteModel) RestoreQuery(db *gorm.DB, r *http.Request) *gorm.DB {
+	return GetByIdQuery(db.Unscoped(), r).Where("deleted_at IS NOT NULL")
+}
+
+// WithDeleted makes the query return also soft deleted rows when they were asked for with include_deleted=true
+// by a user with the write permission of the model
+func WithDeleted(db *gorm.DB, r *http.Request, model any) (*gorm.DB, error) {
+	if r.URL.Query().Get(IncludeDeletedParam) != "true" {
+		return db, nil
+	}
+
+	softDeletable, ok := model.(SoftDeletable)
+	if !ok {
+		return db, nil
+	}
+
+	user, ok := r.Context().Value(httpio.UserContextKey).(User)
+	if !ok || !user.HasPermission(softDeletable.WritePermission()) {
+		return nil, httpio.ActionForbiddenError{}
+	}
+
+	return db.Unscoped(), nil
+}
+
 func GetByIdQuery(db *gorm.DB, r *http.Request) *gorm.DB {
 	id := httpio.IntPathValue(r, "id")
 	return db.Where("id = ?", id)
@@ -118,17 +164,14 @@ func Paginate[T any](query *gorm.DB, r *http.Request, paginationOptions *httpio.
 	return httpio.BuildPaginatedResponse(responseRecords, totalCount, paginationParams), nil
 }
 
+// PaginateQuery limits the query to the page, the order column is quoted, so it can't inject SQL
 func PaginateQuery(db *gorm.DB, paginationParams *httpio.PaginationParams) *gorm.DB {
-	return db.Offset((paginationParams.PageNo - 1) * paginationParams.PerPage).
-		Limit(paginationParams.PerPage).Order(paginationParams.OrderBy + " " + paginationParams.OrderDirection)
+	return PageQuery(db, paginationParams).Order(clause.OrderByColumn{
+		Column: clause.Column{Name: paginationParams.OrderBy},
+		Desc:   strings.EqualFold(paginationParams.OrderDirection, "desc"),
+	})
 }
 
-func getByIds(db *gorm.DB, r *http.Request) *gorm.DB {
-	queryParams := r.URL.Query()
-	if queryParams.Has("ids") {
-		ids := strings.Split(queryParams.Get("ids"), ",")
-		return db.Where("id in (?)", ids)
-	} else {
-		return db
-	}
+func PageQuery(db *gorm.DB, paginationParams *httpio.PaginationParams) *gorm.DB {
+	return db.Offset((paginationParams.PageNo - 1) * paginationParams.PerPage).Limit(paginationParams.PerPage)
 }
@@ -4,6 +4,7 @@ import (
 	"net/http"
 	"strings"
 
+	"github.com/filipio/athletics-backend/pkg/httpio"
 	"gorm.io/datatypes"
 	"gorm.io/gorm"
 )
@@ -18,35 +19,27 @@ type Athlete struct {
 	Disciplines []Discipline    `json:"disciplines" gorm:"many2many:athletes_disciplines;constraint:OnDelete:CASCADE"`
 }
 
-func (m Athlete) GetAllQuery(db *gorm.DB, r *http.Request) *gorm.DB {
-	db = db.Preload("Disciplines")
-	db = getByIds(db, r)
-
-	queryParams := r.URL.Query()
-
-	if queryParams.Has("search") {
-		searchTerm := "%" + strings.ToLower(queryParams.Get("search")) + "%"
-		db = db.Where("first_name || ' ' || last_name LIKE ? OR last_name || ' ' || first_name LIKE ?", searchTerm, searchTerm)
-	}
-
-	if queryParams.Has("discipline_ids") {
-		disciplineIds := strings.Split(queryParams.Get("discipline_ids"), ",")
-
-		db = db.Joins("JOIN athletes_disciplines ON athletes_disciplines.athlete_id = athletes.id").
-			Where("athletes_disciplines.discipline_id IN (?)", disciplineIds)
-	}
-
-	if queryParams.Has("country") {
-		country := strings.ToUpper(queryParams.Get("country"))
-		db = db.Where("country = ?", country)
-	}
-
-	if queryParams.Has("gender") {
-		gender := strings.ToLower(queryParams.Get("gender"))
-		db = db.Where("gender = ?", gender)
-	}
+func (m Athlete) ListFields() map[string]httpio.ListField {
+	return listFields(map[string]httpio.ListField{
+		"first_name": {Operators: []string{httpio.FilterEq, httpio.FilterLike}, Sortable: true},
+		"last_name":  {Operators: []string{httpio.FilterEq, httpio.FilterLike}, Sortable: true},
+		"country": {Operators: []string{httpio.FilterEq, httpio.FilterIn}, Sortable: true,
+			LegacyParams: map[string]httpio.LegacyParam{"country": {Operator: httpio.FilterEq, Normalize: strings.ToUpper}}},
+		"gender": {Operators: []string{httpio.FilterEq},
+			LegacyParams: map[string]httpio.LegacyParam{"gender": {Operator: httpio.FilterEq, Normalize: strings.ToLower}}},
+		"birthday": {Type: httpio.FieldTypeTime, Operators: []string{httpio.FilterGte, httpio.FilterLt}, Sortable: true},
+		// full name in any order of first and last name
+		"name": {Operators: []string{httpio.FilterLike}, Query: func(db *gorm.DB, operator string, values []any) *gorm.DB {
+			return db.Where("(first_name || ' ' || last_name ILIKE ? OR last_name || ' ' || first_name ILIKE ?)", values[0], values[0])
+		}, LegacyParams: map[string]httpio.LegacyParam{"search": {Operator: httpio.FilterLike}}},
+		"discipline_id": {Type: httpio.FieldTypeInt, Operators: []string{httpio.FilterEq, httpio.FilterIn}, Query: func(db *gorm.DB, operator string, values []any) *gorm.DB {
+			return db.Where("athletes.id IN (SELECT athlete_id FROM athletes_disciplines WHERE discipline_id IN ?)", values)
+		}, LegacyParams: map[string]httpio.LegacyParam{"discipline_ids": {Operator: httpio.FilterIn}}},
+	})
+}
 
-	return db
+func (m Athlete) GetAllQuery(db *gorm.DB, r *http.Request) *gorm.DB {
+	return db.Preload("Disciplines")
 }
 
 func (m Athlete) GetQuery(db *gorm.DB, r *http.Request) *gorm.DB {
@@ -0,0 +1,146 @@
+package models
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+
+	"github.com/filipio/athletics-backend/pkg/httpio"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+// moderation of users is recorded under the names of its moderation actions
+const (
+	AuditActionCreate         = "create"
+	AuditActionUpdate         = "update"
+	AuditActionDelete         = "delete"
+	AuditActionRestore        = "restore"
+	AuditActionPublish        = "publish"
+	AuditActionUnpublish      = "unpublish"
+	AuditActionRevokeSessions = "revoke_sessions"
+	AuditActionUnlockLogin    = "unlock_login"
+)
+
+const (
+	AuditResourceEvent    = "event"
+	AuditResourceQuestion = "question"
+	AuditResourceUser     = "user"
+	AuditResourceRole     = "role"
+)
+
+// fields which change with every update, they would only add noise to the diff
+var auditIgnoredFields = []string{"updated_at"}
+
+// AuditLog records privileged change made by organizer or admin, the table is append-only (enforced by the trigger)
+type AuditLog struct {
+	AppModel
+	// actor is kept without foreign key, so the entries outlive deleted users
+	ActorID               *uint          `json:"actor_id" gorm:"index"`
+	SessionID             *string        `json:"session_id"`
+	PersonalAccessTokenID *uint          `json:"personal_access_token_id"`
+	IPAddress             string         `json:"ip_address" gorm:"not null;default:''"`
+	Action                string         `json:"action" gorm:"not null;index"`
+	ResourceType          string         `json:"resource_type" gorm:"not null;index:idx_audit_logs_resource"`
+	ResourceID            uint           `json:"resource_id" gorm:"not null;index:idx_audit_logs_resource"`
+	Changes               datatypes.JSON `json:"changes" gorm:"not null;default:'{}'"`
+}
+
+// AuditChange is the value of a single field before and after the change, nil for created and deleted resources
+type AuditChange struct {
+	Before any `json:"before"`
+	After  any `json:"after"`
+}
+
+func (m AuditLog) ListFields() map[string]httpio.ListField {
+	return listFields(map[string]httpio.ListField{
+		"action":        {Operators: []string{httpio.FilterEq, httpio.FilterIn}, LegacyParams: legacyInParam("action")},
+		"resource_type": {Operators: []string{httpio.FilterEq, httpio.FilterIn}, LegacyParams: legacyInParam("resource_type")},
+		"resource_id":   {Type: httpio.FieldTypeInt, Operators: []string{httpio.FilterEq, httpio.FilterIn}, LegacyParams: legacyInParam("resource_id")},
+		"actor_id":      {Type: httpio.FieldTypeInt, Operators: []string{httpio.FilterEq, httpio.FilterIn}, LegacyParams: legacyInParam("actor_id")},
+		"session_id":    {Operators: []string{httpio.FilterEq, httpio.FilterIn}, LegacyParams: legacyInParam("session_id")},
+		"created_at": {Type: httpio.FieldTypeTime, Operators: []string{httpio.FilterGte, httpio.FilterLt}, Sortable: true,
+			LegacyParams: map[string]httpio.LegacyParam{"from": {Operator: httpio.FilterGte}, "to": {Operator: httpio.FilterLt}}},
+	})
+}
+
+// legacyInParam accepts the filter also as the plain parameter of the same name with comma separated values
+func legacyInParam(name string) map[string]httpio.LegacyParam {
+	return map[string]httpio.LegacyParam{name: {Operator: httpio.FilterIn}}
+}
+
+func (m AuditLog) BuildResponse() any {
+	return m
+}
+
+// RecordAudit writes the entry in the transaction of the change, so the change and its entry are committed together.
+// Before and after are snapshots of the resource (nil when it is created or deleted), only the differing fields are stored
+func RecordAudit(tx *gorm.DB, r *http.Request, action string, resourceType string, resourceID uint, before any, after any) error {
+	changes, err := auditDiff(before, after)
+	if err != nil {
+		return err
+	}
+
+	auditLog := AuditLog{
+		IPAddress:    httpio.ClientIP(r),
+		Action:       action,
+		ResourceType: resourceType,
+		ResourceID:   resourceID,
+		Changes:      changes,
+	}
+
+	if actor, ok := r.Context().Value(httpio.UserContextKey).(User); ok {
+		auditLog.ActorID = &actor.ID
+	}
+	if sessionID, ok := r.Context().Value(httpio.SessionIDContextKey).(string); ok && sessionID != "" {
+		auditLog.SessionID = &sessionID
+	}
+	if tokenID, ok := r.Context().Value(httpio.PersonalAccessTokenIDContextKey).(uint); ok {
+		auditLog.PersonalAccessTokenID = &tokenID
+	}
+
+	return tx.Create(&auditLog).Error
+}
+
+func auditDiff(before any, after any) (datatypes.JSON, error) {
+	beforeFields, err := auditFields(before)
+	if err != nil {
+		return nil, err
+	}
+	afterFields, err := auditFields(after)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := map[string]AuditChange{}
+	for field, value := range beforeFields {
+		if !reflect.DeepEqual(value, afterFields[field]) {
+			changes[field] = AuditChange{Before: value, After: afterFields[field]}
+		}
+	}
+	for field, value := range afterFields {
+		if _, ok := beforeFields[field]; !ok && value != nil {
+			changes[field] = AuditChange{After: value}
+		}
+	}
+	for _, field := range auditIgnoredFields {
+		delete(changes, field)
+	}
+
+	return json.Marshal(changes)
+}
+
+// snapshot is converted through JSON, so only the fields visible in responses are ever recorded
+func auditFields(snapshot any) (map[string]any, error) {
+	fields := map[string]any{}
+	if snapshot == nil {
+		return fields, nil
+	}
+
+	encoded, err := json.Marshal(snapshot)
+	if err != nil {
+		return nil, err
+	}
+
+	return fields, json.Unmarshal(encoded, &fields)
+}
@@ -0,0 +1,54 @@
+package models
+
+import (
+	"time"
+)
+
+const (
+	DataExportStatusPending = "pending"
+	DataExportStatusReady   = "ready"
+	DataExportStatusFailed  = "failed"
+)
+
+// exported archive can be downloaded for that long, after that the cleanup removes it
+const DataExportRetention = 7 * 24 * time.Hour
+
+// DataExport is the archive with all personal data of the user, it is built in the background by the worker
+type DataExport struct {
+	AppModel
+	UserID      uint       `json:"user_id" gorm:"not null;index"`
+	User        User       `json:"-" gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
+	Status      string     `json:"status" gorm:"not null;default:'pending'"`
+	FileKey     *string    `json:"-"`
+	CompletedAt *time.Time `json:"completed_at"`
+	ExpiresAt   *time.Time `json:"expires_at" gorm:"index"`
+}
+
+func (m DataExport) IsDownloadable() bool {
+	return m.Status == DataExportStatusReady && m.FileKey != nil && m.ExpiresAt != nil && time.Now().Before(*m.ExpiresAt)
+}
+
+type DataExportResponse struct {
+	ID          uint       `json:"id"`
+	Status      string     `json:"status"`
+	CreatedAt   time.Time  `json:"created_at"`
+	CompletedAt *time.Time `json:"completed_at"`
+	ExpiresAt   *time.Time `json:"expires_at"`
+	// link to download the archive, present only when the export is ready
+	DownloadURL *string `json:"download_url"`
+}
+
+func (m DataExport) BuildExportResponse(downloadURL string) DataExportResponse {
+	response := DataExportResponse{
+		ID:          m.ID,
+		Status:      m.Status,
+		CreatedAt:   m.CreatedAt,
+		CompletedAt: m.CompletedAt,
+		ExpiresAt:   m.ExpiresAt,
+	}
+	if m.IsDownloadable() {
+		response.DownloadURL = &downloadURL
+	}
+
+	return response
+}
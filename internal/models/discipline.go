@@ -1,5 +1,7 @@
 package models
 
+import "github.com/filipio/athletics-backend/pkg/httpio"
+
 type Discipline struct {
 	AppModel
 	Name     string    `json:"name" gorm:"not null"`
@@ -13,6 +15,13 @@ type DisciplineResponse struct {
 	Type string `json:"type"`
 }
 
+func (m Discipline) ListFields() map[string]httpio.ListField {
+	return listFields(map[string]httpio.ListField{
+		"name": {Operators: []string{httpio.FilterEq, httpio.FilterLike}, Sortable: true},
+		"type": {Operators: []string{httpio.FilterEq, httpio.FilterIn}, Sortable: true},
+	})
+}
+
 func (m Discipline) BuildResponse() any {
 	return DisciplineResponse{
 		ID:   m.ID,
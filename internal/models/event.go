@@ -9,7 +9,7 @@ import (
 )
 
 type Event struct {
-	AppModel
+	SoftDeleteModel
 	Name        string     `json:"name" gorm:"not null" validate:"required"`
 	Description *string    `json:"description"`
 	Deadline    time.Time  `json:"deadline" gorm:"not null" validate:"required"`
@@ -17,30 +17,33 @@ type Event struct {
 	Questions   []Question `json:"questions,omitempty" gorm:"foreignKey:EventID;constraint:OnDelete:CASCADE"`
 }
 
-func (m Event) isOrganizerOrAdmin(r *http.Request) bool {
+// users allowed to manage events can see drafts as well
+func (m Event) canSeeDrafts(r *http.Request) bool {
 	user, ok := r.Context().Value(httpio.UserContextKey).(User)
 	if !ok {
 		return false
 	}
 
-	for _, role := range user.Roles {
-		if role.Name == httpio.OrganizerRole || role.Name == httpio.AdminRole {
-			return true
-		}
-	}
+	return user.HasPermission(httpio.EventsWritePermission)
+}
 
-	return false
+func (m Event) ListFields() map[string]httpio.ListField {
+	return listFields(map[string]httpio.ListField{
+		"name":     {Operators: []string{httpio.FilterEq, httpio.FilterLike}, Sortable: true},
+		"status":   {Operators: []string{httpio.FilterEq, httpio.FilterIn}},
+		"deadline": {Type: httpio.FieldTypeTime, Operators: []string{httpio.FilterGte, httpio.FilterLt}, Sortable: true},
+		// events whose deadline has not passed yet
+		"active": {Type: httpio.FieldTypeBool, Operators: []string{httpio.FilterEq}, Query: func(db *gorm.DB, operator string, values []any) *gorm.DB {
+			if values[0] == true {
+				return db.Where("NOW() < deadline")
+			}
+			return db.Where("deadline <= NOW()")
+		}, LegacyParams: map[string]httpio.LegacyParam{"active": {Operator: httpio.FilterEq}}},
+	})
 }
 
 func (m Event) GetAllQuery(db *gorm.DB, r *http.Request) *gorm.DB {
-	db = getByIds(db, r)
-	queryParams := r.URL.Query()
-
-	if queryParams.Get("active") == "true" {
-		db = db.Where("NOW() < deadline")
-	}
-
-	if !m.isOrganizerOrAdmin(r) {
+	if !m.canSeeDrafts(r) {
 		db = db.Where("status = ?", "published")
 	}
 
@@ -48,13 +51,21 @@ func (m Event) GetAllQuery(db *gorm.DB, r *http.Request) *gorm.DB {
 }
 
 func (m Event) GetQuery(db *gorm.DB, r *http.Request) *gorm.DB {
-	if !m.isOrganizerOrAdmin(r) {
+	if !m.canSeeDrafts(r) {
 		db = db.Where("status = ?", "published")
 	}
 
 	return m.AppModel.GetQuery(db, r)
 }
 
+func (m Event) WritePermission() string {
+	return httpio.EventsWritePermission
+}
+
+func (m Event) AuditResourceType() string {
+	return AuditResourceEvent
+}
+
 func (m Event) BuildResponse() any {
 	return m
 }
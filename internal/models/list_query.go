@@ -0,0 +1,210 @@
+package models
+
+import (
+	"fmt"
+	"maps"
+	"net/http"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/filipio/athletics-backend/pkg/httpio"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var filterParamPattern = regexp.MustCompile(`^filter\[(\w+)\](?:\[(\w+)\])?$`)
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// listFields adds the fields which every model has to the list fields of the model
+func listFields(fields map[string]httpio.ListField) map[string]httpio.ListField {
+	common := map[string]httpio.ListField{
+		"id": {Type: httpio.FieldTypeInt, Operators: []string{httpio.FilterEq, httpio.FilterIn}, Sortable: true,
+			LegacyParams: map[string]httpio.LegacyParam{"ids": {Operator: httpio.FilterIn}}},
+		"created_at": {Type: httpio.FieldTypeTime, Operators: []string{httpio.FilterGte, httpio.FilterLt}, Sortable: true},
+		"updated_at": {Type: httpio.FieldTypeTime, Operators: []string{httpio.FilterGte, httpio.FilterLt}, Sortable: true},
+	}
+	maps.Copy(common, fields)
+
+	return common
+}
+
+// FilterQuery applies filter[field][operator]=value parameters (operator defaults to eq, values of in are comma separated),
+// fields and operators which are not allowed by the model are rejected. Legacy parameters of the fields are applied as their filters
+func FilterQuery(db *gorm.DB, r *http.Request, fields map[string]httpio.ListField) (*gorm.DB, error) {
+	queryParams := r.URL.Query()
+	for param, values := range queryParams {
+		if !strings.HasPrefix(param, "filter[") {
+			continue
+		}
+
+		match := filterParamPattern.FindStringSubmatch(param)
+		if match == nil {
+			return nil, httpio.AppValidationError{
+				FieldPath: "filter",
+				AppError:  httpio.AppError{Message: "must be in the form filter[field][operator]"},
+			}
+		}
+
+		name, operator := match[1], match[2]
+		if operator == "" {
+			operator = httpio.FilterEq
+		}
+
+		field, ok := fields[name]
+		if !ok || len(field.Operators) == 0 {
+			return nil, httpio.AppValidationError{
+				FieldPath: "filter." + name,
+				AppError:  httpio.AppError{Message: "field can't be filtered"},
+			}
+		}
+		if !slices.Contains(field.Operators, operator) {
+			return nil, httpio.AppValidationError{
+				FieldPath: "filter." + name,
+				AppError: httpio.AppError{
+					Message: fmt.Sprintf("operator %s is not allowed, use one of: %s", operator, strings.Join(field.Operators, ", ")),
+				},
+			}
+		}
+
+		var err error
+		if db, err = applyFilter(db, name, field, operator, values[0], "filter."+name); err != nil {
+			return nil, err
+		}
+	}
+
+	for name, field := range fields {
+		for param, legacy := range field.LegacyParams {
+			if !queryParams.Has(param) {
+				continue
+			}
+
+			value := queryParams.Get(param)
+			if legacy.Normalize != nil {
+				value = legacy.Normalize(value)
+			}
+
+			var err error
+			if db, err = applyFilter(db, name, field, legacy.Operator, value, param); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return db, nil
+}
+
+func applyFilter(db *gorm.DB, name string, field httpio.ListField, operator string, value string, fieldPath string) (*gorm.DB, error) {
+	column := clause.Column{Table: clause.CurrentTable, Name: field.ColumnName(name)}
+	if operator == httpio.FilterLike {
+		pattern := "%" + likeEscaper.Replace(value) + "%"
+		if field.Query != nil {
+			return field.Query(db, operator, []any{pattern}), nil
+		}
+		return db.Where("? ILIKE ?", column, pattern), nil
+	}
+
+	rawValues := []string{value}
+	if operator == httpio.FilterIn {
+		rawValues = strings.Split(value, ",")
+	}
+	parsedValues := make([]any, len(rawValues))
+	for i, rawValue := range rawValues {
+		parsedValue, err := field.ParseValue(rawValue)
+		if err != nil {
+			return nil, httpio.AppValidationError{
+				FieldPath: fieldPath,
+				AppError:  httpio.AppError{Message: err.Error()},
+			}
+		}
+		parsedValues[i] = parsedValue
+	}
+
+	if field.Query != nil {
+		return field.Query(db, operator, parsedValues), nil
+	}
+
+	switch operator {
+	case httpio.FilterEq:
+		db = db.Where(clause.Eq{Column: column, Value: parsedValues[0]})
+	case httpio.FilterIn:
+		db = db.Where(clause.IN{Column: column, Values: parsedValues})
+	case httpio.FilterGte:
+		db = db.Where(clause.Gte{Column: column, Value: parsedValues[0]})
+	case httpio.FilterLt:
+		db = db.Where(clause.Lt{Column: column, Value: parsedValues[0]})
+	}
+
+	return db, nil
+}
+
+// ListOrder parses sort parameter with comma separated fields, descending with "-" prefix (e.g. sort=-deadline,name).
+// Single field in order_by and order_dir is accepted as well. Id is always the last column, so pages are stable
+func ListOrder(r *http.Request, fields map[string]httpio.ListField) (clause.OrderBy, error) {
+	queryParams := r.URL.Query()
+
+	param := "sort"
+	var terms []string
+	if queryParams.Has("sort") {
+		terms = strings.Split(queryParams.Get("sort"), ",")
+	} else if queryParams.Has("order_by") {
+		param = "order_by"
+		switch strings.ToLower(queryParams.Get("order_dir")) {
+		case "", "asc":
+			terms = []string{queryParams.Get("order_by")}
+		case "desc":
+			terms = []string{"-" + queryParams.Get("order_by")}
+		default:
+			return clause.OrderBy{}, httpio.AppValidationError{
+				FieldPath: "order_dir",
+				AppError:  httpio.AppError{Message: "must be asc or desc"},
+			}
+		}
+	}
+
+	order := clause.OrderBy{}
+	for _, term := range terms {
+		name := strings.TrimPrefix(term, "-")
+		field, ok := fields[name]
+		if !ok || !field.Sortable {
+			return order, httpio.AppValidationError{
+				FieldPath: param,
+				AppError:  httpio.AppError{Message: fmt.Sprintf("can't sort by %q", name)},
+			}
+		}
+
+		order.Columns = append(order.Columns, clause.OrderByColumn{
+			Column: clause.Column{Table: clause.CurrentTable, Name: field.ColumnName(name)},
+			Desc:   strings.HasPrefix(term, "-"),
+		})
+	}
+
+	if !slices.ContainsFunc(order.Columns, func(column clause.OrderByColumn) bool { return column.Column.Name == "id" }) {
+		order.Columns = append(order.Columns, clause.OrderByColumn{
+			Column: clause.Column{Table: clause.CurrentTable, Name: "id"},
+		})
+	}
+
+	return order, nil
+}
+
+// ListFieldset returns the fields requested with fields parameter (sparse fieldset), nil when all the fields are returned
+func ListFieldset(r *http.Request, response any) ([]string, error) {
+	if !r.URL.Query().Has("fields") {
+		return nil, nil
+	}
+
+	available := httpio.ResponseFields(response)
+	fieldset := strings.Split(r.URL.Query().Get("fields"), ",")
+	for _, name := range fieldset {
+		if !slices.Contains(available, name) {
+			return nil, httpio.AppValidationError{
+				FieldPath: "fields",
+				AppError:  httpio.AppError{Message: fmt.Sprintf("unknown field %q", name)},
+			}
+		}
+	}
+
+	return fieldset, nil
+}
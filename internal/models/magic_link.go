@@ -0,0 +1,51 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	MagicLinkTokenExpirationMinutes = 15
+	MaxMagicLinkRequests            = 3
+	MagicLinkWindowMinutes          = 15
+)
+
+// MagicLinkToken allows passwordless login, only the hash of the token is stored and plain token is sent by email
+type MagicLinkToken struct {
+	AppModel
+	UserID    uint       `json:"user_id" gorm:"not null;index"`
+	TokenHash string     `json:"-" gorm:"not null;unique"`
+	ExpiresAt time.Time  `json:"expires_at" gorm:"not null;index"`
+	UsedAt    *time.Time `json:"used_at"`
+}
+
+// generates new plain token, sets its hash and expiration time on the model
+func (mlt *MagicLinkToken) GenerateToken() string {
+	plainToken := uuid.New().String()
+	mlt.TokenHash = HashLookupToken(plainToken)
+	mlt.ExpiresAt = time.Now().Add(time.Duration(MagicLinkTokenExpirationMinutes) * time.Minute)
+	return plainToken
+}
+
+func (mlt MagicLinkToken) IsValid() bool {
+	if mlt.UsedAt != nil {
+		return false
+	}
+	return time.Now().Before(mlt.ExpiresAt)
+}
+
+type MagicLinkRateLimit struct {
+	AppModel
+	Email string `json:"email" gorm:"not null;unique;index"`
+	RequestRateLimit
+}
+
+func (mlrl *MagicLinkRateLimit) CanRequestMagicLink() bool {
+	return mlrl.canRequest(MaxMagicLinkRequests, time.Duration(MagicLinkWindowMinutes)*time.Minute)
+}
+
+func (mlrl *MagicLinkRateLimit) IncrementRequestCount() {
+	mlrl.incrementRequestCount(MaxMagicLinkRequests, time.Duration(MagicLinkWindowMinutes)*time.Minute)
+}
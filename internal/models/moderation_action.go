@@ -0,0 +1,69 @@
+package models
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/filipio/athletics-backend/pkg/httpio"
+	"gorm.io/gorm"
+)
+
+const (
+	ModerationActionGrantRole     = "grant_role"
+	ModerationActionRevokeRole    = "revoke_role"
+	ModerationActionSuspend       = "suspend"
+	ModerationActionUnsuspend     = "unsuspend"
+	ModerationActionResetUsername = "reset_username"
+)
+
+// ModerationAction records who moderated the user account and why, rows are never updated
+type ModerationAction struct {
+	AppModel
+	ActorID      *uint  `json:"actor_id" gorm:"index"`
+	Actor        *User  `json:"-" gorm:"foreignKey:ActorID;constraint:OnDelete:SET NULL"`
+	TargetUserID uint   `json:"target_user_id" gorm:"not null;index"`
+	TargetUser   User   `json:"-" gorm:"foreignKey:TargetUserID;constraint:OnDelete:CASCADE"`
+	Action       string `json:"action" gorm:"not null"`
+	Reason       string `json:"reason" gorm:"not null"`
+	// action specific value, e.g. role name, previous username or end of the suspension
+	Details string `json:"details" gorm:"not null;default:''"`
+}
+
+func (m ModerationAction) ListFields() map[string]httpio.ListField {
+	return listFields(map[string]httpio.ListField{
+		"action":   {Operators: []string{httpio.FilterEq, httpio.FilterIn}},
+		"actor_id": {Type: httpio.FieldTypeInt, Operators: []string{httpio.FilterEq, httpio.FilterIn}},
+	})
+}
+
+func (m ModerationAction) GetAllQuery(db *gorm.DB, r *http.Request) *gorm.DB {
+	return db.Where("target_user_id = ?", r.PathValue("id")).Preload("Actor")
+}
+
+type ModerationActionResponse struct {
+	ID            uint      `json:"id"`
+	ActorID       *uint     `json:"actor_id"`
+	ActorUsername *string   `json:"actor_username"`
+	TargetUserID  uint      `json:"target_user_id"`
+	Action        string    `json:"action"`
+	Reason        string    `json:"reason"`
+	Details       string    `json:"details"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+func (m ModerationAction) BuildResponse() any {
+	response := ModerationActionResponse{
+		ID:           m.ID,
+		ActorID:      m.ActorID,
+		TargetUserID: m.TargetUserID,
+		Action:       m.Action,
+		Reason:       m.Reason,
+		Details:      m.Details,
+		CreatedAt:    m.CreatedAt,
+	}
+	if m.Actor != nil {
+		response.ActorUsername = &m.Actor.Username
+	}
+
+	return response
+}
@@ -0,0 +1,51 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	PasswordResetTokenExpirationMinutes = 60
+	MaxPasswordResetRequests            = 3
+	PasswordResetWindowMinutes          = 15
+)
+
+// only the hash of the token is stored, plain token is sent to the user by email
+type PasswordResetToken struct {
+	AppModel
+	UserID    uint       `json:"user_id" gorm:"not null;index"`
+	TokenHash string     `json:"-" gorm:"not null;unique"`
+	ExpiresAt time.Time  `json:"expires_at" gorm:"not null;index"`
+	UsedAt    *time.Time `json:"used_at"`
+}
+
+// generates new plain token, sets its hash and expiration time on the model
+func (prt *PasswordResetToken) GenerateToken() string {
+	plainToken := uuid.New().String()
+	prt.TokenHash = HashLookupToken(plainToken)
+	prt.ExpiresAt = time.Now().Add(time.Duration(PasswordResetTokenExpirationMinutes) * time.Minute)
+	return plainToken
+}
+
+func (prt PasswordResetToken) IsValid() bool {
+	if prt.UsedAt != nil {
+		return false
+	}
+	return time.Now().Before(prt.ExpiresAt)
+}
+
+type PasswordResetRateLimit struct {
+	AppModel
+	Email string `json:"email" gorm:"not null;unique;index"`
+	RequestRateLimit
+}
+
+func (prrl *PasswordResetRateLimit) CanRequestPasswordReset() bool {
+	return prrl.canRequest(MaxPasswordResetRequests, time.Duration(PasswordResetWindowMinutes)*time.Minute)
+}
+
+func (prrl *PasswordResetRateLimit) IncrementRequestCount() {
+	prrl.incrementRequestCount(MaxPasswordResetRequests, time.Duration(PasswordResetWindowMinutes)*time.Minute)
+}
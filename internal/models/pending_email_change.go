@@ -0,0 +1,27 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const PendingEmailChangeExpirationHours = 24
+
+type PendingEmailChange struct {
+	AppModel
+	UserID            uint      `json:"user_id" gorm:"not null;index"`
+	NewEmail          string    `json:"new_email" validate:"required,email" gorm:"not null;index"`
+	VerificationToken string    `json:"-" gorm:"not null;index"`
+	ExpiresAt         time.Time `json:"expires_at" gorm:"not null;index"`
+}
+
+func (pec *PendingEmailChange) GenerateVerificationToken() error {
+	pec.VerificationToken = uuid.New().String()
+	pec.ExpiresAt = time.Now().Add(time.Hour * PendingEmailChangeExpirationHours)
+	return nil
+}
+
+func (pec PendingEmailChange) IsExpired() bool {
+	return time.Now().After(pec.ExpiresAt)
+}
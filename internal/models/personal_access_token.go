@@ -0,0 +1,120 @@
+package models
+
+import (
+	"crypto/rand"
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/filipio/athletics-backend/pkg/httpio"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+// PersonalAccessTokenPrefix distinguishes personal access tokens from JWTs in the Authorization header
+const PersonalAccessTokenPrefix = "ath_pat_"
+
+// number of characters of the token (after the prefix) kept in plain text, so users can recognize their tokens
+const personalAccessTokenHintLength = 4
+
+const MaxPersonalAccessTokensPerUser = 20
+const MaxPersonalAccessTokenLifetime = 365 * 24 * time.Hour
+
+// PersonalAccessToken is a long-lived token for scripts, it acts on behalf of the user limited to the granted scopes
+type PersonalAccessToken struct {
+	AppModel
+	UserID     uint                        `json:"user_id" gorm:"not null;index"`
+	Name       string                      `json:"name" gorm:"not null"`
+	TokenHash  string                      `json:"-" gorm:"not null;unique"`
+	Hint       string                      `json:"hint" gorm:"not null"`
+	Scopes     datatypes.JSONSlice[string] `json:"scopes" gorm:"type:jsonb;not null"`
+	ExpiresAt  time.Time                   `json:"expires_at" gorm:"not null;index"`
+	LastUsedAt *time.Time                  `json:"last_used_at"`
+	LastUsedIP string                      `json:"last_used_ip" gorm:"not null;default:''"`
+	RevokedAt  *time.Time                  `json:"revoked_at" gorm:"index"`
+}
+
+func IsPersonalAccessToken(token string) bool {
+	return strings.HasPrefix(token, PersonalAccessTokenPrefix)
+}
+
+// GenerateToken sets hash and hint of a new random token, the plain token is returned only once
+func (pat *PersonalAccessToken) GenerateToken() string {
+	secret := rand.Text()
+	token := PersonalAccessTokenPrefix + secret
+
+	pat.TokenHash = HashLookupToken(token)
+	pat.Hint = PersonalAccessTokenPrefix + secret[:personalAccessTokenHintLength]
+
+	return token
+}
+
+// FindActivePersonalAccessToken returns not expired and not revoked token matching the plain token, nil if there is none
+func FindActivePersonalAccessToken(db *gorm.DB, token string) (*PersonalAccessToken, error) {
+	var pat PersonalAccessToken
+	err := db.Where("token_hash = ? AND expires_at > ? AND revoked_at IS NULL", HashLookupToken(token), time.Now()).First(&pat).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &pat, nil
+}
+
+func (pat PersonalAccessToken) HasScope(scope string) bool {
+	return slices.Contains(pat.Scopes, scope)
+}
+
+// TouchLastUsed updates time and address of the last usage, at most once per SessionLastUsedResolution
+func (pat *PersonalAccessToken) TouchLastUsed(db *gorm.DB, ipAddress string) error {
+	now := time.Now()
+	if pat.LastUsedAt != nil && now.Sub(*pat.LastUsedAt) < SessionLastUsedResolution && pat.LastUsedIP == ipAddress {
+		return nil
+	}
+
+	pat.LastUsedAt = &now
+	pat.LastUsedIP = ipAddress
+	return db.Model(pat).UpdateColumns(map[string]any{"last_used_at": now, "last_used_ip": ipAddress}).Error
+}
+
+func (pat PersonalAccessToken) ListFields() map[string]httpio.ListField {
+	return listFields(map[string]httpio.ListField{
+		"name":         {Operators: []string{httpio.FilterEq, httpio.FilterLike}, Sortable: true},
+		"expires_at":   {Type: httpio.FieldTypeTime, Operators: []string{httpio.FilterGte, httpio.FilterLt}, Sortable: true},
+		"last_used_at": {Type: httpio.FieldTypeTime, Operators: []string{httpio.FilterGte, httpio.FilterLt}, Sortable: true},
+	})
+}
+
+func (pat PersonalAccessToken) GetAllQuery(db *gorm.DB, r *http.Request) *gorm.DB {
+	return onlyCurrentUserRecords(db, r).Where("revoked_at IS NULL")
+}
+
+type PersonalAccessTokenResponse struct {
+	ID         uint       `json:"id"`
+	Name       string     `json:"name"`
+	Hint       string     `json:"hint"`
+	Scopes     []string   `json:"scopes"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	LastUsedIP string     `json:"last_used_ip"`
+	CreatedAt  time.Time  `json:"created_at"`
+	// plain token, present only in the response to its creation
+	Token string `json:"token,omitempty"`
+}
+
+func (pat PersonalAccessToken) BuildResponse() any {
+	return PersonalAccessTokenResponse{
+		ID:         pat.ID,
+		Name:       pat.Name,
+		Hint:       pat.Hint,
+		Scopes:     pat.Scopes,
+		ExpiresAt:  pat.ExpiresAt,
+		LastUsedAt: pat.LastUsedAt,
+		LastUsedIP: pat.LastUsedIP,
+		CreatedAt:  pat.CreatedAt,
+	}
+}
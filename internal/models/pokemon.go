@@ -1,10 +1,6 @@
 package models
 
-import (
-	"net/http"
-
-	"gorm.io/gorm"
-)
+import "github.com/filipio/athletics-backend/pkg/httpio"
 
 type Pokemon struct {
 	AppModel
@@ -14,15 +10,13 @@ type Pokemon struct {
 	Attack      string `json:"attack" validate:"required,oneof=Thunderbolt Ember Vine_Whip Water_Gun,min=2,max=100" gorm:"not null"`
 }
 
-func (m Pokemon) GetAllQuery(db *gorm.DB, r *http.Request) *gorm.DB {
-	db = getByIds(db, r)
-	queryParams := r.URL.Query()
-
-	if queryParams.Has("name") {
-		db = db.Where("pokemon_name IN (?)", queryParams.Get("name"))
-	}
-
-	return db
+func (m Pokemon) ListFields() map[string]httpio.ListField {
+	return listFields(map[string]httpio.ListField{
+		"pokemon_name": {Operators: []string{httpio.FilterEq, httpio.FilterIn}, Sortable: true,
+			LegacyParams: map[string]httpio.LegacyParam{"name": {Operator: httpio.FilterEq}}},
+		"age":    {Type: httpio.FieldTypeInt, Operators: []string{httpio.FilterEq, httpio.FilterGte, httpio.FilterLt}, Sortable: true},
+		"attack": {Operators: []string{httpio.FilterEq, httpio.FilterIn}},
+	})
 }
 
 func (m Pokemon) BuildResponse() any {
@@ -8,7 +8,7 @@ import (
 )
 
 type Question struct {
-	AppModel
+	SoftDeleteModel
 	EventID       uint              `json:"event_id" gorm:"not null" validate:"required,id_of=event"`
 	Content       string            `json:"content" gorm:"not null" validate:"required"`
 	CorrectAnswer *AnswerOfQuestion `json:"correct_answer"`
@@ -30,15 +30,43 @@ func (m Question) Validate(db *gorm.DB) error {
 	return nil
 }
 
+func (m Question) ListFields() map[string]httpio.ListField {
+	return listFields(map[string]httpio.ListField{
+		"event_id": {Type: httpio.FieldTypeInt, Operators: []string{httpio.FilterEq, httpio.FilterIn},
+			LegacyParams: map[string]httpio.LegacyParam{"event_id": {Operator: httpio.FilterEq}}},
+		"type":    {Operators: []string{httpio.FilterEq, httpio.FilterIn}},
+		"content": {Operators: []string{httpio.FilterLike}},
+		"points":  {Type: httpio.FieldTypeInt, Operators: []string{httpio.FilterEq, httpio.FilterGte, httpio.FilterLt}, Sortable: true},
+	})
+}
+
 func (m Question) GetAllQuery(db *gorm.DB, r *http.Request) *gorm.DB {
-	db = getByIds(db, r)
-	queryParams := r.URL.Query()
+	return questionsOfVisibleEvents(db)
+}
+
+func (m Question) GetQuery(db *gorm.DB, r *http.Request) *gorm.DB {
+	return questionsOfVisibleEvents(GetByIdQuery(db, r))
+}
 
-	if queryParams.Has("event_id") {
-		db = db.Where("event_id = ?", queryParams.Get("event_id"))
+// questions are not deleted together with their event, so they come back when the event is restored
+func QuestionsOfActiveEvents(db *gorm.DB) *gorm.DB {
+	return db.Where("questions.event_id NOT IN (SELECT id FROM events WHERE deleted_at IS NOT NULL)")
+}
+
+// deleted rows requested by admin are not filtered out
+func questionsOfVisibleEvents(db *gorm.DB) *gorm.DB {
+	if db.Statement.Unscoped {
+		return db
 	}
+	return QuestionsOfActiveEvents(db)
+}
+
+func (m Question) WritePermission() string {
+	return httpio.QuestionsWritePermission
+}
 
-	return db
+func (m Question) AuditResourceType() string {
+	return AuditResourceQuestion
 }
 
 func (m Question) BuildResponse() any {
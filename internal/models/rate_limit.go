@@ -0,0 +1,60 @@
+package models
+
+import (
+	"math"
+	"time"
+)
+
+// counts requests made within a time window, embedded in models which rate limit actions (e.g. per email)
+type RequestRateLimit struct {
+	RequestCount  int        `json:"request_count" gorm:"not null;default:0"`
+	LastRequestAt *time.Time `json:"last_request_at"`
+	BlockedUntil  *time.Time `json:"blocked_until" gorm:"index"`
+}
+
+func (rl *RequestRateLimit) IsBlocked() bool {
+	if rl.BlockedUntil == nil {
+		return false
+	}
+	return time.Now().Before(*rl.BlockedUntil)
+}
+
+func (rl *RequestRateLimit) canRequest(maxRequests int, window time.Duration) bool {
+	if rl.IsBlocked() {
+		return false
+	}
+
+	// Reset counter if window has passed
+	if rl.LastRequestAt != nil && time.Since(*rl.LastRequestAt) > window {
+		rl.RequestCount = 0
+	}
+
+	return rl.RequestCount < maxRequests
+}
+
+func (rl *RequestRateLimit) incrementRequestCount(maxRequests int, window time.Duration) {
+	now := time.Now()
+	rl.RequestCount++
+	rl.LastRequestAt = &now
+
+	if rl.RequestCount >= maxRequests {
+		blockUntil := now.Add(window)
+		rl.BlockedUntil = &blockUntil
+	}
+}
+
+// formatted time until which requests are blocked, nil when not blocked
+func (rl *RequestRateLimit) BlockedUntilString() *string {
+	if rl.BlockedUntil == nil {
+		return nil
+	}
+	blockedUntil := rl.BlockedUntil.Format(time.RFC3339)
+	return &blockedUntil
+}
+
+func (rl *RequestRateLimit) RetryAfterSeconds() int {
+	if !rl.IsBlocked() {
+		return 0
+	}
+	return int(math.Ceil(time.Until(*rl.BlockedUntil).Seconds()))
+}
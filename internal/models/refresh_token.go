@@ -1,37 +1,79 @@
 package models
 
 import (
+	"crypto/rand"
+	"crypto/subtle"
+	"errors"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
-	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
 )
 
+const refreshTokenSeparator = "."
+
+// LastUsedAt of the session is not updated more often than that, so not every request results in a write
+const SessionLastUsedResolution = time.Minute
+
 type RefreshToken struct {
 	AppModel
-	UserID    uint       `json:"user_id" validate:"required" gorm:"not null;index"`
-	TokenHash string     `json:"-" gorm:"not null;index"`
-	SessionID string     `json:"session_id" gorm:"not null;unique;type:uuid;index"`
-	ExpiresAt time.Time  `json:"expires_at" gorm:"not null;index"`
-	RevokedAt *time.Time `json:"revoked_at" gorm:"index"`
+	UserID           uint       `json:"user_id" validate:"required" gorm:"not null;index"`
+	Selector         *string    `json:"-" gorm:"unique"`
+	TokenHash        string     `json:"-" gorm:"not null;index"`
+	SessionID        string     `json:"session_id" gorm:"not null;type:uuid;index"`
+	ExpiresAt        time.Time  `json:"expires_at" gorm:"not null;index"`
+	RevokedAt        *time.Time `json:"revoked_at" gorm:"index"`
+	UsedAt           *time.Time `json:"used_at"` // set when the token is rotated, used tokens are kept to detect their reuse
+	SessionStartedAt time.Time  `json:"session_started_at" gorm:"not null;default:CURRENT_TIMESTAMP"`
+	LastUsedAt       *time.Time `json:"last_used_at"`
+	UserAgent        string     `json:"user_agent" gorm:"not null;default:''"`
+	IPAddress        string     `json:"ip_address" gorm:"not null;default:''"`
 }
 
+// GenerateToken generates a new token in format <selector>.<verifier> and sets selector and hash of the verifier on the model.
+// Selector is used to find the token with a single indexed query, verifier is compared in constant time.
 func (rt *RefreshToken) GenerateToken() string {
-	return uuid.New().String()
+	selector := rand.Text()
+	verifier := rand.Text()
+
+	rt.Selector = &selector
+	rt.TokenHash = HashLookupToken(verifier)
+
+	return selector + refreshTokenSeparator + verifier
 }
 
 func (rt *RefreshToken) GenerateSessionID() string {
 	return uuid.New().String()
 }
 
-// HashToken hashes a token string using bcrypt
-func (rt *RefreshToken) HashToken(token string) (string, error) {
-	hash, err := bcrypt.GenerateFromPassword([]byte(token), 10)
+func (rt RefreshToken) verify(verifier string) bool {
+	return subtle.ConstantTimeCompare([]byte(rt.TokenHash), []byte(HashLookupToken(verifier))) == 1
+}
+
+// FindActiveRefreshToken returns not expired and not revoked refresh token matching the plain token, nil if there is none.
+// Already rotated (used) tokens are returned as well, so their reuse can be detected.
+func FindActiveRefreshToken(db *gorm.DB, token string) (*RefreshToken, error) {
+	// tokens issued before selectors were introduced are revoked, so only selector.verifier tokens are looked up
+	selector, verifier, found := strings.Cut(token, refreshTokenSeparator)
+	if !found {
+		return nil, nil
+	}
+
+	var refreshToken RefreshToken
+	err := db.Where("selector = ? AND expires_at > ? AND revoked_at IS NULL", selector, time.Now()).First(&refreshToken).Error
 	if err != nil {
-		return "", err
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	if !refreshToken.verify(verifier) {
+		return nil, nil
 	}
-	return string(hash), nil
+
+	return &refreshToken, nil
 }
 
 func (rt RefreshToken) IsValid() bool {
@@ -41,8 +83,64 @@ func (rt RefreshToken) IsValid() bool {
 	return time.Now().Before(rt.ExpiresAt)
 }
 
+func (rt RefreshToken) IsUsed() bool {
+	return rt.UsedAt != nil
+}
+
+// MarkUsed marks the token as rotated. It returns false if the token was already used in the meantime (concurrent refresh).
+func (rt *RefreshToken) MarkUsed(db *gorm.DB) (bool, error) {
+	now := time.Now()
+	result := db.Model(rt).Where("used_at IS NULL").Update("used_at", now)
+	if result.Error != nil {
+		return false, result.Error
+	}
+
+	rt.UsedAt = &now
+	return result.RowsAffected == 1, nil
+}
+
 func (rt *RefreshToken) Revoke(db *gorm.DB) error {
 	now := time.Now()
 	rt.RevokedAt = &now
 	return db.Model(rt).Update("revoked_at", now).Error
 }
+
+// RevokeSession revokes all tokens of the session (family of rotated tokens)
+func (rt *RefreshToken) RevokeSession(db *gorm.DB) error {
+	return db.Model(&RefreshToken{}).
+		Where("session_id = ? AND revoked_at IS NULL", rt.SessionID).
+		Update("revoked_at", time.Now()).Error
+}
+
+// TouchLastUsed updates time of the last usage of the session, at most once per SessionLastUsedResolution
+func (rt *RefreshToken) TouchLastUsed(db *gorm.DB) error {
+	now := time.Now()
+	if rt.LastUsedAt != nil && now.Sub(*rt.LastUsedAt) < SessionLastUsedResolution {
+		return nil
+	}
+
+	rt.LastUsedAt = &now
+	return db.Model(rt).UpdateColumn("last_used_at", now).Error
+}
+
+type SessionResponse struct {
+	SessionID  string     `json:"session_id"`
+	UserAgent  string     `json:"user_agent"`
+	IPAddress  string     `json:"ip_address"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	IsCurrent  bool       `json:"is_current"`
+}
+
+func (rt RefreshToken) BuildSessionResponse(currentSessionID string) SessionResponse {
+	return SessionResponse{
+		SessionID:  rt.SessionID,
+		UserAgent:  rt.UserAgent,
+		IPAddress:  rt.IPAddress,
+		CreatedAt:  rt.SessionStartedAt,
+		LastUsedAt: rt.LastUsedAt,
+		ExpiresAt:  rt.ExpiresAt,
+		IsCurrent:  rt.SessionID == currentSessionID,
+	}
+}
@@ -1,7 +1,90 @@
 package models
 
+import (
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/filipio/athletics-backend/pkg/httpio"
+	"gorm.io/gorm"
+)
+
 type Role struct {
 	AppModel
-	Name  string `json:"name" validate:"required,oneof=admin user organizer" gorm:"not null;unique"`
-	Users []User `json:"users" gorm:"many2many:user_roles;constraint:OnDelete:CASCADE"`
+	Name              string           `json:"name" validate:"required,min=2,max=50" gorm:"not null;unique"`
+	RequiresTwoFactor bool             `json:"requires_two_factor" gorm:"not null;default:false"`
+	Permissions       []RolePermission `json:"-" gorm:"foreignKey:RoleID;constraint:OnDelete:CASCADE"`
+	Users             []User           `json:"users" gorm:"many2many:user_roles;constraint:OnDelete:CASCADE"`
+}
+
+// RolePermission is a single permission from httpio.AllPermissions granted to the role
+type RolePermission struct {
+	AppModel
+	RoleID     uint   `json:"role_id" gorm:"not null;uniqueIndex:idx_role_permissions_role_id_permission"`
+	Permission string `json:"permission" gorm:"not null;uniqueIndex:idx_role_permissions_role_id_permission"`
+}
+
+func (m Role) ListFields() map[string]httpio.ListField {
+	return listFields(map[string]httpio.ListField{
+		"name": {Operators: []string{httpio.FilterEq, httpio.FilterLike}, Sortable: true},
+	})
+}
+
+func (m Role) GetAllQuery(db *gorm.DB, r *http.Request) *gorm.DB {
+	return db.Preload("Permissions")
+}
+
+func (m Role) GetQuery(db *gorm.DB, r *http.Request) *gorm.DB {
+	return GetByIdQuery(db.Preload("Permissions"), r)
+}
+
+func (m Role) IsBuiltIn() bool {
+	return slices.Contains(httpio.BuiltInRoles, m.Name)
+}
+
+// HasPermission requires permissions to be preloaded
+func (m Role) HasPermission(permission string) bool {
+	for _, rolePermission := range m.Permissions {
+		if rolePermission.Permission == permission {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (m Role) PermissionNames() []string {
+	permissions := make([]string, len(m.Permissions))
+	for i, rolePermission := range m.Permissions {
+		permissions[i] = rolePermission.Permission
+	}
+	slices.Sort(permissions)
+
+	return permissions
+}
+
+type RoleResponse struct {
+	ID                uint      `json:"id"`
+	Name              string    `json:"name"`
+	Permissions       []string  `json:"permissions"`
+	RequiresTwoFactor bool      `json:"requires_two_factor"`
+	BuiltIn           bool      `json:"built_in"`
+	CreatedAt         time.Time `json:"created_at"`
+	UpdatedAt         time.Time `json:"updated_at"`
+}
+
+func (m Role) AuditResourceType() string {
+	return AuditResourceRole
+}
+
+func (m Role) BuildResponse() any {
+	return RoleResponse{
+		ID:                m.ID,
+		Name:              m.Name,
+		Permissions:       m.PermissionNames(),
+		RequiresTwoFactor: m.RequiresTwoFactor,
+		BuiltIn:           m.IsBuiltIn(),
+		CreatedAt:         m.CreatedAt,
+		UpdatedAt:         m.UpdatedAt,
+	}
 }
@@ -0,0 +1,13 @@
+package models
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// HashLookupToken hashes high-entropy random tokens with SHA-256, so they can be looked up by an indexed column.
+// It must not be used for passwords - those are hashed with bcrypt.
+func HashLookupToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
@@ -0,0 +1,104 @@
+package models
+
+import (
+	"crypto/rand"
+	"strings"
+	"time"
+
+	"github.com/filipio/athletics-backend/pkg/totp"
+	"gorm.io/gorm"
+)
+
+const (
+	RecoveryCodesCount              = 10
+	LoginChallengeExpirationMinutes = 5
+	MaxLoginChallengeFailedAttempts = 5
+)
+
+// TOTP secret of the user, 2FA is enabled once ConfirmedAt is set
+type UserTwoFactor struct {
+	AppModel
+	UserID       uint       `json:"user_id" gorm:"not null;unique"`
+	Secret       string     `json:"-" gorm:"not null"`
+	ConfirmedAt  *time.Time `json:"confirmed_at"`
+	LastUsedStep int64      `json:"-" gorm:"not null;default:0"`
+}
+
+func (utf UserTwoFactor) IsConfirmed() bool {
+	return utf.ConfirmedAt != nil
+}
+
+// UseCode validates the code and marks its time step as used, so the same code can't be replayed
+func (utf *UserTwoFactor) UseCode(db *gorm.DB, code string) (bool, error) {
+	step, ok := totp.Validate(utf.Secret, code, time.Now(), utf.LastUsedStep)
+	if !ok {
+		return false, nil
+	}
+
+	result := db.Model(&UserTwoFactor{}).
+		Where("id = ? AND last_used_step < ?", utf.ID, step).
+		Update("last_used_step", step)
+	if result.Error != nil {
+		return false, result.Error
+	}
+
+	utf.LastUsedStep = step
+	return result.RowsAffected == 1, nil
+}
+
+// single-use code which replaces TOTP code when the authenticator is lost, only the hash is stored
+type TwoFactorRecoveryCode struct {
+	AppModel
+	UserID   uint       `json:"user_id" gorm:"not null;index"`
+	CodeHash string     `json:"-" gorm:"not null;unique"`
+	UsedAt   *time.Time `json:"used_at"`
+}
+
+// GenerateRecoveryCodes returns plain codes (shown to the user once) and models to be stored
+func GenerateRecoveryCodes(userID uint) ([]string, []TwoFactorRecoveryCode) {
+	plainCodes := make([]string, RecoveryCodesCount)
+	recoveryCodes := make([]TwoFactorRecoveryCode, RecoveryCodesCount)
+
+	for i := range plainCodes {
+		randomText := strings.ToLower(rand.Text()[:16])
+		plainCodes[i] = randomText[0:4] + "-" + randomText[4:8] + "-" + randomText[8:12] + "-" + randomText[12:16]
+		recoveryCodes[i] = TwoFactorRecoveryCode{UserID: userID, CodeHash: hashRecoveryCode(plainCodes[i])}
+	}
+
+	return plainCodes, recoveryCodes
+}
+
+// UseRecoveryCode marks matching unused recovery code of the user as used
+func UseRecoveryCode(db *gorm.DB, userID uint, code string) (bool, error) {
+	result := db.Model(&TwoFactorRecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, hashRecoveryCode(code)).
+		Update("used_at", time.Now())
+
+	return result.RowsAffected == 1, result.Error
+}
+
+// recovery codes are accepted regardless of case and dashes
+func hashRecoveryCode(code string) string {
+	normalizedCode := strings.ToLower(strings.ReplaceAll(strings.TrimSpace(code), "-", ""))
+	return HashLookupToken(normalizedCode)
+}
+
+// issued after valid password for users with 2FA enabled, exchanged for token pair with the second factor
+type LoginChallenge struct {
+	AppModel
+	UserID         uint      `json:"user_id" gorm:"not null;index"`
+	TokenHash      string    `json:"-" gorm:"not null;unique"`
+	ExpiresAt      time.Time `json:"expires_at" gorm:"not null;index"`
+	FailedAttempts int       `json:"failed_attempts" gorm:"not null;default:0"`
+}
+
+func (lc *LoginChallenge) GenerateToken() string {
+	plainToken := rand.Text()
+	lc.TokenHash = HashLookupToken(plainToken)
+	lc.ExpiresAt = time.Now().Add(time.Duration(LoginChallengeExpirationMinutes) * time.Minute)
+	return plainToken
+}
+
+func (lc LoginChallenge) IsValid() bool {
+	return lc.FailedAttempts < MaxLoginChallengeFailedAttempts && time.Now().Before(lc.ExpiresAt)
+}
@@ -2,6 +2,7 @@ package models
 
 import (
 	"net/http"
+	"slices"
 	"time"
 
 	"github.com/filipio/athletics-backend/pkg/httpio"
@@ -10,21 +11,37 @@ import (
 
 type User struct {
 	AppModel
-	Username            string   `json:"username" validate:"required" gorm:"not null;default:'no_name'"`
-	Email               string   `json:"email" validate:"required,email" gorm:"not null;unique"`
-	Password            string   `json:"password" validate:"required,min=6" gorm:"not null"`
-	Roles               []Role   `json:"roles" gorm:"many2many:user_roles;constraint:OnDelete:CASCADE"`
-	Answers             []Answer `json:"answers,omitempty" gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
-	SkipPasswordHashing bool     `json:"-" gorm:"-"`
+	Username             string                  `json:"username" validate:"required" gorm:"not null;index:idx_users_username_lower,unique,expression:lower(username)"`
+	Email                string                  `json:"email" validate:"required,email" gorm:"not null;unique"`
+	Password             string                  `json:"password" validate:"required,min=6" gorm:"not null"`
+	Roles                []Role                  `json:"roles" gorm:"many2many:user_roles;constraint:OnDelete:CASCADE"`
+	Answers              []Answer                `json:"answers,omitempty" gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
+	Identities           []UserIdentity          `json:"-" gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
+	TwoFactor            *UserTwoFactor          `json:"-" gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
+	RecoveryCodes        []TwoFactorRecoveryCode `json:"-" gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
+	PersonalAccessTokens []PersonalAccessToken   `json:"-" gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
+	Profile              *UserProfile            `json:"-" gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
+	SuspendedAt          *time.Time              `json:"-"`
+	SuspendedUntil       *time.Time              `json:"-"`
+	// set when the account was deleted, but the user is kept without personal data for rankings
+	AnonymizedAt        *time.Time `json:"-"`
+	SkipPasswordHashing bool       `json:"-" gorm:"-"`
+}
+
+func (m User) ListFields() map[string]httpio.ListField {
+	return listFields(map[string]httpio.ListField{
+		"username": {Operators: []string{httpio.FilterEq, httpio.FilterLike}, Sortable: true},
+		"email":    {Operators: []string{httpio.FilterEq, httpio.FilterLike}, Sortable: true},
+	})
 }
 
 func (m User) GetAllQuery(db *gorm.DB, r *http.Request) *gorm.DB {
-	return db.Preload("Roles")
+	return db.Preload("Roles.Permissions")
 }
 
 func (m User) GetQuery(db *gorm.DB, r *http.Request) *gorm.DB {
 	db = onlyCurrentUser(db, r)
-	db = db.Preload("Roles")
+	db = db.Preload("Roles.Permissions")
 	if httpio.IntPathValue(r, "id") != 0 {
 		db = GetByIdQuery(db, r)
 	}
@@ -32,6 +49,84 @@ func (m User) GetQuery(db *gorm.DB, r *http.Request) *gorm.DB {
 	return db
 }
 
+func (m User) HasAnyRole(roleNames ...string) bool {
+	for _, role := range m.Roles {
+		if slices.Contains(roleNames, role.Name) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// IsSuspended is true also for banned users, whose suspension has no end
+func (m User) IsSuspended() bool {
+	if m.SuspendedAt == nil {
+		return false
+	}
+
+	return m.SuspendedUntil == nil || time.Now().Before(*m.SuspendedUntil)
+}
+
+func (m User) SuspendedError() httpio.UserSuspendedError {
+	suspendedError := httpio.UserSuspendedError{}
+	if m.SuspendedUntil != nil {
+		suspendedUntil := m.SuspendedUntil.UTC().Format(time.RFC3339)
+		suspendedError.SuspendedUntil = &suspendedUntil
+	}
+
+	return suspendedError
+}
+
+// HasPermission is true if any of the user roles grants the permission, roles with permissions must be preloaded
+func (m User) HasPermission(permission string) bool {
+	for _, role := range m.Roles {
+		if role.HasPermission(permission) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// RestrictToScopes drops permissions which were not granted to the personal access token, roles with permissions must be preloaded
+func (m *User) RestrictToScopes(scopes []string) {
+	for i, role := range m.Roles {
+		permissions := []RolePermission{}
+		for _, rolePermission := range role.Permissions {
+			if slices.Contains(scopes, rolePermission.Permission) {
+				permissions = append(permissions, rolePermission)
+			}
+		}
+		m.Roles[i].Permissions = permissions
+	}
+}
+
+func (m User) PermissionNames() []string {
+	permissions := []string{}
+	for _, role := range m.Roles {
+		for _, permission := range role.PermissionNames() {
+			if !slices.Contains(permissions, permission) {
+				permissions = append(permissions, permission)
+			}
+		}
+	}
+	slices.Sort(permissions)
+
+	return permissions
+}
+
+// RequiresTwoFactor is true if any of the user roles requires 2FA, roles must be preloaded
+func (m User) RequiresTwoFactor() bool {
+	for _, role := range m.Roles {
+		if role.RequiresTwoFactor {
+			return true
+		}
+	}
+
+	return false
+}
+
 func onlyCurrentUser(db *gorm.DB, r *http.Request) *gorm.DB {
 	onlyForCurrentUser := r.Context().Value(httpio.OnlyCurrentUserContextKey).(bool)
 	if onlyForCurrentUser {
@@ -43,12 +138,19 @@ func onlyCurrentUser(db *gorm.DB, r *http.Request) *gorm.DB {
 }
 
 type UserResponse struct {
-	ID        uint      `json:"id"`
-	Email     string    `json:"email"`
-	Username  string    `json:"username"`
-	Roles     []string  `json:"roles"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID             uint       `json:"id"`
+	Email          string     `json:"email"`
+	Username       string     `json:"username"`
+	Roles          []string   `json:"roles"`
+	Permissions    []string   `json:"permissions"`
+	Suspended      bool       `json:"suspended"`
+	SuspendedUntil *time.Time `json:"suspended_until"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
+
+func (m User) AuditResourceType() string {
+	return AuditResourceUser
 }
 
 func (m User) BuildResponse() any {
@@ -58,11 +160,14 @@ func (m User) BuildResponse() any {
 	}
 
 	return UserResponse{
-		ID:        m.ID,
-		Email:     m.Email,
-		Username:  m.Username,
-		Roles:     roles,
-		CreatedAt: m.CreatedAt,
-		UpdatedAt: m.UpdatedAt,
+		ID:             m.ID,
+		Email:          m.Email,
+		Username:       m.Username,
+		Roles:          roles,
+		Permissions:    m.PermissionNames(),
+		Suspended:      m.IsSuspended(),
+		SuspendedUntil: m.SuspendedUntil,
+		CreatedAt:      m.CreatedAt,
+		UpdatedAt:      m.UpdatedAt,
 	}
 }
@@ -0,0 +1,36 @@
+package models
+
+import (
+	"time"
+)
+
+const OidcLoginRequestExpirationMinutes = 10
+
+// external identity (OpenID Connect provider account) linked to the user
+type UserIdentity struct {
+	AppModel
+	UserID   uint   `json:"user_id" gorm:"not null;index"`
+	Provider string `json:"provider" gorm:"not null;uniqueIndex:idx_user_identities_provider_subject"`
+	Subject  string `json:"-" gorm:"not null;uniqueIndex:idx_user_identities_provider_subject"`
+	Email    string `json:"email" gorm:"not null"`
+}
+
+// state of authorization request started by the user, consumed by the callback.
+// Only the hash of the state is stored, nonce and code verifier are needed in plain form for the code exchange.
+type OidcLoginRequest struct {
+	AppModel
+	Provider     string    `json:"provider" gorm:"not null"`
+	StateHash    string    `json:"-" gorm:"not null;unique"`
+	Nonce        string    `json:"-" gorm:"not null"`
+	CodeVerifier string    `json:"-" gorm:"not null"`
+	ExpiresAt    time.Time `json:"expires_at" gorm:"not null;index"`
+}
+
+func (olr *OidcLoginRequest) SetState(state string) {
+	olr.StateHash = HashLookupToken(state)
+	olr.ExpiresAt = time.Now().Add(time.Duration(OidcLoginRequestExpirationMinutes) * time.Minute)
+}
+
+func (olr OidcLoginRequest) IsExpired() bool {
+	return time.Now().After(olr.ExpiresAt)
+}
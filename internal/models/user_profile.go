@@ -0,0 +1,66 @@
+package models
+
+import (
+	"time"
+)
+
+const (
+	MaxFavouriteDisciplines = 10
+	MaxFavouriteAthletes    = 10
+)
+
+// UserProfile holds public information about the user, it is created on the first update
+type UserProfile struct {
+	AppModel
+	UserID               uint         `json:"user_id" gorm:"not null;unique"`
+	DisplayName          string       `json:"display_name" gorm:"not null;default:''"`
+	Country              *string      `json:"country"`
+	Bio                  string       `json:"bio" gorm:"not null;default:''"`
+	AvatarKey            *string      `json:"-"`
+	FavouriteDisciplines []Discipline `json:"favourite_disciplines" gorm:"many2many:user_profile_disciplines;constraint:OnDelete:CASCADE"`
+	FavouriteAthletes    []Athlete    `json:"favourite_athletes" gorm:"many2many:user_profile_athletes;constraint:OnDelete:CASCADE"`
+}
+
+// UserProfileResponse is public, so it must not contain email or any other private data
+type UserProfileResponse struct {
+	UserID               uint                 `json:"user_id"`
+	Username             string               `json:"username"`
+	DisplayName          string               `json:"display_name"`
+	Country              *string              `json:"country"`
+	Bio                  string               `json:"bio"`
+	AvatarURL            *string              `json:"avatar_url"`
+	FavouriteDisciplines []DisciplineResponse `json:"favourite_disciplines"`
+	FavouriteAthletes    []AthleteResponse    `json:"favourite_athletes"`
+	MemberSince          time.Time            `json:"member_since"`
+}
+
+// BuildProfileResponse works also for users without profile, avatarURL maps storage key to public url
+func (m UserProfile) BuildProfileResponse(user User, avatarURL func(key string) string) UserProfileResponse {
+	disciplines := make([]DisciplineResponse, len(m.FavouriteDisciplines))
+	for i, discipline := range m.FavouriteDisciplines {
+		disciplines[i] = discipline.BuildResponse().(DisciplineResponse)
+	}
+
+	athletes := make([]AthleteResponse, len(m.FavouriteAthletes))
+	for i, athlete := range m.FavouriteAthletes {
+		athletes[i] = athlete.BuildResponse().(AthleteResponse)
+	}
+
+	response := UserProfileResponse{
+		UserID:               user.ID,
+		Username:             user.Username,
+		DisplayName:          m.DisplayName,
+		Country:              m.Country,
+		Bio:                  m.Bio,
+		FavouriteDisciplines: disciplines,
+		FavouriteAthletes:    athletes,
+		MemberSince:          user.CreatedAt,
+	}
+
+	if m.AvatarKey != nil {
+		url := avatarURL(*m.AvatarKey)
+		response.AvatarURL = &url
+	}
+
+	return response
+}
@@ -0,0 +1,187 @@
+package models
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"math/big"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/filipio/athletics-backend/pkg/httpio"
+	"gorm.io/gorm"
+)
+
+const (
+	UsernameMinLength = 3
+	UsernameMaxLength = 30
+)
+
+// letters, digits and "_", "." or "-" inside, so usernames are readable in rankings and urls
+var usernamePattern = regexp.MustCompile(`^[A-Za-z0-9](?:[A-Za-z0-9_.-]*[A-Za-z0-9])?$`)
+
+// usernames generated for moderated, OIDC and deleted users, reserved so they never collide with chosen ones
+var generatedUsernamePattern = regexp.MustCompile(`^(user|deleted|athlete)_[0-9]+$`)
+
+var reservedUsernames = []string{
+	"admin", "administrator", "root", "system", "support", "moderator", "organizer", "staff", "official",
+	"api", "auth", "login", "logout", "register", "me", "null", "undefined", "anonymous", "no_name",
+	"lekkoatletawka", "athletics",
+}
+
+// matched as a part of the username, after lowercasing and dropping separators
+var profaneParts = []string{
+	"fuck", "shit", "bitch", "cunt", "whore", "nigger", "faggot", "rapist", "nazi", "hitler",
+	"kurwa", "chuj", "pierdol", "jebac", "jebany", "pizda", "cwel", "szmata", "dziwka",
+}
+
+// ValidateUsername checks the format and blocklists, it doesn't touch the database
+func ValidateUsername(username string) error {
+	if len(username) < UsernameMinLength || len(username) > UsernameMaxLength {
+		return usernameError(fmt.Sprintf("must be between %d and %d characters long", UsernameMinLength, UsernameMaxLength))
+	}
+
+	if !usernamePattern.MatchString(username) {
+		return usernameError("can contain only letters, digits, '_', '.' and '-', and must start and end with a letter or digit")
+	}
+
+	lowered := strings.ToLower(username)
+	if slices.Contains(reservedUsernames, lowered) || generatedUsernamePattern.MatchString(lowered) {
+		return usernameError("is reserved")
+	}
+
+	if ContainsProfanity(username) {
+		return usernameError("is not allowed")
+	}
+
+	return nil
+}
+
+// ContainsProfanity checks the text against the blocklist, ignoring case, spaces and separators
+func ContainsProfanity(text string) bool {
+	normalized := strings.NewReplacer("_", "", ".", "", "-", "", " ", "").Replace(strings.ToLower(text))
+	for _, part := range profaneParts {
+		if strings.Contains(normalized, part) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ValidateUsernameAvailable checks the username rules and case-insensitive uniqueness among users and pending registrations.
+// Pending registrations of exceptEmail and the user with exceptUserID are skipped, so they can keep their username.
+func ValidateUsernameAvailable(db *gorm.DB, username string, exceptUserID uint, exceptEmail string) error {
+	if err := ValidateUsername(username); err != nil {
+		return err
+	}
+
+	taken, err := IsUsernameTaken(db, username, exceptUserID, exceptEmail)
+	if err != nil {
+		return err
+	}
+	if taken {
+		return usernameError("is already taken")
+	}
+
+	return nil
+}
+
+func IsUsernameTaken(db *gorm.DB, username string, exceptUserID uint, exceptEmail string) (bool, error) {
+	var usersCount int64
+	if err := db.Model(&User{}).
+		Where("LOWER(username) = LOWER(?) AND id <> ?", username, exceptUserID).
+		Count(&usersCount).Error; err != nil {
+		return false, err
+	}
+	if usersCount > 0 {
+		return true, nil
+	}
+
+	// username of not expired registration is reserved, so its verification can't fail on duplicate
+	var pendingCount int64
+	if err := db.Model(&PendingRegistration{}).
+		Where("LOWER(username) = LOWER(?) AND email <> ? AND verified = ? AND expires_at > ?", username, exceptEmail, false, time.Now()).
+		Count(&pendingCount).Error; err != nil {
+		return false, err
+	}
+
+	return pendingCount > 0, nil
+}
+
+// AvailableUsername turns the candidate (e.g. name from OIDC provider) into a valid username which isn't taken yet.
+// Random suffix is added when needed, generated username (reserved, so only taken by other generated ones) is the last resort.
+func AvailableUsername(db *gorm.DB, candidate string) (string, error) {
+	base := sanitizeUsername(candidate)
+
+	for attempt := 0; attempt < 5; attempt++ {
+		username := base
+		if attempt > 0 {
+			suffix := fmt.Sprintf("_%d", randomUsernameSuffix())
+			username = strings.TrimRight(truncate(base, UsernameMaxLength-len(suffix)), "_.-") + suffix
+		}
+
+		if ValidateUsername(username) != nil {
+			continue
+		}
+
+		taken, err := IsUsernameTaken(db, username, 0, "")
+		if err != nil {
+			return "", err
+		}
+		if !taken {
+			return username, nil
+		}
+	}
+
+	for attempt := 0; attempt < 5; attempt++ {
+		username := fmt.Sprintf("athlete_%d", randomUsernameSuffix())
+		taken, err := IsUsernameTaken(db, username, 0, "")
+		if err != nil {
+			return "", err
+		}
+		if !taken {
+			return username, nil
+		}
+	}
+
+	return "", errors.New("no available username found")
+}
+
+func sanitizeUsername(candidate string) string {
+	var builder strings.Builder
+	for _, char := range strings.TrimSpace(candidate) {
+		switch {
+		case char >= 'a' && char <= 'z', char >= 'A' && char <= 'Z', char >= '0' && char <= '9', char == '.', char == '-':
+			builder.WriteRune(char)
+		default:
+			builder.WriteRune('_')
+		}
+	}
+
+	return strings.Trim(truncate(builder.String(), UsernameMaxLength), "_.-")
+}
+
+func truncate(value string, maxLength int) string {
+	if len(value) > maxLength {
+		return value[:maxLength]
+	}
+	return value
+}
+
+func randomUsernameSuffix() int64 {
+	number, err := rand.Int(rand.Reader, big.NewInt(100000))
+	if err != nil {
+		return time.Now().UnixNano() % 100000
+	}
+	return number.Int64()
+}
+
+func usernameError(message string) httpio.AppValidationError {
+	return httpio.AppValidationError{
+		FieldPath: "username",
+		AppError:  httpio.AppError{Message: message},
+	}
+}
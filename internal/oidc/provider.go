@@ -0,0 +1,202 @@
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+
+	gooidc "github.com/coreos/go-oidc/v3/oidc"
+	"golang.org/x/oauth2"
+)
+
+var defaultIssuers = map[string]string{
+	"google": "https://accounts.google.com",
+	"apple":  "https://appleid.apple.com",
+}
+
+var defaultScopes = []string{gooidc.ScopeOpenID, "email", "profile"}
+
+type ProviderConfig struct {
+	Name         string
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	Scopes       []string
+}
+
+// Provider performs authorization code flow with PKCE against OpenID Connect provider.
+// Discovery document is fetched on first use, so unavailable provider does not prevent server from starting.
+type Provider struct {
+	config       ProviderConfig
+	discoverLock sync.Mutex
+	oidcProvider *gooidc.Provider
+}
+
+// AuthorizationRequest holds values which have to be kept until the callback is received
+type AuthorizationRequest struct {
+	URL          string
+	State        string
+	Nonce        string
+	CodeVerifier string
+}
+
+// Identity of the user returned by the provider in ID token
+type Identity struct {
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+}
+
+func NewProvider(config ProviderConfig) *Provider {
+	if len(config.Scopes) == 0 {
+		config.Scopes = defaultScopes
+	}
+
+	return &Provider{config: config}
+}
+
+// LoadProviders reads providers listed in OIDC_PROVIDERS (comma separated names),
+// each configured with OIDC_<NAME>_ISSUER, OIDC_<NAME>_CLIENT_ID, OIDC_<NAME>_CLIENT_SECRET, OIDC_<NAME>_REDIRECT_URL and optional OIDC_<NAME>_SCOPES
+func LoadProviders() map[string]*Provider {
+	providers := map[string]*Provider{}
+
+	for _, name := range strings.Split(os.Getenv("OIDC_PROVIDERS"), ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+
+		envPrefix := "OIDC_" + strings.ToUpper(name) + "_"
+		config := ProviderConfig{
+			Name:         name,
+			Issuer:       os.Getenv(envPrefix + "ISSUER"),
+			ClientID:     os.Getenv(envPrefix + "CLIENT_ID"),
+			ClientSecret: os.Getenv(envPrefix + "CLIENT_SECRET"),
+			RedirectURL:  os.Getenv(envPrefix + "REDIRECT_URL"),
+		}
+		if config.Issuer == "" {
+			config.Issuer = defaultIssuers[name]
+		}
+		if scopes := os.Getenv(envPrefix + "SCOPES"); scopes != "" {
+			config.Scopes = strings.Fields(scopes)
+		}
+
+		if config.Issuer == "" || config.ClientID == "" || config.RedirectURL == "" {
+			slog.Error("oidc provider is not fully configured, skipping", "provider", name)
+			continue
+		}
+
+		providers[name] = NewProvider(config)
+	}
+
+	return providers
+}
+
+func (p *Provider) Name() string {
+	return p.config.Name
+}
+
+// NewAuthorizationRequest builds URL to which the user has to be redirected, with fresh state, nonce and PKCE verifier
+func (p *Provider) NewAuthorizationRequest(ctx context.Context) (AuthorizationRequest, error) {
+	oauth2Config, _, err := p.oauth2Config(ctx)
+	if err != nil {
+		return AuthorizationRequest{}, err
+	}
+
+	authRequest := AuthorizationRequest{
+		State:        rand.Text(),
+		Nonce:        rand.Text(),
+		CodeVerifier: oauth2.GenerateVerifier(),
+	}
+	authRequest.URL = oauth2Config.AuthCodeURL(
+		authRequest.State,
+		gooidc.Nonce(authRequest.Nonce),
+		oauth2.S256ChallengeOption(authRequest.CodeVerifier),
+	)
+
+	return authRequest, nil
+}
+
+// Exchange redeems authorization code and verifies returned ID token (signature, issuer, audience, expiry and nonce)
+func (p *Provider) Exchange(ctx context.Context, code string, codeVerifier string, nonce string) (Identity, error) {
+	oauth2Config, oidcProvider, err := p.oauth2Config(ctx)
+	if err != nil {
+		return Identity{}, err
+	}
+
+	oauth2Token, err := oauth2Config.Exchange(ctx, code, oauth2.VerifierOption(codeVerifier))
+	if err != nil {
+		return Identity{}, fmt.Errorf("code exchange failed: %w", err)
+	}
+
+	rawIDToken, ok := oauth2Token.Extra("id_token").(string)
+	if !ok {
+		return Identity{}, errors.New("id_token missing in token response")
+	}
+
+	idToken, err := oidcProvider.Verifier(&gooidc.Config{ClientID: p.config.ClientID}).Verify(ctx, rawIDToken)
+	if err != nil {
+		return Identity{}, fmt.Errorf("id_token verification failed: %w", err)
+	}
+
+	if idToken.Nonce != nonce {
+		return Identity{}, errors.New("id_token nonce mismatch")
+	}
+
+	var claims struct {
+		Email         string `json:"email"`
+		EmailVerified any    `json:"email_verified"`
+		Name          string `json:"name"`
+	}
+	if err := idToken.Claims(&claims); err != nil {
+		return Identity{}, err
+	}
+
+	return Identity{
+		Subject: idToken.Subject,
+		Email:   claims.Email,
+		// some providers (e.g. Apple) send email_verified as a string
+		EmailVerified: claims.EmailVerified == true || claims.EmailVerified == "true",
+		Name:          claims.Name,
+	}, nil
+}
+
+func (p *Provider) oauth2Config(ctx context.Context) (oauth2.Config, *gooidc.Provider, error) {
+	oidcProvider, err := p.discover(ctx)
+	if err != nil {
+		return oauth2.Config{}, nil, err
+	}
+
+	return oauth2.Config{
+		ClientID:     p.config.ClientID,
+		ClientSecret: p.config.ClientSecret,
+		RedirectURL:  p.config.RedirectURL,
+		Endpoint:     oidcProvider.Endpoint(),
+		Scopes:       p.config.Scopes,
+	}, oidcProvider, nil
+}
+
+func (p *Provider) discover(ctx context.Context) (*gooidc.Provider, error) {
+	p.discoverLock.Lock()
+	defer p.discoverLock.Unlock()
+
+	if p.oidcProvider != nil {
+		return p.oidcProvider, nil
+	}
+
+	// provider keeps using the context for fetching signing keys, so it must not be bound to the request
+	oidcProvider, err := gooidc.NewProvider(context.WithoutCancel(ctx), p.config.Issuer)
+	if err != nil {
+		return nil, fmt.Errorf("oidc discovery for %s failed: %w", p.config.Name, err)
+	}
+
+	p.oidcProvider = oidcProvider
+	return oidcProvider, nil
+}
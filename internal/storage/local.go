@@ -0,0 +1,99 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// LocalFilesPath is the path under which the server itself serves files of the local storage
+const LocalFilesPath = "/uploads/"
+
+// LocalStorage keeps files on the local disk, it is also http.Handler serving them
+type LocalStorage struct {
+	dir       string
+	publicURL string
+	server    http.Handler
+}
+
+// NewLocalStorage stores files in dir, publicURL is prepended to the file paths (empty means relative urls)
+func NewLocalStorage(dir string, publicURL string) (*LocalStorage, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	return &LocalStorage{
+		dir:       dir,
+		publicURL: strings.TrimRight(publicURL, "/"),
+		server:    http.StripPrefix(LocalFilesPath, http.FileServer(http.Dir(dir))),
+	}, nil
+}
+
+func (s *LocalStorage) Save(ctx context.Context, key string, content io.Reader, size int64, contentType string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	_, err = io.Copy(file, content)
+	return err
+}
+
+func (s *LocalStorage) Delete(ctx context.Context, key string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+func (s *LocalStorage) Open(ctx context.Context, key string) (io.ReadCloser, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return os.Open(path)
+}
+
+func (s *LocalStorage) URL(key string) string {
+	return s.publicURL + LocalFilesPath + key
+}
+
+// ServeHTTP serves public files, private files and directory listings are not found
+func (s *LocalStorage) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	key := strings.TrimPrefix(path.Clean(r.URL.Path), LocalFilesPath)
+	if strings.HasPrefix(key, PrivatePrefix) || strings.HasSuffix(r.URL.Path, "/") {
+		http.NotFound(w, r)
+		return
+	}
+
+	s.server.ServeHTTP(w, r)
+}
+
+// path makes sure the key can't point outside of the storage directory
+func (s *LocalStorage) path(key string) (string, error) {
+	if !filepath.IsLocal(key) {
+		return "", errors.New("invalid storage key " + key)
+	}
+	return filepath.Join(s.dir, key), nil
+}
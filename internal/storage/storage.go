@@ -0,0 +1,50 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+// PrivatePrefix is the prefix of keys of files which must not be publicly available (e.g. data exports),
+// for s3 storage the bucket policy must not allow public reads under this prefix
+const PrivatePrefix = "private/"
+
+// FileStorage keeps uploaded files (e.g. avatars) under keys like "avatars/12/abc.jpg"
+type FileStorage interface {
+	Save(ctx context.Context, key string, content io.Reader, size int64, contentType string) error
+	Delete(ctx context.Context, key string) error
+	// Open reads the file, it is used for private files which are not available under public URL
+	Open(ctx context.Context, key string) (io.ReadCloser, error)
+	// URL returns public address of the file
+	URL(key string) string
+}
+
+// LoadStorage creates the storage configured with STORAGE_DRIVER, which is either "local" (default) or "s3"
+func LoadStorage() (FileStorage, error) {
+	driver := strings.ToLower(os.Getenv("STORAGE_DRIVER"))
+	switch driver {
+	case "", "local":
+		dir := os.Getenv("STORAGE_LOCAL_DIR")
+		if dir == "" {
+			dir = "uploads"
+		}
+		return NewLocalStorage(dir, os.Getenv("STORAGE_PUBLIC_URL"))
+	case "s3":
+		return NewS3Storage(S3Config{
+			Endpoint:        os.Getenv("S3_ENDPOINT"),
+			Region:          os.Getenv("S3_REGION"),
+			Bucket:          os.Getenv("S3_BUCKET"),
+			AccessKeyID:     os.Getenv("S3_ACCESS_KEY_ID"),
+			SecretAccessKey: os.Getenv("S3_SECRET_ACCESS_KEY"),
+			UseSSL:          os.Getenv("S3_USE_SSL") != "false",
+			PublicURL:       os.Getenv("STORAGE_PUBLIC_URL"),
+		})
+	default:
+		slog.Error("unknown storage driver", "driver", driver)
+		return nil, fmt.Errorf("unknown storage driver %q", driver)
+	}
+}
@@ -0,0 +1,6 @@
+package args
+
+// CleanupArgs has no fields, the job is scheduled periodically
+type CleanupArgs struct{}
+
+func (CleanupArgs) Kind() string { return "cleanup" }
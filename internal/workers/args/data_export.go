@@ -0,0 +1,7 @@
+package args
+
+type DataExportArgs struct {
+	ExportID uint `json:"export_id"`
+}
+
+func (DataExportArgs) Kind() string { return "data_export" }
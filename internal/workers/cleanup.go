@@ -0,0 +1,142 @@
+package workers
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+	"time"
+
+	"github.com/filipio/athletics-backend/internal/models"
+	args "github.com/filipio/athletics-backend/internal/workers/args"
+	"github.com/filipio/athletics-backend/pkg/config"
+	"github.com/riverqueue/river"
+)
+
+const CleanupInterval = time.Hour
+
+// revoked refresh tokens are kept for a while, so reuse of a token from revoked session is still reported as such
+const RevokedRefreshTokenRetention = 7 * 24 * time.Hour
+
+// CleanupWorker purges rows which are no longer used: expired pending registrations, rate limits whose window
+// has passed, expired or revoked refresh and personal access tokens, other expired authentication rows (OIDC login requests,
+// 2FA login challenges, magic links, password resets, email changes) and expired data exports with their files
+type CleanupWorker struct {
+	river.WorkerDefaults[args.CleanupArgs]
+	deps *config.Dependencies
+}
+
+func NewCleanupWorker(deps *config.Dependencies) *CleanupWorker {
+	return &CleanupWorker{deps: deps}
+}
+
+func (w *CleanupWorker) Work(ctx context.Context, job *river.Job[args.CleanupArgs]) error {
+	db := w.deps.DB.WithContext(ctx)
+	now := time.Now()
+
+	pendingRegistrations := db.Where("expires_at < ?", now).Delete(&models.PendingRegistration{})
+	if pendingRegistrations.Error != nil {
+		return pendingRegistrations.Error
+	}
+
+	rateLimitsCount := int64(0)
+	for _, rateLimit := range []struct {
+		model  any
+		window time.Duration
+	}{
+		{&models.EmailVerificationRateLimit{}, time.Duration(models.VerificationWindowMinutes) * time.Minute},
+		{&models.PasswordResetRateLimit{}, time.Duration(models.PasswordResetWindowMinutes) * time.Minute},
+		{&models.MagicLinkRateLimit{}, time.Duration(models.MagicLinkWindowMinutes) * time.Minute},
+	} {
+		result := db.Where("(blocked_until IS NULL OR blocked_until < ?) AND (last_request_at IS NULL OR last_request_at < ?)",
+			now, now.Add(-rateLimit.window)).Delete(rateLimit.model)
+		if result.Error != nil {
+			return result.Error
+		}
+		rateLimitsCount += result.RowsAffected
+	}
+
+	loginAttemptLimits := db.Where("(locked_until IS NULL OR locked_until < ?) AND (last_failed_at IS NULL OR last_failed_at < ?)",
+		now, now.Add(-time.Duration(models.FailedLoginWindowHours)*time.Hour)).Delete(&models.LoginAttemptLimit{})
+	if loginAttemptLimits.Error != nil {
+		return loginAttemptLimits.Error
+	}
+	rateLimitsCount += loginAttemptLimits.RowsAffected
+
+	refreshTokens := db.Where("expires_at < ? OR revoked_at < ?", now, now.Add(-RevokedRefreshTokenRetention)).
+		Delete(&models.RefreshToken{})
+	if refreshTokens.Error != nil {
+		return refreshTokens.Error
+	}
+
+	// short-lived authentication rows are useless once they expire, used tokens are kept until then
+	authRowsCount := int64(0)
+	for _, expiring := range []struct {
+		model any
+		query string
+	}{
+		{&models.OidcLoginRequest{}, "expires_at < @now"},
+		{&models.LoginChallenge{}, "expires_at < @now"},
+		{&models.MagicLinkToken{}, "expires_at < @now"},
+		{&models.PasswordResetToken{}, "expires_at < @now"},
+		{&models.PendingEmailChange{}, "expires_at < @now"},
+		{&models.PersonalAccessToken{}, "expires_at < @now OR revoked_at < @now"},
+	} {
+		result := db.Where(expiring.query, sql.Named("now", now)).Delete(expiring.model)
+		if result.Error != nil {
+			return result.Error
+		}
+		authRowsCount += result.RowsAffected
+	}
+
+	dataExportsCount, err := w.deleteExpiredDataExports(ctx, now)
+	if err != nil {
+		return err
+	}
+
+	slog.Info("cleanup finished",
+		"pending_registrations", pendingRegistrations.RowsAffected,
+		"rate_limits", rateLimitsCount,
+		"refresh_tokens", refreshTokens.RowsAffected,
+		"auth_rows", authRowsCount,
+		"data_exports", dataExportsCount)
+
+	return nil
+}
+
+// files are removed first, so the row of the export whose file could not be removed is retried by the next cleanup
+func (w *CleanupWorker) deleteExpiredDataExports(ctx context.Context, now time.Time) (int, error) {
+	db := w.deps.DB.WithContext(ctx)
+
+	var dataExports []models.DataExport
+	if err := db.Where("expires_at < ?", now).Find(&dataExports).Error; err != nil {
+		return 0, err
+	}
+
+	deletedIDs := []uint{}
+	for _, dataExport := range dataExports {
+		if dataExport.FileKey != nil {
+			if err := w.deps.Storage.Delete(ctx, *dataExport.FileKey); err != nil {
+				slog.Error("error deleting data export from storage", "key", *dataExport.FileKey, "error", err)
+				continue
+			}
+		}
+		deletedIDs = append(deletedIDs, dataExport.ID)
+	}
+
+	if len(deletedIDs) == 0 {
+		return 0, nil
+	}
+
+	return len(deletedIDs), db.Delete(&models.DataExport{}, deletedIDs).Error
+}
+
+// CleanupPeriodicJob schedules the cleanup every CleanupInterval and once on start
+func CleanupPeriodicJob() *river.PeriodicJob {
+	return river.NewPeriodicJob(
+		river.PeriodicInterval(CleanupInterval),
+		func() (river.JobArgs, *river.InsertOpts) {
+			return args.CleanupArgs{}, nil
+		},
+		&river.PeriodicJobOpts{RunOnStart: true},
+	)
+}
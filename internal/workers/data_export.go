@@ -0,0 +1,169 @@
+package workers
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"path"
+	"time"
+
+	"github.com/filipio/athletics-backend/internal/models"
+	"github.com/filipio/athletics-backend/internal/storage"
+	args "github.com/filipio/athletics-backend/internal/workers/args"
+	"github.com/filipio/athletics-backend/pkg/config"
+	"github.com/riverqueue/river"
+)
+
+// DataExportDocument is the content of data.json in the export archive
+type DataExportDocument struct {
+	ExportedAt           time.Time                            `json:"exported_at"`
+	User                 models.UserResponse                  `json:"user"`
+	Profile              models.UserProfileResponse           `json:"profile"`
+	Answers              []models.Answer                      `json:"answers"`
+	TotalPoints          int                                  `json:"total_points"`
+	Sessions             []models.SessionResponse             `json:"sessions"`
+	PersonalAccessTokens []models.PersonalAccessTokenResponse `json:"personal_access_tokens"`
+	Identities           []models.UserIdentity                `json:"identities"`
+	ModerationActions    []models.ModerationAction            `json:"moderation_actions"`
+}
+
+// DataExportWorker builds zip archive with data.json and the avatar of the user and stores it as private file
+type DataExportWorker struct {
+	river.WorkerDefaults[args.DataExportArgs]
+	deps *config.Dependencies
+}
+
+func NewDataExportWorker(deps *config.Dependencies) *DataExportWorker {
+	return &DataExportWorker{deps: deps}
+}
+
+func (w *DataExportWorker) Work(ctx context.Context, job *river.Job[args.DataExportArgs]) error {
+	db := w.deps.DB.WithContext(ctx)
+
+	var export models.DataExport
+	db.First(&export, job.Args.ExportID)
+	if export.ID == 0 || export.Status != models.DataExportStatusPending {
+		return nil
+	}
+
+	archive, err := w.buildArchive(ctx, export.UserID)
+	if err == nil {
+		key := fmt.Sprintf("%sexports/%d/%s.zip", storage.PrivatePrefix, export.UserID, rand.Text())
+		err = w.deps.Storage.Save(ctx, key, bytes.NewReader(archive), int64(len(archive)), "application/zip")
+		if err == nil {
+			now := time.Now()
+			expiresAt := now.Add(models.DataExportRetention)
+			return db.Model(&export).Updates(models.DataExport{
+				Status:      models.DataExportStatusReady,
+				FileKey:     &key,
+				CompletedAt: &now,
+				ExpiresAt:   &expiresAt,
+			}).Error
+		}
+	}
+
+	slog.Error("error building data export", "export_id", export.ID, "attempt", job.Attempt, "error", err)
+	if job.Attempt >= job.MaxAttempts {
+		now := time.Now()
+		db.Model(&export).Updates(models.DataExport{Status: models.DataExportStatusFailed, CompletedAt: &now})
+	}
+
+	return err
+}
+
+func (w *DataExportWorker) buildArchive(ctx context.Context, userID uint) ([]byte, error) {
+	db := w.deps.DB.WithContext(ctx)
+
+	var user models.User
+	if err := db.Preload("Roles.Permissions").First(&user, userID).Error; err != nil {
+		return nil, err
+	}
+
+	var profile models.UserProfile
+	db.Preload("FavouriteDisciplines").Preload("FavouriteAthletes").Where("user_id = ?", userID).First(&profile)
+
+	document := DataExportDocument{
+		ExportedAt: time.Now(),
+		User:       user.BuildResponse().(models.UserResponse),
+		Profile:    profile.BuildProfileResponse(user, w.deps.Storage.URL),
+	}
+
+	if err := db.Where("user_id = ?", userID).Order("id").Find(&document.Answers).Error; err != nil {
+		return nil, err
+	}
+	for _, answer := range document.Answers {
+		document.TotalPoints += int(answer.Points)
+	}
+
+	var refreshTokens []models.RefreshToken
+	if err := db.Where("user_id = ?", userID).Order("id").Find(&refreshTokens).Error; err != nil {
+		return nil, err
+	}
+	document.Sessions = make([]models.SessionResponse, len(refreshTokens))
+	for i, refreshToken := range refreshTokens {
+		document.Sessions[i] = refreshToken.BuildSessionResponse("")
+	}
+
+	var personalAccessTokens []models.PersonalAccessToken
+	if err := db.Where("user_id = ?", userID).Order("id").Find(&personalAccessTokens).Error; err != nil {
+		return nil, err
+	}
+	document.PersonalAccessTokens = make([]models.PersonalAccessTokenResponse, len(personalAccessTokens))
+	for i, personalAccessToken := range personalAccessTokens {
+		document.PersonalAccessTokens[i] = personalAccessToken.BuildResponse().(models.PersonalAccessTokenResponse)
+	}
+
+	if err := db.Where("user_id = ?", userID).Order("id").Find(&document.Identities).Error; err != nil {
+		return nil, err
+	}
+
+	if err := db.Where("target_user_id = ?", userID).Order("id").Find(&document.ModerationActions).Error; err != nil {
+		return nil, err
+	}
+
+	buffer := bytes.Buffer{}
+	zipWriter := zip.NewWriter(&buffer)
+
+	dataFile, err := zipWriter.Create("data.json")
+	if err != nil {
+		return nil, err
+	}
+	encoder := json.NewEncoder(dataFile)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(document); err != nil {
+		return nil, err
+	}
+
+	if profile.AvatarKey != nil {
+		if err := copyToArchive(ctx, w.deps.Storage, zipWriter, *profile.AvatarKey, "avatar"+path.Ext(*profile.AvatarKey)); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := zipWriter.Close(); err != nil {
+		return nil, err
+	}
+
+	return buffer.Bytes(), nil
+}
+
+func copyToArchive(ctx context.Context, fileStorage storage.FileStorage, zipWriter *zip.Writer, key string, name string) error {
+	file, err := fileStorage.Open(ctx, key)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	archiveFile, err := zipWriter.Create(name)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(archiveFile, file)
+	return err
+}
@@ -0,0 +1,19 @@
+-- Create "pending_email_changes" table
+CREATE TABLE "pending_email_changes" (
+  "id" bigserial NOT NULL,
+  "created_at" timestamptz NULL,
+  "updated_at" timestamptz NULL,
+  "user_id" bigint NOT NULL,
+  "new_email" text NOT NULL,
+  "verification_token" text NOT NULL,
+  "expires_at" timestamptz NOT NULL,
+  PRIMARY KEY ("id")
+);
+-- Create index "idx_pending_email_changes_expires_at" to table: "pending_email_changes"
+CREATE INDEX "idx_pending_email_changes_expires_at" ON "pending_email_changes" ("expires_at");
+-- Create index "idx_pending_email_changes_new_email" to table: "pending_email_changes"
+CREATE INDEX "idx_pending_email_changes_new_email" ON "pending_email_changes" ("new_email");
+-- Create index "idx_pending_email_changes_user_id" to table: "pending_email_changes"
+CREATE INDEX "idx_pending_email_changes_user_id" ON "pending_email_changes" ("user_id");
+-- Create index "idx_pending_email_changes_verification_token" to table: "pending_email_changes"
+CREATE INDEX "idx_pending_email_changes_verification_token" ON "pending_email_changes" ("verification_token");
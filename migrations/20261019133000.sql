@@ -0,0 +1,31 @@
+-- Create "oidc_login_requests" table
+CREATE TABLE "oidc_login_requests" (
+  "id" bigserial NOT NULL,
+  "created_at" timestamptz NULL,
+  "updated_at" timestamptz NULL,
+  "provider" text NOT NULL,
+  "state_hash" text NOT NULL,
+  "nonce" text NOT NULL,
+  "code_verifier" text NOT NULL,
+  "expires_at" timestamptz NOT NULL,
+  PRIMARY KEY ("id"),
+  CONSTRAINT "uni_oidc_login_requests_state_hash" UNIQUE ("state_hash")
+);
+-- Create index "idx_oidc_login_requests_expires_at" to table: "oidc_login_requests"
+CREATE INDEX "idx_oidc_login_requests_expires_at" ON "oidc_login_requests" ("expires_at");
+-- Create "user_identities" table
+CREATE TABLE "user_identities" (
+  "id" bigserial NOT NULL,
+  "created_at" timestamptz NULL,
+  "updated_at" timestamptz NULL,
+  "user_id" bigint NOT NULL,
+  "provider" text NOT NULL,
+  "subject" text NOT NULL,
+  "email" text NOT NULL,
+  PRIMARY KEY ("id"),
+  CONSTRAINT "fk_users_identities" FOREIGN KEY ("user_id") REFERENCES "users" ("id") ON UPDATE NO ACTION ON DELETE CASCADE
+);
+-- Create index "idx_user_identities_provider_subject" to table: "user_identities"
+CREATE UNIQUE INDEX "idx_user_identities_provider_subject" ON "user_identities" ("provider", "subject");
+-- Create index "idx_user_identities_user_id" to table: "user_identities"
+CREATE INDEX "idx_user_identities_user_id" ON "user_identities" ("user_id");
@@ -0,0 +1,28 @@
+-- Create "role_permissions" table
+CREATE TABLE "role_permissions" (
+  "id" bigserial NOT NULL,
+  "created_at" timestamptz NULL,
+  "updated_at" timestamptz NULL,
+  "role_id" bigint NOT NULL,
+  "permission" text NOT NULL,
+  PRIMARY KEY ("id"),
+  CONSTRAINT "fk_roles_permissions" FOREIGN KEY ("role_id") REFERENCES "roles" ("id") ON UPDATE NO ACTION ON DELETE CASCADE
+);
+-- Create index "idx_role_permissions_role_id_permission" to table: "role_permissions"
+CREATE UNIQUE INDEX "idx_role_permissions_role_id_permission" ON "role_permissions" ("role_id", "permission");
+-- Grant default permissions to built-in roles
+INSERT INTO "role_permissions" ("created_at", "updated_at", "role_id", "permission")
+SELECT NOW(), NOW(), "roles"."id", "defaults"."permission"
+FROM "roles"
+JOIN (VALUES
+  ('admin', 'events:write'),
+  ('admin', 'questions:write'),
+  ('admin', 'questions:grade'),
+  ('admin', 'stats:read'),
+  ('admin', 'users:manage'),
+  ('admin', 'roles:manage'),
+  ('organizer', 'events:write'),
+  ('organizer', 'questions:write'),
+  ('organizer', 'questions:grade'),
+  ('organizer', 'stats:read')
+) AS "defaults" ("role_name", "permission") ON "defaults"."role_name" = "roles"."name";
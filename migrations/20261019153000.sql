@@ -0,0 +1,20 @@
+-- Modify "users" table
+ALTER TABLE "users" ADD COLUMN "suspended_at" timestamptz NULL, ADD COLUMN "suspended_until" timestamptz NULL;
+-- Create "moderation_actions" table
+CREATE TABLE "moderation_actions" (
+  "id" bigserial NOT NULL,
+  "created_at" timestamptz NULL,
+  "updated_at" timestamptz NULL,
+  "actor_id" bigint NULL,
+  "target_user_id" bigint NOT NULL,
+  "action" text NOT NULL,
+  "reason" text NOT NULL,
+  "details" text NOT NULL DEFAULT '',
+  PRIMARY KEY ("id"),
+  CONSTRAINT "fk_moderation_actions_actor" FOREIGN KEY ("actor_id") REFERENCES "users" ("id") ON UPDATE NO ACTION ON DELETE SET NULL,
+  CONSTRAINT "fk_moderation_actions_target_user" FOREIGN KEY ("target_user_id") REFERENCES "users" ("id") ON UPDATE NO ACTION ON DELETE CASCADE
+);
+-- Create index "idx_moderation_actions_actor_id" to table: "moderation_actions"
+CREATE INDEX "idx_moderation_actions_actor_id" ON "moderation_actions" ("actor_id");
+-- Create index "idx_moderation_actions_target_user_id" to table: "moderation_actions"
+CREATE INDEX "idx_moderation_actions_target_user_id" ON "moderation_actions" ("target_user_id");
@@ -0,0 +1,24 @@
+-- Create "personal_access_tokens" table
+CREATE TABLE "personal_access_tokens" (
+  "id" bigserial NOT NULL,
+  "created_at" timestamptz NULL,
+  "updated_at" timestamptz NULL,
+  "user_id" bigint NOT NULL,
+  "name" text NOT NULL,
+  "token_hash" text NOT NULL,
+  "hint" text NOT NULL,
+  "scopes" jsonb NOT NULL,
+  "expires_at" timestamptz NOT NULL,
+  "last_used_at" timestamptz NULL,
+  "last_used_ip" text NOT NULL DEFAULT '',
+  "revoked_at" timestamptz NULL,
+  PRIMARY KEY ("id"),
+  CONSTRAINT "uni_personal_access_tokens_token_hash" UNIQUE ("token_hash"),
+  CONSTRAINT "fk_users_personal_access_tokens" FOREIGN KEY ("user_id") REFERENCES "users" ("id") ON UPDATE NO ACTION ON DELETE CASCADE
+);
+-- Create index "idx_personal_access_tokens_expires_at" to table: "personal_access_tokens"
+CREATE INDEX "idx_personal_access_tokens_expires_at" ON "personal_access_tokens" ("expires_at");
+-- Create index "idx_personal_access_tokens_revoked_at" to table: "personal_access_tokens"
+CREATE INDEX "idx_personal_access_tokens_revoked_at" ON "personal_access_tokens" ("revoked_at");
+-- Create index "idx_personal_access_tokens_user_id" to table: "personal_access_tokens"
+CREATE INDEX "idx_personal_access_tokens_user_id" ON "personal_access_tokens" ("user_id");
@@ -0,0 +1,30 @@
+-- Create "user_profiles" table
+CREATE TABLE "user_profiles" (
+  "id" bigserial NOT NULL,
+  "created_at" timestamptz NULL,
+  "updated_at" timestamptz NULL,
+  "user_id" bigint NOT NULL,
+  "display_name" text NOT NULL DEFAULT '',
+  "country" text NULL,
+  "bio" text NOT NULL DEFAULT '',
+  "avatar_key" text NULL,
+  PRIMARY KEY ("id"),
+  CONSTRAINT "uni_user_profiles_user_id" UNIQUE ("user_id"),
+  CONSTRAINT "fk_users_profile" FOREIGN KEY ("user_id") REFERENCES "users" ("id") ON UPDATE NO ACTION ON DELETE CASCADE
+);
+-- Create "user_profile_athletes" table
+CREATE TABLE "user_profile_athletes" (
+  "user_profile_id" bigint NOT NULL,
+  "athlete_id" bigint NOT NULL,
+  PRIMARY KEY ("user_profile_id", "athlete_id"),
+  CONSTRAINT "fk_user_profile_athletes_athlete" FOREIGN KEY ("athlete_id") REFERENCES "athletes" ("id") ON UPDATE NO ACTION ON DELETE CASCADE,
+  CONSTRAINT "fk_user_profile_athletes_user_profile" FOREIGN KEY ("user_profile_id") REFERENCES "user_profiles" ("id") ON UPDATE NO ACTION ON DELETE CASCADE
+);
+-- Create "user_profile_disciplines" table
+CREATE TABLE "user_profile_disciplines" (
+  "user_profile_id" bigint NOT NULL,
+  "discipline_id" bigint NOT NULL,
+  PRIMARY KEY ("user_profile_id", "discipline_id"),
+  CONSTRAINT "fk_user_profile_disciplines_discipline" FOREIGN KEY ("discipline_id") REFERENCES "disciplines" ("id") ON UPDATE NO ACTION ON DELETE CASCADE,
+  CONSTRAINT "fk_user_profile_disciplines_user_profile" FOREIGN KEY ("user_profile_id") REFERENCES "user_profiles" ("id") ON UPDATE NO ACTION ON DELETE CASCADE
+);
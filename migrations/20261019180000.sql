@@ -0,0 +1,19 @@
+-- Modify "users" table
+ALTER TABLE "users" ADD COLUMN "anonymized_at" timestamptz NULL;
+-- Create "data_exports" table
+CREATE TABLE "data_exports" (
+  "id" bigserial NOT NULL,
+  "created_at" timestamptz NULL,
+  "updated_at" timestamptz NULL,
+  "user_id" bigint NOT NULL,
+  "status" text NOT NULL DEFAULT 'pending',
+  "file_key" text NULL,
+  "completed_at" timestamptz NULL,
+  "expires_at" timestamptz NULL,
+  PRIMARY KEY ("id"),
+  CONSTRAINT "fk_data_exports_user" FOREIGN KEY ("user_id") REFERENCES "users" ("id") ON UPDATE NO ACTION ON DELETE CASCADE
+);
+-- Create index "idx_data_exports_expires_at" to table: "data_exports"
+CREATE INDEX "idx_data_exports_expires_at" ON "data_exports" ("expires_at");
+-- Create index "idx_data_exports_user_id" to table: "data_exports"
+CREATE INDEX "idx_data_exports_user_id" ON "data_exports" ("user_id");
@@ -0,0 +1,8 @@
+-- Modify "events" table
+ALTER TABLE "events" ADD COLUMN "deleted_at" timestamptz NULL;
+-- Create index "idx_events_deleted_at" to table: "events"
+CREATE INDEX "idx_events_deleted_at" ON "events" ("deleted_at");
+-- Modify "questions" table
+ALTER TABLE "questions" ADD COLUMN "deleted_at" timestamptz NULL;
+-- Create index "idx_questions_deleted_at" to table: "questions"
+CREATE INDEX "idx_questions_deleted_at" ON "questions" ("deleted_at");
@@ -0,0 +1,39 @@
+-- Create "audit_logs" table
+CREATE TABLE "audit_logs" (
+  "id" bigserial NOT NULL,
+  "created_at" timestamptz NULL,
+  "updated_at" timestamptz NULL,
+  "actor_id" bigint NULL,
+  "session_id" text NULL,
+  "personal_access_token_id" bigint NULL,
+  "ip_address" text NOT NULL DEFAULT '',
+  "action" text NOT NULL,
+  "resource_type" text NOT NULL,
+  "resource_id" bigint NOT NULL,
+  "changes" jsonb NOT NULL DEFAULT '{}',
+  PRIMARY KEY ("id")
+);
+-- Create index "idx_audit_logs_action" to table: "audit_logs"
+CREATE INDEX "idx_audit_logs_action" ON "audit_logs" ("action");
+-- Create index "idx_audit_logs_actor_id" to table: "audit_logs"
+CREATE INDEX "idx_audit_logs_actor_id" ON "audit_logs" ("actor_id");
+-- Create index "idx_audit_logs_resource" to table: "audit_logs"
+CREATE INDEX "idx_audit_logs_resource" ON "audit_logs" ("resource_type", "resource_id");
+-- Make "audit_logs" table append-only
+CREATE OR REPLACE FUNCTION audit_logs_append_only()
+  RETURNS TRIGGER
+  AS $$
+BEGIN
+  RAISE EXCEPTION 'audit_logs is append-only';
+END;
+$$
+LANGUAGE plpgsql;
+CREATE TRIGGER audit_logs_append_only
+  BEFORE UPDATE OR DELETE ON "audit_logs"
+  FOR EACH ROW
+  EXECUTE PROCEDURE audit_logs_append_only();
+-- Grant "audit:read" permission to admin role
+INSERT INTO "role_permissions" ("created_at", "updated_at", "role_id", "permission")
+SELECT NOW(), NOW(), "roles"."id", 'audit:read'
+FROM "roles"
+WHERE "roles"."name" = 'admin';
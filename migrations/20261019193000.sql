@@ -0,0 +1,3 @@
+-- Refresh tokens issued before selectors were introduced could be found only by comparing bcrypt hashes one by one,
+-- they are revoked so the users of these sessions log in again
+UPDATE "refresh_tokens" SET "revoked_at" = NOW() WHERE "selector" IS NULL AND "revoked_at" IS NULL;
@@ -0,0 +1,32 @@
+-- Create "password_reset_rate_limits" table
+CREATE TABLE "password_reset_rate_limits" (
+  "id" bigserial NOT NULL,
+  "created_at" timestamptz NULL,
+  "updated_at" timestamptz NULL,
+  "email" text NOT NULL,
+  "request_count" bigint NOT NULL DEFAULT 0,
+  "last_request_at" timestamptz NULL,
+  "blocked_until" timestamptz NULL,
+  PRIMARY KEY ("id"),
+  CONSTRAINT "uni_password_reset_rate_limits_email" UNIQUE ("email")
+);
+-- Create index "idx_password_reset_rate_limits_blocked_until" to table: "password_reset_rate_limits"
+CREATE INDEX "idx_password_reset_rate_limits_blocked_until" ON "password_reset_rate_limits" ("blocked_until");
+-- Create index "idx_password_reset_rate_limits_email" to table: "password_reset_rate_limits"
+CREATE INDEX "idx_password_reset_rate_limits_email" ON "password_reset_rate_limits" ("email");
+-- Create "password_reset_tokens" table
+CREATE TABLE "password_reset_tokens" (
+  "id" bigserial NOT NULL,
+  "created_at" timestamptz NULL,
+  "updated_at" timestamptz NULL,
+  "user_id" bigint NOT NULL,
+  "token_hash" text NOT NULL,
+  "expires_at" timestamptz NOT NULL,
+  "used_at" timestamptz NULL,
+  PRIMARY KEY ("id"),
+  CONSTRAINT "uni_password_reset_tokens_token_hash" UNIQUE ("token_hash")
+);
+-- Create index "idx_password_reset_tokens_expires_at" to table: "password_reset_tokens"
+CREATE INDEX "idx_password_reset_tokens_expires_at" ON "password_reset_tokens" ("expires_at");
+-- Create index "idx_password_reset_tokens_user_id" to table: "password_reset_tokens"
+CREATE INDEX "idx_password_reset_tokens_user_id" ON "password_reset_tokens" ("user_id");
@@ -0,0 +1,2 @@
+-- Modify "refresh_tokens" table
+ALTER TABLE "refresh_tokens" ADD COLUMN "session_started_at" timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP, ADD COLUMN "last_used_at" timestamptz NULL, ADD COLUMN "user_agent" text NOT NULL DEFAULT '', ADD COLUMN "ip_address" text NOT NULL DEFAULT '';
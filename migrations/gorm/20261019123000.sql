@@ -0,0 +1,2 @@
+-- Modify "refresh_tokens" table
+ALTER TABLE "refresh_tokens" ADD COLUMN "selector" text NULL, ADD CONSTRAINT "uni_refresh_tokens_selector" UNIQUE ("selector");
@@ -0,0 +1,2 @@
+-- Modify "refresh_tokens" table
+ALTER TABLE "refresh_tokens" DROP CONSTRAINT "uni_refresh_tokens_session_id", ADD COLUMN "used_at" timestamptz NULL;
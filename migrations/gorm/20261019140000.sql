@@ -0,0 +1,45 @@
+-- Modify "roles" table
+ALTER TABLE "roles" ADD COLUMN "requires_two_factor" boolean NOT NULL DEFAULT false;
+-- Create "login_challenges" table
+CREATE TABLE "login_challenges" (
+  "id" bigserial NOT NULL,
+  "created_at" timestamptz NULL,
+  "updated_at" timestamptz NULL,
+  "user_id" bigint NOT NULL,
+  "token_hash" text NOT NULL,
+  "expires_at" timestamptz NOT NULL,
+  "failed_attempts" bigint NOT NULL DEFAULT 0,
+  PRIMARY KEY ("id"),
+  CONSTRAINT "uni_login_challenges_token_hash" UNIQUE ("token_hash")
+);
+-- Create index "idx_login_challenges_expires_at" to table: "login_challenges"
+CREATE INDEX "idx_login_challenges_expires_at" ON "login_challenges" ("expires_at");
+-- Create index "idx_login_challenges_user_id" to table: "login_challenges"
+CREATE INDEX "idx_login_challenges_user_id" ON "login_challenges" ("user_id");
+-- Create "two_factor_recovery_codes" table
+CREATE TABLE "two_factor_recovery_codes" (
+  "id" bigserial NOT NULL,
+  "created_at" timestamptz NULL,
+  "updated_at" timestamptz NULL,
+  "user_id" bigint NOT NULL,
+  "code_hash" text NOT NULL,
+  "used_at" timestamptz NULL,
+  PRIMARY KEY ("id"),
+  CONSTRAINT "uni_two_factor_recovery_codes_code_hash" UNIQUE ("code_hash"),
+  CONSTRAINT "fk_users_recovery_codes" FOREIGN KEY ("user_id") REFERENCES "users" ("id") ON UPDATE NO ACTION ON DELETE CASCADE
+);
+-- Create index "idx_two_factor_recovery_codes_user_id" to table: "two_factor_recovery_codes"
+CREATE INDEX "idx_two_factor_recovery_codes_user_id" ON "two_factor_recovery_codes" ("user_id");
+-- Create "user_two_factors" table
+CREATE TABLE "user_two_factors" (
+  "id" bigserial NOT NULL,
+  "created_at" timestamptz NULL,
+  "updated_at" timestamptz NULL,
+  "user_id" bigint NOT NULL,
+  "secret" text NOT NULL,
+  "confirmed_at" timestamptz NULL,
+  "last_used_step" bigint NOT NULL DEFAULT 0,
+  PRIMARY KEY ("id"),
+  CONSTRAINT "uni_user_two_factors_user_id" UNIQUE ("user_id"),
+  CONSTRAINT "fk_users_two_factor" FOREIGN KEY ("user_id") REFERENCES "users" ("id") ON UPDATE NO ACTION ON DELETE CASCADE
+);
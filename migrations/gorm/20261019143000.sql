@@ -0,0 +1,16 @@
+-- Create "login_attempt_limits" table
+CREATE TABLE "login_attempt_limits" (
+  "id" bigserial NOT NULL,
+  "created_at" timestamptz NULL,
+  "updated_at" timestamptz NULL,
+  "scope" text NOT NULL,
+  "identifier" text NOT NULL,
+  "failed_count" bigint NOT NULL DEFAULT 0,
+  "last_failed_at" timestamptz NULL,
+  "locked_until" timestamptz NULL,
+  PRIMARY KEY ("id")
+);
+-- Create index "idx_login_attempt_limits_locked_until" to table: "login_attempt_limits"
+CREATE INDEX "idx_login_attempt_limits_locked_until" ON "login_attempt_limits" ("locked_until");
+-- Create index "idx_login_attempt_limits_scope_identifier" to table: "login_attempt_limits"
+CREATE UNIQUE INDEX "idx_login_attempt_limits_scope_identifier" ON "login_attempt_limits" ("scope", "identifier");
@@ -0,0 +1,10 @@
+-- Rename duplicated (case-insensitively) and default usernames, the oldest account keeps its username
+UPDATE "users" SET "username" = 'user_' || "id" WHERE "username" = 'no_name' OR "id" IN (
+  SELECT "id" FROM (
+    SELECT "id", ROW_NUMBER() OVER (PARTITION BY LOWER("username") ORDER BY "id") AS "position" FROM "users"
+  ) AS "ranked_users" WHERE "position" > 1
+);
+-- Modify "users" table
+ALTER TABLE "users" ALTER COLUMN "username" DROP DEFAULT;
+-- Create index "idx_users_username_lower" to table: "users"
+CREATE UNIQUE INDEX "idx_users_username_lower" ON "users" ((lower((username)::text)));
@@ -2,19 +2,27 @@ package config
 
 import (
 	"github.com/filipio/athletics-backend/internal/email"
+	"github.com/filipio/athletics-backend/internal/oidc"
+	"github.com/filipio/athletics-backend/internal/storage"
 	"gorm.io/gorm"
 )
 
 type Dependencies struct {
-	DB          *gorm.DB
-	Workers     *InsertWorkerClient
-	EmailSender email.EmailSender
+	DB            *gorm.DB
+	Workers       *InsertWorkerClient
+	EmailSender   email.EmailSender
+	JwtKeys       *JwtKeySet
+	OidcProviders map[string]*oidc.Provider
+	Storage       storage.FileStorage
 }
 
-func NewDependencies(db *gorm.DB, workers *InsertWorkerClient, emailSender email.EmailSender) *Dependencies {
+func NewDependencies(db *gorm.DB, workers *InsertWorkerClient, emailSender email.EmailSender, jwtKeys *JwtKeySet, oidcProviders map[string]*oidc.Provider, fileStorage storage.FileStorage) *Dependencies {
 	return &Dependencies{
-		DB:          db,
-		Workers:     workers,
-		EmailSender: emailSender,
+		DB:            db,
+		Workers:       workers,
+		EmailSender:   emailSender,
+		JwtKeys:       jwtKeys,
+		OidcProviders: oidcProviders,
+		Storage:       fileStorage,
 	}
 }
@@ -0,0 +1,252 @@
+package config
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const defaultJwtIssuer = "athletics-backend"
+const defaultJwtAudience = "athletics-api"
+
+// key used for signing (if private part is present) and verification of access tokens
+type JwtKey struct {
+	ID         string
+	Method     jwt.SigningMethod
+	PublicKey  crypto.PublicKey
+	privateKey crypto.Signer
+}
+
+// JwtKeySet holds all keys accepted for verification, only one of them is used for signing.
+// Keys are rotated by adding new key, switching signing key to it and removing the old key once tokens signed with it expire.
+type JwtKeySet struct {
+	Issuer     string
+	Audience   string
+	signingKey *JwtKey
+	keys       map[string]*JwtKey
+}
+
+type Jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+type JwkSet struct {
+	Keys []Jwk `json:"keys"`
+}
+
+var jwtKeySet *JwtKeySet
+var jwtKeySetOnce sync.Once
+
+// JwtKeys loads keys from JWT_KEYS_DIR (one <kid>.pem file per key), JWT_SIGNING_KEY_ID selects the signing key.
+// Outside of prod an ephemeral key is generated when no directory is configured.
+func JwtKeys() *JwtKeySet {
+	jwtKeySetOnce.Do(func() {
+		keysDir := os.Getenv("JWT_KEYS_DIR")
+
+		var keySet *JwtKeySet
+		var err error
+		if keysDir == "" && os.Getenv("APP_ENV") != "prod" {
+			slog.Warn("JWT_KEYS_DIR is not set, using ephemeral jwt signing key")
+			keySet, err = NewEphemeralJwtKeySet()
+		} else {
+			keySet, err = LoadJwtKeySet(keysDir, os.Getenv("JWT_SIGNING_KEY_ID"))
+		}
+
+		if err != nil {
+			slog.Error("failed to load jwt keys", "error", err)
+			os.Exit(1)
+		}
+
+		if issuer := os.Getenv("JWT_ISSUER"); issuer != "" {
+			keySet.Issuer = issuer
+		}
+		if audience := os.Getenv("JWT_AUDIENCE"); audience != "" {
+			keySet.Audience = audience
+		}
+
+		jwtKeySet = keySet
+	})
+
+	return jwtKeySet
+}
+
+func LoadJwtKeySet(keysDir string, signingKeyID string) (*JwtKeySet, error) {
+	entries, err := os.ReadDir(keysDir)
+	if err != nil {
+		return nil, fmt.Errorf("read jwt keys dir: %w", err)
+	}
+
+	keySet := newJwtKeySet()
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".pem" {
+			continue
+		}
+
+		pemBytes, err := os.ReadFile(filepath.Join(keysDir, entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("read jwt key %s: %w", entry.Name(), err)
+		}
+
+		key, err := ParseJwtKey(strings.TrimSuffix(entry.Name(), ".pem"), pemBytes)
+		if err != nil {
+			return nil, err
+		}
+
+		keySet.keys[key.ID] = key
+	}
+
+	signingKey, ok := keySet.keys[signingKeyID]
+	if !ok {
+		return nil, fmt.Errorf("signing key '%s' not found in %s", signingKeyID, keysDir)
+	}
+	if signingKey.privateKey == nil {
+		return nil, fmt.Errorf("signing key '%s' has no private key", signingKeyID)
+	}
+	keySet.signingKey = signingKey
+
+	return keySet, nil
+}
+
+func NewEphemeralJwtKeySet() (*JwtKeySet, error) {
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+
+	key := &JwtKey{
+		ID:         "ephemeral-" + rand.Text()[:8],
+		Method:     jwt.SigningMethodEdDSA,
+		PublicKey:  publicKey,
+		privateKey: privateKey,
+	}
+
+	keySet := newJwtKeySet()
+	keySet.keys[key.ID] = key
+	keySet.signingKey = key
+
+	return keySet, nil
+}
+
+// ParseJwtKey parses PEM encoded RSA or Ed25519 key. Public keys can be used only for verification.
+func ParseJwtKey(keyID string, pemBytes []byte) (*JwtKey, error) {
+	block, _ := pem.Decode(pemBytes)
+	if block == nil {
+		return nil, fmt.Errorf("jwt key %s: no PEM data found", keyID)
+	}
+
+	var parsedKey any
+	var err error
+	switch block.Type {
+	case "PRIVATE KEY":
+		parsedKey, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		parsedKey, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		parsedKey, err = x509.ParsePKIXPublicKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("jwt key %s: unsupported PEM block type %s", keyID, block.Type)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("jwt key %s: %w", keyID, err)
+	}
+
+	key := &JwtKey{ID: keyID}
+	switch typedKey := parsedKey.(type) {
+	case *rsa.PrivateKey:
+		key.Method, key.PublicKey, key.privateKey = jwt.SigningMethodRS256, &typedKey.PublicKey, typedKey
+	case *rsa.PublicKey:
+		key.Method, key.PublicKey = jwt.SigningMethodRS256, typedKey
+	case ed25519.PrivateKey:
+		key.Method, key.PublicKey, key.privateKey = jwt.SigningMethodEdDSA, typedKey.Public(), typedKey
+	case ed25519.PublicKey:
+		key.Method, key.PublicKey = jwt.SigningMethodEdDSA, typedKey
+	default:
+		return nil, fmt.Errorf("jwt key %s: only RSA and Ed25519 keys are supported", keyID)
+	}
+
+	return key, nil
+}
+
+// Sign signs claims with the signing key, 'kid' header identifies the key for verification
+func (ks *JwtKeySet) Sign(claims jwt.MapClaims) (string, error) {
+	token := jwt.NewWithClaims(ks.signingKey.Method, claims)
+	token.Header["kid"] = ks.signingKey.ID
+
+	return token.SignedString(ks.signingKey.privateKey)
+}
+
+// Parse verifies signature of the token with the key pointed by 'kid' header, and validates exp, iat, iss and aud claims
+func (ks *JwtKeySet) Parse(tokenString string) (*jwt.Token, error) {
+	return jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		keyID, _ := token.Header["kid"].(string)
+		key, ok := ks.keys[keyID]
+		if !ok {
+			return nil, errors.New("unknown jwt signing key")
+		}
+
+		if token.Method.Alg() != key.Method.Alg() {
+			return nil, errors.New("unexpected jwt signing method")
+		}
+
+		return key.PublicKey, nil
+	},
+		jwt.WithValidMethods([]string{jwt.SigningMethodRS256.Alg(), jwt.SigningMethodEdDSA.Alg()}),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+		jwt.WithIssuer(ks.Issuer),
+		jwt.WithAudience(ks.Audience),
+	)
+}
+
+// JWKS returns public parts of all verification keys in JSON Web Key Set format
+func (ks *JwtKeySet) JWKS() JwkSet {
+	jwkSet := JwkSet{Keys: make([]Jwk, 0, len(ks.keys))}
+
+	for _, key := range ks.keys {
+		jwk := Jwk{Kid: key.ID, Use: "sig", Alg: key.Method.Alg()}
+
+		switch publicKey := key.PublicKey.(type) {
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(publicKey.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(publicKey.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.Kty = "OKP"
+			jwk.Crv = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(publicKey)
+		}
+
+		jwkSet.Keys = append(jwkSet.Keys, jwk)
+	}
+
+	return jwkSet
+}
+
+func newJwtKeySet() *JwtKeySet {
+	return &JwtKeySet{
+		Issuer:   defaultJwtIssuer,
+		Audience: defaultJwtAudience,
+		keys:     map[string]*JwtKey{},
+	}
+}
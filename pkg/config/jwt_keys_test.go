@@ -0,0 +1,148 @@
+package config
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestJwtKeySetRotation(t *testing.T) {
+	keysDir := t.TempDir()
+	writePrivateKeyPem(t, keysDir, "rsa-2026-01", mustGenerateRsaKey(t))
+	_, edKey, _ := ed25519.GenerateKey(rand.Reader)
+	writePrivateKeyPem(t, keysDir, "ed-2026-10", edKey)
+
+	oldKeySet, err := LoadJwtKeySet(keysDir, "rsa-2026-01")
+	if err != nil {
+		t.Fatalf("failed to load key set: %s", err)
+	}
+	newKeySet, err := LoadJwtKeySet(keysDir, "ed-2026-10")
+	if err != nil {
+		t.Fatalf("failed to load key set: %s", err)
+	}
+
+	oldToken, err := oldKeySet.Sign(validClaims(oldKeySet))
+	if err != nil {
+		t.Fatalf("failed to sign token: %s", err)
+	}
+
+	parsedToken, err := newKeySet.Parse(oldToken)
+	if err != nil {
+		t.Fatalf("expected token signed with previous key to be valid, got %s", err)
+	}
+	if parsedToken.Header["kid"] != "rsa-2026-01" || parsedToken.Method.Alg() != "RS256" {
+		t.Errorf("unexpected token header %v", parsedToken.Header)
+	}
+
+	newToken, _ := newKeySet.Sign(validClaims(newKeySet))
+	parsedToken, err = newKeySet.Parse(newToken)
+	if err != nil {
+		t.Fatalf("expected token to be valid, got %s", err)
+	}
+	if parsedToken.Header["kid"] != "ed-2026-10" || parsedToken.Method.Alg() != "EdDSA" {
+		t.Errorf("unexpected token header %v", parsedToken.Header)
+	}
+
+	if len(newKeySet.JWKS().Keys) != 2 {
+		t.Errorf("expected 2 keys in jwks, got %d", len(newKeySet.JWKS().Keys))
+	}
+}
+
+func TestJwtKeySetParseValidation(t *testing.T) {
+	keySet, err := NewEphemeralJwtKeySet()
+	if err != nil {
+		t.Fatalf("failed to generate key set: %s", err)
+	}
+	otherKeySet, _ := NewEphemeralJwtKeySet()
+
+	testData := []struct {
+		name       string
+		keySet     *JwtKeySet
+		claims     func(claims jwt.MapClaims)
+		shouldFail bool
+	}{
+		{name: "valid", keySet: keySet, claims: func(claims jwt.MapClaims) {}},
+		{name: "expired", keySet: keySet, claims: func(claims jwt.MapClaims) { claims["exp"] = time.Now().Add(-time.Minute).Unix() }, shouldFail: true},
+		{name: "issued in future", keySet: keySet, claims: func(claims jwt.MapClaims) { claims["iat"] = time.Now().Add(time.Hour).Unix() }, shouldFail: true},
+		{name: "wrong issuer", keySet: keySet, claims: func(claims jwt.MapClaims) { claims["iss"] = "someone-else" }, shouldFail: true},
+		{name: "wrong audience", keySet: keySet, claims: func(claims jwt.MapClaims) { claims["aud"] = "other-api" }, shouldFail: true},
+		{name: "unknown key", keySet: otherKeySet, claims: func(claims jwt.MapClaims) {}, shouldFail: true},
+	}
+
+	for _, data := range testData {
+		t.Run(data.name, func(t *testing.T) {
+			claims := validClaims(keySet)
+			data.claims(claims)
+
+			tokenString, err := data.keySet.Sign(claims)
+			if err != nil {
+				t.Fatalf("failed to sign token: %s", err)
+			}
+
+			_, err = keySet.Parse(tokenString)
+			if data.shouldFail && err == nil {
+				t.Error("expected parsing to fail")
+			}
+			if !data.shouldFail && err != nil {
+				t.Errorf("expected parsing to succeed, got %s", err)
+			}
+		})
+	}
+
+	hmacToken, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, validClaims(keySet)).SignedString([]byte("jwt_secret"))
+	if _, err := keySet.Parse(hmacToken); err == nil {
+		t.Error("expected HS256 token to be rejected")
+	}
+}
+
+func TestLoadJwtKeySetRequiresPrivateSigningKey(t *testing.T) {
+	keysDir := t.TempDir()
+	rsaKey := mustGenerateRsaKey(t)
+	publicKeyBytes, _ := x509.MarshalPKIXPublicKey(&rsaKey.PublicKey)
+	os.WriteFile(filepath.Join(keysDir, "public.pem"), pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicKeyBytes}), 0600)
+
+	if _, err := LoadJwtKeySet(keysDir, "public"); err == nil {
+		t.Error("expected error for signing key without private part")
+	}
+	if _, err := LoadJwtKeySet(keysDir, "missing"); err == nil {
+		t.Error("expected error for missing signing key")
+	}
+}
+
+func validClaims(keySet *JwtKeySet) jwt.MapClaims {
+	return jwt.MapClaims{
+		"sub": 1,
+		"iss": keySet.Issuer,
+		"aud": keySet.Audience,
+		"iat": time.Now().Unix(),
+		"exp": time.Now().Add(time.Hour).Unix(),
+	}
+}
+
+func mustGenerateRsaKey(t *testing.T) *rsa.PrivateKey {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("failed to generate rsa key: %s", err)
+	}
+	return key
+}
+
+func writePrivateKeyPem(t *testing.T, dir string, keyID string, key any) {
+	keyBytes, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatalf("failed to marshal key: %s", err)
+	}
+
+	pemBytes := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyBytes})
+	if err := os.WriteFile(filepath.Join(dir, keyID+".pem"), pemBytes, 0600); err != nil {
+		t.Fatalf("failed to write key: %s", err)
+	}
+}
@@ -39,7 +39,7 @@ func (c *WorkersClients) Shutdown(ctx context.Context) error {
 var workersClientsInstance *WorkersClients
 var workersOnce sync.Once
 
-func SetupWorkersClient(ctx context.Context, db *gorm.DB, appWorkers *river.Workers) *WorkersClients {
+func SetupWorkersClient(ctx context.Context, db *gorm.DB, appWorkers *river.Workers, periodicJobs []*river.PeriodicJob) *WorkersClients {
 
 	workersOnce.Do(func() {
 		dbExecutionPool, err := pgxpool.New(ctx, os.Getenv("DB_URL"))
@@ -51,7 +51,8 @@ func SetupWorkersClient(ctx context.Context, db *gorm.DB, appWorkers *river.Work
 			Queues: map[string]river.QueueConfig{
 				river.QueueDefault: {MaxWorkers: 100},
 			},
-			Workers: appWorkers,
+			Workers:      appWorkers,
+			PeriodicJobs: periodicJobs,
 		})
 		if err != nil {
 			slog.Error("error creating river execution client: ", "error", err)
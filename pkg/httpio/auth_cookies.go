@@ -0,0 +1,93 @@
+package httpio
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+const AccessTokenCookieName = "access_token"
+const RefreshTokenCookieName = "refresh_token"
+const CsrfTokenCookieName = "csrf_token"
+const CsrfTokenHeader = "X-CSRF-Token"
+
+// clients opt in to the cookie auth mode by sending "X-Auth-Mode: cookie" with login, refresh and email verification requests
+const AuthModeHeader = "X-Auth-Mode"
+const CookieAuthMode = "cookie"
+
+// refresh token cookie is sent only to refresh and logout endpoints
+const refreshTokenCookiePath = "/api/v1/auth"
+const accessTokenCookiePath = "/api"
+
+func CookieAuthRequested(r *http.Request) bool {
+	return strings.EqualFold(r.Header.Get(AuthModeHeader), CookieAuthMode)
+}
+
+// SetAuthCookies sets HttpOnly cookies with tokens and a cookie with CSRF token, which the frontend sends back in X-CSRF-Token header
+func SetAuthCookies(w http.ResponseWriter, accessToken string, refreshToken string, csrfToken string) {
+	http.SetCookie(w, authCookie(AccessTokenCookieName, accessToken, accessTokenCookiePath, AccessTokenExpiration, true))
+	http.SetCookie(w, authCookie(RefreshTokenCookieName, refreshToken, refreshTokenCookiePath, RefreshTokenExpiration, true))
+	http.SetCookie(w, authCookie(CsrfTokenCookieName, csrfToken, "/", RefreshTokenExpiration, false))
+}
+
+func ClearAuthCookies(w http.ResponseWriter) {
+	http.SetCookie(w, authCookie(AccessTokenCookieName, "", accessTokenCookiePath, -1, true))
+	http.SetCookie(w, authCookie(RefreshTokenCookieName, "", refreshTokenCookiePath, -1, true))
+	http.SetCookie(w, authCookie(CsrfTokenCookieName, "", "/", -1, false))
+}
+
+// VerifyCsrfToken implements double-submit check - X-CSRF-Token header must be equal to the CSRF cookie.
+// Safe methods don't change state, so they are not checked.
+func VerifyCsrfToken(r *http.Request) error {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return nil
+	}
+
+	cookie, err := r.Cookie(CsrfTokenCookieName)
+	if err != nil || cookie.Value == "" {
+		return CsrfTokenMismatchError{}
+	}
+
+	header := r.Header.Get(CsrfTokenHeader)
+	if subtle.ConstantTimeCompare([]byte(header), []byte(cookie.Value)) != 1 {
+		return CsrfTokenMismatchError{}
+	}
+
+	return nil
+}
+
+// cookie attributes can be configured with AUTH_COOKIE_DOMAIN, AUTH_COOKIE_SAME_SITE (lax, strict or none)
+// and AUTH_COOKIE_SECURE, which can be set to "false" only for local development over plain http
+func authCookie(name string, value string, path string, maxAge time.Duration, httpOnly bool) *http.Cookie {
+	cookie := &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     path,
+		Domain:   os.Getenv("AUTH_COOKIE_DOMAIN"),
+		HttpOnly: httpOnly,
+		Secure:   os.Getenv("AUTH_COOKIE_SECURE") != "false",
+		SameSite: cookieSameSite(),
+	}
+
+	if maxAge < 0 {
+		cookie.MaxAge = -1
+	} else {
+		cookie.MaxAge = int(maxAge.Seconds())
+	}
+
+	return cookie
+}
+
+func cookieSameSite() http.SameSite {
+	switch strings.ToLower(os.Getenv("AUTH_COOKIE_SAME_SITE")) {
+	case "strict":
+		return http.SameSiteStrictMode
+	case "none":
+		return http.SameSiteNoneMode
+	default:
+		return http.SameSiteLaxMode
+	}
+}
@@ -0,0 +1,40 @@
+package httpio
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestVerifyCsrfToken(t *testing.T) {
+	testData := []struct {
+		name        string
+		method      string
+		cookieValue string
+		headerValue string
+		expectError bool
+	}{
+		{name: "safe method without token", method: http.MethodGet, expectError: false},
+		{name: "matching token", method: http.MethodPost, cookieValue: "token", headerValue: "token", expectError: false},
+		{name: "missing header", method: http.MethodPost, cookieValue: "token", expectError: true},
+		{name: "missing cookie", method: http.MethodDelete, headerValue: "token", expectError: true},
+		{name: "different token", method: http.MethodPut, cookieValue: "token", headerValue: "other", expectError: true},
+	}
+
+	for _, td := range testData {
+		t.Run(td.name, func(t *testing.T) {
+			r := httptest.NewRequest(td.method, "/api/v1/events", nil)
+			if td.cookieValue != "" {
+				r.AddCookie(&http.Cookie{Name: CsrfTokenCookieName, Value: td.cookieValue})
+			}
+			if td.headerValue != "" {
+				r.Header.Set(CsrfTokenHeader, td.headerValue)
+			}
+
+			err := VerifyCsrfToken(r)
+			if (err != nil) != td.expectError {
+				t.Errorf("expected error: %v, got: %v", td.expectError, err)
+			}
+		})
+	}
+}
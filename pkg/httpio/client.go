@@ -0,0 +1,36 @@
+package httpio
+
+import (
+	"net"
+	"net/http"
+	"os"
+	"strings"
+)
+
+const maxUserAgentLength = 512
+
+// ClientIP returns IP address of the client. X-Forwarded-For header is taken into account only when
+// TRUST_PROXY_HEADERS is set to "true" (app is deployed behind reverse proxy), otherwise it could be spoofed.
+func ClientIP(r *http.Request) string {
+	if os.Getenv("TRUST_PROXY_HEADERS") == "true" {
+		if forwardedFor := r.Header.Get("X-Forwarded-For"); forwardedFor != "" {
+			return strings.TrimSpace(strings.Split(forwardedFor, ",")[0])
+		}
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+
+	return host
+}
+
+func ClientUserAgent(r *http.Request) string {
+	userAgent := r.UserAgent()
+	if len(userAgent) > maxUserAgentLength {
+		return userAgent[:maxUserAgentLength]
+	}
+
+	return userAgent
+}
@@ -6,6 +6,40 @@ const AdminRole = "admin"
 const UserRole = "user"
 const OrganizerRole = "organizer"
 
+// built-in roles can't be renamed or deleted
+var BuiltInRoles = []string{AdminRole, UserRole, OrganizerRole}
+
+const EventsWritePermission = "events:write"
+const QuestionsWritePermission = "questions:write"
+const QuestionsGradePermission = "questions:grade"
+const StatsReadPermission = "stats:read" // stats of any event, also before its deadline
+const UsersManagePermission = "users:manage"
+const RolesManagePermission = "roles:manage"
+const AuditReadPermission = "audit:read"
+
+// catalogue of permissions which can be bundled into roles
+var AllPermissions = []string{
+	EventsWritePermission,
+	QuestionsWritePermission,
+	QuestionsGradePermission,
+	StatsReadPermission,
+	UsersManagePermission,
+	RolesManagePermission,
+	AuditReadPermission,
+}
+
+// permissions granted to built-in roles when they are created
+var DefaultRolePermissions = map[string][]string{
+	AdminRole: AllPermissions,
+	UserRole:  {},
+	OrganizerRole: {
+		EventsWritePermission,
+		QuestionsWritePermission,
+		QuestionsGradePermission,
+		StatsReadPermission,
+	},
+}
+
 const OnlyCurrentUserPath = "/users/me"
 
 type ContextKey uint
@@ -13,6 +47,7 @@ type ContextKey uint
 const UserContextKey = ContextKey(0)
 const OnlyCurrentUserContextKey = ContextKey(1)
 const SessionIDContextKey = ContextKey(2)
+const PersonalAccessTokenIDContextKey = ContextKey(3)
 
 const DefaultPageSize = 20
 const DefaultPageNumber = 1
@@ -86,3 +86,72 @@ type RefreshTokenExpiredError struct {
 type SessionExpiredError struct {
 	AppError
 }
+
+type StatsNotAvailableError struct {
+	AppError
+}
+
+type RateLimitError struct {
+	AppError
+	BlockedUntil *string
+	// sent in Retry-After header when greater than zero
+	RetryAfterSeconds int
+}
+
+type InvalidPasswordResetTokenError struct {
+	AppError
+}
+
+type OidcProviderNotFoundError struct {
+	AppError
+}
+
+type InvalidOidcStateError struct {
+	AppError
+}
+
+type OidcLoginError struct {
+	AppError
+}
+
+type OidcEmailNotVerifiedError struct {
+	AppError
+}
+
+type TwoFactorRequiredError struct {
+	AppError
+}
+
+type TwoFactorAlreadyEnabledError struct {
+	AppError
+}
+
+type TwoFactorNotEnabledError struct {
+	AppError
+}
+
+type InvalidLoginChallengeError struct {
+	AppError
+}
+
+type UserSuspendedError struct {
+	AppError
+	// nil for banned users
+	SuspendedUntil *string
+}
+
+type InvalidPersonalAccessTokenError struct {
+	AppError
+}
+
+type CsrfTokenMismatchError struct {
+	AppError
+}
+
+type InvalidMagicLinkTokenError struct {
+	AppError
+}
+
+type PreconditionFailedError struct {
+	AppError
+}
@@ -0,0 +1,59 @@
+package httpio
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+// ResponseETag returns strong ETag of the encoded response, so it changes also when embedded associations change
+func ResponseETag(response any) (string, error) {
+	encoded, err := json.Marshal(response)
+	if err != nil {
+		return "", err
+	}
+
+	hash := sha256.Sum256(encoded)
+	return `"` + hex.EncodeToString(hash[:16]) + `"`, nil
+}
+
+// IfMatchFailed tells whether the update must be rejected, because the resource changed since the client fetched it.
+// Requests without If-Match header are never rejected
+func IfMatchFailed(r *http.Request, etag string) bool {
+	header := r.Header.Get("If-Match")
+	if header == "" {
+		return false
+	}
+
+	// If-Match uses strong comparison, so weak tags never match
+	return !matchesETag(header, etag, false)
+}
+
+// IfNoneMatchHit tells whether the client already has the current version of the resource
+func IfNoneMatchHit(r *http.Request, etag string) bool {
+	header := r.Header.Get("If-None-Match")
+	if header == "" {
+		return false
+	}
+
+	return matchesETag(header, etag, true)
+}
+
+func matchesETag(header string, etag string, weak bool) bool {
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" {
+			return true
+		}
+		if weak {
+			tag = strings.TrimPrefix(tag, "W/")
+		}
+		if tag == etag {
+			return true
+		}
+	}
+
+	return false
+}
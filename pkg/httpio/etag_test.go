@@ -0,0 +1,80 @@
+package httpio
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestIfMatchFailed(t *testing.T) {
+	etag := `"1-100"`
+	tests := []struct {
+		header string
+		failed bool
+	}{
+		{"", false},
+		{`"1-100"`, false},
+		{`"1-99", "1-100"`, false},
+		{"*", false},
+		{`"1-99"`, true},
+		{`W/"1-100"`, true},
+	}
+
+	for _, test := range tests {
+		r := httptest.NewRequest("PUT", "/", nil)
+		if test.header != "" {
+			r.Header.Set("If-Match", test.header)
+		}
+		if failed := IfMatchFailed(r, etag); failed != test.failed {
+			t.Errorf("If-Match %q: expected %v, got %v", test.header, test.failed, failed)
+		}
+	}
+}
+
+func TestIfNoneMatchHit(t *testing.T) {
+	etag := `"1-100"`
+	tests := []struct {
+		header string
+		hit    bool
+	}{
+		{"", false},
+		{`"1-100"`, true},
+		{`W/"1-100"`, true},
+		{"*", true},
+		{`"1-99"`, false},
+	}
+
+	for _, test := range tests {
+		r := httptest.NewRequest("GET", "/", nil)
+		if test.header != "" {
+			r.Header.Set("If-None-Match", test.header)
+		}
+		if hit := IfNoneMatchHit(r, etag); hit != test.hit {
+			t.Errorf("If-None-Match %q: expected %v, got %v", test.header, test.hit, hit)
+		}
+	}
+}
+
+func TestResponseETag(t *testing.T) {
+	type association struct {
+		Name string `json:"name"`
+	}
+	type response struct {
+		ID           uint          `json:"id"`
+		Associations []association `json:"associations"`
+	}
+
+	etag, _ := ResponseETag(response{ID: 1, Associations: []association{{Name: "first"}}})
+	sameETag, _ := ResponseETag(response{ID: 1, Associations: []association{{Name: "first"}}})
+	changedETag, _ := ResponseETag(response{ID: 1, Associations: []association{{Name: "second"}}})
+
+	if etag != sameETag {
+		t.Errorf("Expected the same ETag for the same response, got %s and %s", etag, sameETag)
+	}
+	if etag == changedETag {
+		t.Errorf("Expected different ETag when association changes")
+	}
+	if !strings.HasPrefix(etag, `"`) || !strings.HasSuffix(etag, `"`) {
+		t.Errorf("Expected quoted ETag, got %s", etag)
+	}
+}
@@ -3,6 +3,7 @@ package httpio
 import (
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
 	"strconv"
 
@@ -84,9 +85,13 @@ func Encode[T any](w http.ResponseWriter, r *http.Request, status int, v T) erro
 }
 
 func DecodeAndValidate[T Validatable](r *http.Request, db *gorm.DB) (T, error) {
+	return decodeAndValidate[T](r.Body, db)
+}
+
+func decodeAndValidate[T Validatable](body io.Reader, db *gorm.DB) (T, error) {
 	var record T
 
-	if err := json.NewDecoder(r.Body).Decode(&record); err != nil {
+	if err := json.NewDecoder(body).Decode(&record); err != nil {
 		if err, ok := err.(*json.UnmarshalTypeError); ok {
 			return record, AppValidationError{
 				FieldPath: err.Field,
@@ -21,8 +21,20 @@ type DbModel interface {
 	BeforeDeleteCtx(context.Context, *gorm.DB) error
 	AfterDeleteCtx(context.Context, *gorm.DB) error
 	GetAllQuery(*gorm.DB, *http.Request) *gorm.DB
+	ListFields() map[string]ListField
 	GetQuery(*gorm.DB, *http.Request) *gorm.DB
 	UpdateQuery(*gorm.DB, *http.Request) *gorm.DB
 	DeleteQuery(*gorm.DB, *http.Request) *gorm.DB
 	BuildResponse() any
 }
+
+// SoftDeletableModel is the model whose deleted rows are kept and can be restored
+type SoftDeletableModel interface {
+	DbModel
+	RestoreQuery(*gorm.DB, *http.Request) *gorm.DB
+}
+
+// AuditedModel is the model whose changes made with the generic handlers are recorded in the audit log
+type AuditedModel interface {
+	AuditResourceType() string
+}
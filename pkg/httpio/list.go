@@ -0,0 +1,154 @@
+package httpio
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	FilterEq   = "eq"
+	FilterIn   = "in"
+	FilterGte  = "gte"
+	FilterLt   = "lt"
+	FilterLike = "like"
+)
+
+// types of the filter values, values which can't be parsed to the type of the field are rejected
+const (
+	FieldTypeString = ""
+	FieldTypeInt    = "int"
+	FieldTypeFloat  = "float"
+	FieldTypeBool   = "bool"
+	FieldTypeTime   = "time"
+)
+
+// ListField declares how the field of the model can be used in the query parameters of its list
+type ListField struct {
+	// column used in the queries, the name of the field when empty
+	Column string
+	// type of the filter values, one of FieldType constants
+	Type string
+	// filter operators allowed for the field, the field can't be filtered without them
+	Operators []string
+	Sortable  bool
+	// Query applies the filter of the field which isn't a plain comparison of the column, e.g. a condition on associated records.
+	// Values are parsed to the type of the field (there are more of them only for in operator), like gets the ILIKE pattern
+	Query func(db *gorm.DB, operator string, values []any) *gorm.DB
+	// query parameters from before filter[field][operator] parameters, which are still accepted as aliases of the filter
+	LegacyParams map[string]LegacyParam
+}
+
+// LegacyParam maps the deprecated query parameter onto the filter of the field
+type LegacyParam struct {
+	Operator string
+	// Normalize converts the value of the parameter to the filter value, e.g. to the case of the stored values
+	Normalize func(value string) string
+}
+
+func (f ListField) ColumnName(name string) string {
+	if f.Column != "" {
+		return f.Column
+	}
+
+	return name
+}
+
+// ParseValue converts the filter value to the type of the field, times are accepted in RFC 3339 format or as dates
+func (f ListField) ParseValue(value string) (any, error) {
+	switch f.Type {
+	case FieldTypeInt:
+		parsed, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return nil, errors.New("must be an integer")
+		}
+		return parsed, nil
+	case FieldTypeFloat:
+		parsed, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return nil, errors.New("must be a number")
+		}
+		return parsed, nil
+	case FieldTypeBool:
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, errors.New("must be true or false")
+		}
+		return parsed, nil
+	case FieldTypeTime:
+		if parsed, err := time.Parse(time.RFC3339, value); err == nil {
+			return parsed, nil
+		}
+		parsed, err := time.Parse(time.DateOnly, value)
+		if err != nil {
+			return nil, errors.New("must be a date or a time in RFC 3339 format")
+		}
+		return parsed, nil
+	default:
+		return value, nil
+	}
+}
+
+// ResponseFields lists JSON names of the fields of the response struct, fields of embedded structs included
+func ResponseFields(response any) []string {
+	responseType := reflect.TypeOf(response)
+	if responseType == nil {
+		return nil
+	}
+	if responseType.Kind() == reflect.Pointer {
+		responseType = responseType.Elem()
+	}
+	if responseType.Kind() != reflect.Struct {
+		return nil
+	}
+
+	fields := []string{}
+	for _, field := range reflect.VisibleFields(responseType) {
+		if !field.IsExported() {
+			continue
+		}
+
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if field.Anonymous && name == "" {
+			continue
+		}
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		fields = append(fields, name)
+	}
+
+	return fields
+}
+
+// SelectFields reduces the response to the requested fields (sparse fieldset), id is always kept
+func SelectFields(response any, fields []string) (map[string]any, error) {
+	encoded, err := json.Marshal(response)
+	if err != nil {
+		return nil, err
+	}
+
+	var all map[string]any
+	if err := json.Unmarshal(encoded, &all); err != nil {
+		return nil, err
+	}
+
+	selected := map[string]any{}
+	for name, value := range all {
+		if name == "id" || slices.Contains(fields, name) {
+			selected[name] = value
+		}
+	}
+
+	return selected, nil
+}
@@ -0,0 +1,82 @@
+package httpio
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+type listTestBase struct {
+	ID        uint      `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type listTestResponse struct {
+	listTestBase
+	Name     string  `json:"name"`
+	Note     *string `json:"note,omitempty"`
+	Password string  `json:"-"`
+	internal string
+}
+
+func TestResponseFields(t *testing.T) {
+	expected := []string{"id", "created_at", "name", "note"}
+	if diff := cmp.Diff(expected, ResponseFields(listTestResponse{})); diff != "" {
+		t.Errorf("fields mismatch (-expected +got):\n%s", diff)
+	}
+
+	if fields := ResponseFields(map[string]any{"id": 1}); fields != nil {
+		t.Errorf("Expected no fields of map response, got %v", fields)
+	}
+}
+
+func TestSelectFields(t *testing.T) {
+	response := listTestResponse{listTestBase: listTestBase{ID: 7}, Name: "Final", Password: "secret"}
+
+	selected, err := SelectFields(response, []string{"name"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err.Error())
+	}
+
+	expected := map[string]any{"id": float64(7), "name": "Final"}
+	if diff := cmp.Diff(expected, selected); diff != "" {
+		t.Errorf("selected fields mismatch (-expected +got):\n%s", diff)
+	}
+}
+
+func TestListFieldParseValue(t *testing.T) {
+	testCases := []struct {
+		fieldType string
+		value     string
+		expected  any
+	}{
+		{FieldTypeString, "abc", "abc"},
+		{FieldTypeInt, "42", int64(42)},
+		{FieldTypeFloat, "1.5", 1.5},
+		{FieldTypeBool, "true", true},
+		{FieldTypeTime, "2026-10-19T12:00:00Z", time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)},
+		{FieldTypeTime, "2026-10-19", time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC)},
+	}
+
+	for _, testCase := range testCases {
+		parsed, err := ListField{Type: testCase.fieldType}.ParseValue(testCase.value)
+		if err != nil {
+			t.Errorf("Unexpected error for %q: %s", testCase.value, err.Error())
+		}
+		if diff := cmp.Diff(testCase.expected, parsed); diff != "" {
+			t.Errorf("parsed value mismatch (-expected +got):\n%s", diff)
+		}
+	}
+
+	for fieldType, value := range map[string]string{
+		FieldTypeInt:   "abc",
+		FieldTypeFloat: "1,5",
+		FieldTypeBool:  "yes",
+		FieldTypeTime:  "notadate",
+	} {
+		if _, err := (ListField{Type: fieldType}).ParseValue(value); err == nil {
+			t.Errorf("Expected error for %s value %q", fieldType, value)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package httpio
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"gorm.io/gorm"
+)
+
+// DecodeMergePatchAndValidate applies JSON merge patch (RFC 7396) from the request body to the record and validates the result as a whole.
+// Top level keys of the patch are returned, so the caller writes only the fields which were sent, also when they are zero or null
+func DecodeMergePatchAndValidate[T Validatable](r *http.Request, db *gorm.DB, record T) (T, []string, error) {
+	var patch any
+	if err := json.NewDecoder(r.Body).Decode(&patch); err != nil {
+		return record, nil, fmt.Errorf("decode json: %w", err)
+	}
+
+	patchFields, ok := patch.(map[string]any)
+	if !ok {
+		return record, nil, AppValidationError{
+			FieldPath: "body",
+			AppError:  AppError{Message: "must be a JSON object"},
+		}
+	}
+
+	document, err := json.Marshal(record)
+	if err != nil {
+		return record, nil, err
+	}
+
+	var target any
+	if err := json.Unmarshal(document, &target); err != nil {
+		return record, nil, err
+	}
+
+	merged, err := json.Marshal(MergePatch(target, patchFields))
+	if err != nil {
+		return record, nil, err
+	}
+
+	patched, err := decodeAndValidate[T](bytes.NewReader(merged), db)
+	if err != nil {
+		return patched, nil, err
+	}
+
+	keys := make([]string, 0, len(patchFields))
+	for key := range patchFields {
+		keys = append(keys, key)
+	}
+
+	return patched, keys, nil
+}
+
+// MergePatch returns the target with the patch applied, null values in the patch remove the members of the target
+func MergePatch(target any, patch any) any {
+	patchFields, ok := patch.(map[string]any)
+	if !ok {
+		return patch
+	}
+
+	targetFields, ok := target.(map[string]any)
+	if !ok {
+		targetFields = map[string]any{}
+	}
+
+	for key, value := range patchFields {
+		if value == nil {
+			delete(targetFields, key)
+		} else {
+			targetFields[key] = MergePatch(targetFields[key], value)
+		}
+	}
+
+	return targetFields
+}
@@ -2,6 +2,7 @@ package httpio
 
 import (
 	"fmt"
+	"slices"
 	"strings"
 
 	"github.com/go-playground/validator/v10"
@@ -11,12 +12,18 @@ import (
 
 var validate *validator.Validate = validator.New(validator.WithRequiredStructEnabled())
 
+// tables of the soft deleted models, their deleted rows can't be referenced with id_of
+var softDeleteTables = []string{"events", "questions"}
+
 func RegisterValidations(db *gorm.DB) {
 
 	validate.RegisterValidation("id_of", func(fl validator.FieldLevel) bool {
 		tableName := fl.Param() + "s"
 		passedId := fl.Field().Uint()
 		sqlQuery := fmt.Sprintf("SELECT id FROM %s WHERE id = ?", tableName)
+		if slices.Contains(softDeleteTables, tableName) {
+			sqlQuery += " AND deleted_at IS NULL"
+		}
 
 		result := db.Exec(sqlQuery, passedId)
 		if result.Error != nil {
@@ -0,0 +1,62 @@
+package imageutil
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/jpeg"
+	"io"
+
+	_ "image/gif"
+	_ "image/png"
+
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+)
+
+const AvatarSize = 256
+
+// images with more pixels are rejected before decoding, so a small file can't expand into huge bitmap
+const maxSourcePixels = 40_000_000
+
+var ErrUnsupportedImage = errors.New("unsupported or invalid image")
+
+// ResizeAvatar decodes JPEG, PNG, GIF or WebP image, crops it to the centered square and scales it to AvatarSize.
+// The result is always JPEG, which also drops any metadata (e.g. location) of the original image.
+func ResizeAvatar(source io.Reader) ([]byte, error) {
+	content, err := io.ReadAll(source)
+	if err != nil {
+		return nil, err
+	}
+
+	config, _, err := image.DecodeConfig(bytes.NewReader(content))
+	if err != nil {
+		return nil, ErrUnsupportedImage
+	}
+	if config.Width*config.Height > maxSourcePixels {
+		return nil, ErrUnsupportedImage
+	}
+
+	sourceImage, _, err := image.Decode(bytes.NewReader(content))
+	if err != nil {
+		return nil, ErrUnsupportedImage
+	}
+
+	avatar := image.NewRGBA(image.Rect(0, 0, AvatarSize, AvatarSize))
+	draw.CatmullRom.Scale(avatar, avatar.Bounds(), sourceImage, centeredSquare(sourceImage.Bounds()), draw.Src, nil)
+
+	var output bytes.Buffer
+	if err := jpeg.Encode(&output, avatar, &jpeg.Options{Quality: 85}); err != nil {
+		return nil, err
+	}
+
+	return output.Bytes(), nil
+}
+
+func centeredSquare(bounds image.Rectangle) image.Rectangle {
+	size := min(bounds.Dx(), bounds.Dy())
+	x := bounds.Min.X + (bounds.Dx()-size)/2
+	y := bounds.Min.Y + (bounds.Dy()-size)/2
+
+	return image.Rect(x, y, x+size, y+size)
+}
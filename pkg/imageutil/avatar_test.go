@@ -0,0 +1,55 @@
+package imageutil
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+func TestResizeAvatar(t *testing.T) {
+	testData := []struct {
+		name   string
+		width  int
+		height int
+	}{
+		{name: "landscape", width: 800, height: 400},
+		{name: "portrait", width: 300, height: 900},
+		{name: "smaller than avatar", width: 64, height: 64},
+	}
+
+	for _, td := range testData {
+		t.Run(td.name, func(t *testing.T) {
+			source := image.NewRGBA(image.Rect(0, 0, td.width, td.height))
+			source.Set(td.width/2, td.height/2, color.White)
+
+			var encoded bytes.Buffer
+			if err := png.Encode(&encoded, source); err != nil {
+				t.Fatal(err)
+			}
+
+			avatar, err := ResizeAvatar(&encoded)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			decoded, err := jpeg.Decode(bytes.NewReader(avatar))
+			if err != nil {
+				t.Fatalf("expected jpeg, got error: %v", err)
+			}
+			if decoded.Bounds().Dx() != AvatarSize || decoded.Bounds().Dy() != AvatarSize {
+				t.Errorf("expected %dx%d, got %v", AvatarSize, AvatarSize, decoded.Bounds())
+			}
+		})
+	}
+
+	t.Run("not an image", func(t *testing.T) {
+		_, err := ResizeAvatar(bytes.NewReader([]byte("plain text")))
+		if !errors.Is(err, ErrUnsupportedImage) {
+			t.Errorf("expected ErrUnsupportedImage, got %v", err)
+		}
+	})
+}
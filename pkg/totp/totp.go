@@ -0,0 +1,96 @@
+// Package totp implements time-based one-time passwords (RFC 6238) compatible with authenticator apps
+package totp
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	Period = 30
+	Digits = 6
+	// number of periods before and after the current one in which the code is still accepted (clock drift)
+	Skew = 1
+)
+
+var secretEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// GenerateSecret returns random 160 bit secret encoded in base32
+func GenerateSecret() string {
+	secret := make([]byte, 20)
+	rand.Read(secret)
+	return secretEncoding.EncodeToString(secret)
+}
+
+// URI builds otpauth URI which authenticator apps read from QR code
+func URI(issuer string, accountName string, secret string) string {
+	query := url.Values{}
+	query.Set("secret", secret)
+	query.Set("issuer", issuer)
+	query.Set("algorithm", "SHA1")
+	query.Set("digits", fmt.Sprint(Digits))
+	query.Set("period", fmt.Sprint(Period))
+
+	label := url.PathEscape(issuer + ":" + accountName)
+	return "otpauth://totp/" + label + "?" + query.Encode()
+}
+
+// Step returns the time step for given time
+func Step(t time.Time) int64 {
+	return t.Unix() / Period
+}
+
+// Code returns the code for given time step
+func Code(secret string, step int64) (string, error) {
+	key, err := secretEncoding.DecodeString(strings.ToUpper(secret))
+	if err != nil {
+		return "", err
+	}
+
+	message := make([]byte, 8)
+	binary.BigEndian.PutUint64(message, uint64(step))
+
+	mac := hmac.New(sha1.New, key)
+	mac.Write(message)
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	return fmt.Sprintf("%0*d", Digits, value%1_000_000), nil
+}
+
+// Validate checks the code against steps around t. Only steps after lastUsedStep are accepted, so the same code can't be used twice.
+// Returns the matched step, which should be stored as the new lastUsedStep.
+func Validate(secret string, code string, t time.Time, lastUsedStep int64) (int64, bool) {
+	code = strings.TrimSpace(code)
+	if len(code) != Digits {
+		return 0, false
+	}
+
+	currentStep := Step(t)
+	for step := currentStep - Skew; step <= currentStep+Skew; step++ {
+		if step <= lastUsedStep {
+			continue
+		}
+
+		expectedCode, err := Code(secret, step)
+		if err != nil {
+			return 0, false
+		}
+
+		if subtle.ConstantTimeCompare([]byte(expectedCode), []byte(code)) == 1 {
+			return step, true
+		}
+	}
+
+	return 0, false
+}
@@ -0,0 +1,72 @@
+package totp
+
+import (
+	"encoding/base32"
+	"strings"
+	"testing"
+	"time"
+)
+
+// test vectors from RFC 6238 (SHA1), truncated to 6 digits
+func TestCode(t *testing.T) {
+	secret := secretEncoding.EncodeToString([]byte("12345678901234567890"))
+
+	testData := []struct {
+		unixTime     int64
+		expectedCode string
+	}{
+		{unixTime: 59, expectedCode: "287082"},
+		{unixTime: 1111111109, expectedCode: "081804"},
+		{unixTime: 1111111111, expectedCode: "050471"},
+		{unixTime: 1234567890, expectedCode: "005924"},
+		{unixTime: 2000000000, expectedCode: "279037"},
+	}
+
+	for _, data := range testData {
+		code, err := Code(secret, Step(time.Unix(data.unixTime, 0)))
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+
+		if code != data.expectedCode {
+			t.Errorf("expected code %s for time %d, got %s", data.expectedCode, data.unixTime, code)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	secret := GenerateSecret()
+	now := time.Now()
+	currentStep := Step(now)
+	previousCode, _ := Code(secret, currentStep-1)
+	oldCode, _ := Code(secret, currentStep-3)
+
+	step, ok := Validate(secret, previousCode, now, 0)
+	if !ok || step != currentStep-1 {
+		t.Errorf("expected code from previous step to be accepted")
+	}
+
+	if _, ok := Validate(secret, previousCode, now, step); ok {
+		t.Errorf("expected already used code to be rejected")
+	}
+
+	if _, ok := Validate(secret, oldCode, now, 0); ok {
+		t.Errorf("expected code outside of allowed skew to be rejected")
+	}
+
+	if _, ok := Validate(secret, "12345", now, 0); ok {
+		t.Errorf("expected code with invalid length to be rejected")
+	}
+}
+
+func TestURI(t *testing.T) {
+	secret := GenerateSecret()
+	if _, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(secret); err != nil {
+		t.Fatalf("expected base32 secret, got %s", secret)
+	}
+
+	uri := URI("Athletics", "user@example.com", secret)
+	if !strings.HasPrefix(uri, "otpauth://totp/Athletics:user@example.com?") || !strings.Contains(uri, "secret="+secret) {
+		t.Errorf("unexpected uri %s", uri)
+	}
+}
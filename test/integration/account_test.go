@@ -0,0 +1,93 @@
+package controllers
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/filipio/athletics-backend/internal/models"
+	"github.com/filipio/athletics-backend/pkg/httpio"
+)
+
+func TestChangePassword(t *testing.T) {
+	t.Run("changes password and revokes other sessions", testCaseAccount(func(t *testing.T) {
+		createUser("change@account.test", "change", "oldpassword", httpio.UserRole)
+		currentSession := loginAs("change@account.test", "oldpassword")
+		otherSession := loginAs("change@account.test", "oldpassword")
+
+		response, _, err := executeHttpWithToken[map[string]any]("PUT", "/api/v1/users/me/password", httpio.AnyMap{
+			"current_password": "oldpassword",
+			"new_password":     "newpassword",
+		}, currentSession["access_token"].(string))
+		if err != nil {
+			t.Fatalf("Error executing request: %s", err.Error())
+		}
+
+		if response.StatusCode != http.StatusOK {
+			t.Fatalf("Expected status code 200, got %d", response.StatusCode)
+		}
+
+		response, _, _ = executeHttpWithToken[map[string]any]("GET", "/api/v1/users/me", nil, currentSession["access_token"].(string))
+		if response.StatusCode != http.StatusOK {
+			t.Errorf("Expected current session to stay active, got status %d", response.StatusCode)
+		}
+
+		response, _, _ = executeHttpWithToken[map[string]any]("GET", "/api/v1/users/me", nil, otherSession["access_token"].(string))
+		if response.StatusCode != http.StatusUnauthorized {
+			t.Errorf("Expected other session to be revoked, got status %d", response.StatusCode)
+		}
+	}))
+
+	t.Run("rejects invalid current password", testCaseAccount(func(t *testing.T) {
+		createUser("wrong@account.test", "wrong", "oldpassword", httpio.UserRole)
+		session := loginAs("wrong@account.test", "oldpassword")
+
+		response, _, err := executeHttpWithToken[map[string]any]("PUT", "/api/v1/users/me/password", httpio.AnyMap{
+			"current_password": "notmypassword",
+			"new_password":     "newpassword",
+		}, session["access_token"].(string))
+		if err != nil {
+			t.Fatalf("Error executing request: %s", err.Error())
+		}
+
+		if response.StatusCode != http.StatusBadRequest {
+			t.Errorf("Expected status code 400, got %d", response.StatusCode)
+		}
+	}))
+}
+
+func TestConfirmEmailChange(t *testing.T) {
+	t.Run("switches email after confirmation", testCaseAccount(func(t *testing.T) {
+		user := createUser("old@account.test", "mover", "password123", httpio.UserRole)
+
+		pendingChange := models.PendingEmailChange{UserID: user.ID, NewEmail: "new@account.test"}
+		pendingChange.GenerateVerificationToken()
+		dbInstance.Create(&pendingChange)
+
+		response, userResponse, err := Post[models.UserResponse]("/api/v1/auth/email-change/confirm", httpio.AnyMap{
+			"token": pendingChange.VerificationToken,
+		})
+		if err != nil {
+			t.Fatalf("Error executing request: %s", err.Error())
+		}
+
+		if response.StatusCode != http.StatusOK {
+			t.Fatalf("Expected status code 200, got %d", response.StatusCode)
+		}
+
+		if userResponse.Email != "new@account.test" {
+			t.Errorf("Expected email to be changed, got %s", userResponse.Email)
+		}
+	}))
+}
+
+func beforeEachAccount() {
+	dbInstance.Where("email LIKE ?", "%@account.test").Delete(&models.User{})
+	dbInstance.Where("new_email LIKE ?", "%@account.test").Delete(&models.PendingEmailChange{})
+}
+
+func testCaseAccount(test func(t *testing.T)) func(*testing.T) {
+	return func(t *testing.T) {
+		beforeEachAccount()
+		test(t)
+	}
+}
@@ -0,0 +1,107 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/filipio/athletics-backend/internal/models"
+	"github.com/filipio/athletics-backend/pkg/httpio"
+)
+
+func TestAuditLog(t *testing.T) {
+	t.Run("event changes are recorded with their diff", testCaseAuditLog(func(t *testing.T) {
+		response, event, err := Post[models.Event]("/api/v1/events", httpio.AnyMap{
+			"name":     "Audit Event",
+			"deadline": time.Now().Add(24 * time.Hour),
+		})
+		if err != nil {
+			t.Fatalf("Error executing request: %s", err.Error())
+		}
+		if response.StatusCode != http.StatusOK {
+			t.Fatalf("Expected status code 200, got %d", response.StatusCode)
+		}
+
+		eventPath := fmt.Sprintf("/api/v1/events/%d", event.ID)
+		response, _, _ = Put[models.Event](eventPath, httpio.AnyMap{
+			"name":     "Audit Event Renamed",
+			"deadline": event.Deadline,
+		})
+		if response.StatusCode != http.StatusOK {
+			t.Fatalf("Expected status code 200 on update, got %d", response.StatusCode)
+		}
+
+		response, _, _ = Delete[map[string]any](eventPath)
+		if response.StatusCode != http.StatusOK {
+			t.Fatalf("Expected status code 200 on delete, got %d", response.StatusCode)
+		}
+
+		response, logs, err := Get[httpio.PaginatedResponse](fmt.Sprintf("/api/v1/audit-logs?filter[resource_type]=event&filter[resource_id]=%d", event.ID))
+		if err != nil {
+			t.Fatalf("Error executing request: %s", err.Error())
+		}
+		if response.StatusCode != http.StatusOK {
+			t.Fatalf("Expected status code 200, got %d", response.StatusCode)
+		}
+
+		entries := logs.Data.([]any)
+		if len(entries) != 3 {
+			t.Fatalf("Expected 3 audit log entries, got %d", len(entries))
+		}
+
+		actions := []string{models.AuditActionCreate, models.AuditActionUpdate, models.AuditActionDelete}
+		for i, entry := range entries {
+			entry := entry.(map[string]any)
+			if entry["action"] != actions[i] || entry["actor_id"] == nil {
+				t.Errorf("Expected %s entry with actor, got %v", actions[i], entry)
+			}
+		}
+
+		changes := entries[1].(map[string]any)["changes"].(map[string]any)
+		name, _ := changes["name"].(map[string]any)
+		if name["before"] != "Audit Event" || name["after"] != "Audit Event Renamed" {
+			t.Errorf("Expected name change in the diff, got %v", changes)
+		}
+		if _, ok := changes["updated_at"]; ok {
+			t.Errorf("Expected updated_at to be left out of the diff")
+		}
+	}))
+
+	t.Run("audit log is append-only", testCaseAuditLog(func(t *testing.T) {
+		auditLog := models.AuditLog{Action: models.AuditActionCreate, ResourceType: models.AuditResourceEvent, ResourceID: 1}
+		if err := dbInstance.Create(&auditLog).Error; err != nil {
+			t.Fatalf("Error creating audit log: %s", err.Error())
+		}
+
+		if err := dbInstance.Model(&auditLog).Update("action", models.AuditActionDelete).Error; err == nil {
+			t.Errorf("Expected update of audit log to fail")
+		}
+		if err := dbInstance.Delete(&auditLog).Error; err == nil {
+			t.Errorf("Expected deletion of audit log to fail")
+		}
+	}))
+
+	t.Run("audit log requires audit:read permission", testCaseAuditLog(func(t *testing.T) {
+		createUser("organizer@audit.test", "audit_organizer", "password123", httpio.OrganizerRole)
+		accessToken := loginAs("organizer@audit.test", "password123")["access_token"].(string)
+
+		response, _, _ := executeHttpWithToken[map[string]any]("GET", "/api/v1/audit-logs", nil, accessToken)
+		if response.StatusCode != http.StatusUnauthorized {
+			t.Errorf("Expected status code 401, got %d", response.StatusCode)
+		}
+	}))
+}
+
+func beforeEachAuditLog() {
+	dbInstance.Where("email LIKE ?", "%@audit.test").Delete(&models.User{})
+	dbInstance.Unscoped().Where("name LIKE ?", "Audit Event%").Delete(&models.Event{})
+}
+
+func testCaseAuditLog(test func(t *testing.T)) func(*testing.T) {
+	return func(t *testing.T) {
+		beforeEachAuditLog()
+		defer beforeEachAuditLog()
+		test(t)
+	}
+}
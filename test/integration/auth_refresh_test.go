@@ -3,8 +3,12 @@ package controllers
 import (
 	"net/http"
 	"testing"
+	"time"
 
+	"github.com/filipio/athletics-backend/internal/models"
 	"github.com/filipio/athletics-backend/pkg/httpio"
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
 )
 
 // TestRefreshToken_Success tests that a valid refresh token returns new tokens
@@ -325,3 +329,66 @@ func TestAccessToken_SessionValidation(t *testing.T) {
 		}
 	}))
 }
+
+// TestRefreshToken_LegacyToken tests that tokens issued before selector.verifier format are rejected
+func TestRefreshToken_LegacyToken(t *testing.T) {
+	t.Run("legacy bcrypt-hashed token is rejected", testCase(func(t *testing.T) {
+		var admin models.User
+		dbInstance.Where("email = ?", adminEmail).First(&admin)
+
+		legacyToken := uuid.New().String()
+		hashedToken, _ := bcrypt.GenerateFromPassword([]byte(legacyToken), 10)
+		dbInstance.Create(&models.RefreshToken{
+			UserID:           admin.ID,
+			TokenHash:        string(hashedToken),
+			SessionID:        uuid.New().String(),
+			ExpiresAt:        time.Now().Add(time.Hour),
+			SessionStartedAt: time.Now(),
+		})
+
+		response, _, err := Post[map[string]any]("/api/v1/auth/refresh", httpio.AnyMap{
+			"refresh_token": legacyToken,
+		})
+		if err != nil {
+			t.Fatalf("Failed to refresh: %v", err)
+		}
+
+		if response.StatusCode != http.StatusUnauthorized {
+			t.Errorf("Expected status 401, got %d", response.StatusCode)
+		}
+	}))
+}
+
+// TestRefreshToken_ReuseRevokesSession tests that replaying a rotated refresh token revokes the whole session
+func TestRefreshToken_ReuseRevokesSession(t *testing.T) {
+	t.Run("replayed refresh token revokes session family", testCase(func(t *testing.T) {
+		email, password := getAdminCredentials()
+		tokens := loginAs(email, password)
+
+		_, rotatedTokens, err := Post[map[string]any]("/api/v1/auth/refresh", httpio.AnyMap{
+			"refresh_token": tokens["refresh_token"],
+		})
+		if err != nil {
+			t.Fatalf("Failed to refresh: %v", err)
+		}
+
+		response, _, _ := Post[map[string]any]("/api/v1/auth/refresh", httpio.AnyMap{
+			"refresh_token": tokens["refresh_token"],
+		})
+		if response.StatusCode != http.StatusUnauthorized {
+			t.Fatalf("Expected status 401 for replayed token, got %d", response.StatusCode)
+		}
+
+		response, _, _ = Post[map[string]any]("/api/v1/auth/refresh", httpio.AnyMap{
+			"refresh_token": (*rotatedTokens)["refresh_token"],
+		})
+		if response.StatusCode != http.StatusUnauthorized {
+			t.Errorf("Expected newest refresh token of the session to be revoked, got %d", response.StatusCode)
+		}
+
+		response, _, _ = executeHttpWithToken[map[string]any]("GET", "/api/v1/users/me", nil, (*rotatedTokens)["access_token"].(string))
+		if response.StatusCode != http.StatusUnauthorized {
+			t.Errorf("Expected access token of the session to be rejected, got %d", response.StatusCode)
+		}
+	}))
+}
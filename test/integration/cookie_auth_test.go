@@ -0,0 +1,167 @@
+package controllers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/filipio/athletics-backend/internal/models"
+	"github.com/filipio/athletics-backend/pkg/httpio"
+)
+
+func TestCookieAuth(t *testing.T) {
+	t.Run("login in cookie mode sets HttpOnly cookies instead of returning tokens", testCaseCookieAuth(func(t *testing.T) {
+		createUser("login@cookie.test", "cookielogin", "password123", httpio.UserRole)
+
+		response, body := executeHttpWithCookies(t, "POST", "/api/v1/login", httpio.AnyMap{
+			"email":    "login@cookie.test",
+			"password": "password123",
+		}, nil, map[string]string{httpio.AuthModeHeader: httpio.CookieAuthMode})
+		if response.StatusCode != http.StatusOK {
+			t.Fatalf("Expected status code 200, got %d", response.StatusCode)
+		}
+		if body["access_token"] != nil || body["refresh_token"] != nil || body["csrf_token"] == nil {
+			t.Errorf("Expected only CSRF token in the body, got %v", body)
+		}
+
+		cookies := cookiesByName(response.Cookies())
+		for _, name := range []string{httpio.AccessTokenCookieName, httpio.RefreshTokenCookieName} {
+			cookie, ok := cookies[name]
+			if !ok || !cookie.HttpOnly || cookie.SameSite == http.SameSiteDefaultMode {
+				t.Errorf("Expected HttpOnly SameSite cookie %s, got %v", name, cookie)
+			}
+		}
+		if cookies[httpio.CsrfTokenCookieName] == nil || cookies[httpio.CsrfTokenCookieName].Value != body["csrf_token"] {
+			t.Errorf("Expected CSRF cookie matching the body")
+		}
+	}))
+
+	t.Run("cookie authenticated requests require CSRF header for unsafe methods", testCaseCookieAuth(func(t *testing.T) {
+		createUser("csrf@cookie.test", "cookiecsrf", "password123", httpio.UserRole)
+		cookies, csrfToken := cookieLogin(t, "csrf@cookie.test", "password123")
+
+		response, _ := executeHttpWithCookies(t, "GET", "/api/v1/users/me", nil, cookies, nil)
+		if response.StatusCode != http.StatusOK {
+			t.Errorf("Expected status code 200 on GET, got %d", response.StatusCode)
+		}
+
+		response, body := executeHttpWithCookies(t, "POST", "/api/v1/auth/logout", nil, cookies, nil)
+		if response.StatusCode != http.StatusForbidden || body["error_type"] != "csrf_error" {
+			t.Errorf("Expected csrf_error without header, got %d %v", response.StatusCode, body)
+		}
+
+		response, _ = executeHttpWithCookies(t, "POST", "/api/v1/auth/logout", nil, cookies, map[string]string{httpio.CsrfTokenHeader: "forged"})
+		if response.StatusCode != http.StatusForbidden {
+			t.Errorf("Expected status code 403 with wrong header, got %d", response.StatusCode)
+		}
+
+		response, _ = executeHttpWithCookies(t, "POST", "/api/v1/auth/logout", nil, cookies, map[string]string{httpio.CsrfTokenHeader: csrfToken})
+		if response.StatusCode != http.StatusOK {
+			t.Fatalf("Expected status code 200 on logout, got %d", response.StatusCode)
+		}
+		if cookie := cookiesByName(response.Cookies())[httpio.AccessTokenCookieName]; cookie == nil || cookie.MaxAge >= 0 {
+			t.Errorf("Expected access token cookie to be cleared, got %v", cookie)
+		}
+	}))
+
+	t.Run("refresh token is rotated from the cookie", testCaseCookieAuth(func(t *testing.T) {
+		createUser("refresh@cookie.test", "cookierefresh", "password123", httpio.UserRole)
+		cookies, csrfToken := cookieLogin(t, "refresh@cookie.test", "password123")
+		headers := map[string]string{httpio.AuthModeHeader: httpio.CookieAuthMode}
+
+		response, _ := executeHttpWithCookies(t, "POST", "/api/v1/auth/refresh", nil, cookies, headers)
+		if response.StatusCode != http.StatusForbidden {
+			t.Errorf("Expected status code 403 without CSRF header, got %d", response.StatusCode)
+		}
+
+		headers[httpio.CsrfTokenHeader] = csrfToken
+		response, body := executeHttpWithCookies(t, "POST", "/api/v1/auth/refresh", nil, cookies, headers)
+		if response.StatusCode != http.StatusOK {
+			t.Fatalf("Expected status code 200, got %d", response.StatusCode)
+		}
+		rotated := cookiesByName(response.Cookies())
+		if rotated[httpio.RefreshTokenCookieName] == nil || rotated[httpio.RefreshTokenCookieName].Value == cookiesByName(cookies)[httpio.RefreshTokenCookieName].Value {
+			t.Errorf("Expected rotated refresh token cookie")
+		}
+		if body["csrf_token"] == csrfToken {
+			t.Errorf("Expected new CSRF token")
+		}
+	}))
+
+	t.Run("bearer tokens keep working without CSRF header", testCaseCookieAuth(func(t *testing.T) {
+		createUser("bearer@cookie.test", "cookiebearer", "password123", httpio.UserRole)
+		accessToken := loginAs("bearer@cookie.test", "password123")["access_token"].(string)
+
+		response, _, _ := executeLogout("/api/v1/auth/logout", accessToken)
+		if response.StatusCode != http.StatusOK {
+			t.Errorf("Expected status code 200, got %d", response.StatusCode)
+		}
+	}))
+}
+
+// cookieLogin logs in in the cookie auth mode and returns the cookies and CSRF token
+func cookieLogin(t *testing.T, email string, password string) ([]*http.Cookie, string) {
+	response, body := executeHttpWithCookies(t, "POST", "/api/v1/login", httpio.AnyMap{
+		"email":    email,
+		"password": password,
+	}, nil, map[string]string{httpio.AuthModeHeader: httpio.CookieAuthMode})
+	if response.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status code 200 on login, got %d", response.StatusCode)
+	}
+
+	return response.Cookies(), body["csrf_token"].(string)
+}
+
+// executeHttpWithCookies sends cookies explicitly, the test server runs over plain http so Secure cookies wouldn't be kept by a cookie jar
+func executeHttpWithCookies(t *testing.T, method string, path string, body any, cookies []*http.Cookie, headers map[string]string) (*http.Response, map[string]any) {
+	var jsonBody []byte
+	if body != nil {
+		var err error
+		if jsonBody, err = json.Marshal(body); err != nil {
+			t.Fatalf("cannot marshal into json: %s", err.Error())
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, host+path, bytes.NewBuffer(jsonBody))
+	if err != nil {
+		t.Fatalf("failed to create request: %s", err.Error())
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+	for _, cookie := range cookies {
+		req.AddCookie(&http.Cookie{Name: cookie.Name, Value: cookie.Value})
+	}
+
+	response, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("failed to do request: %s", err.Error())
+	}
+
+	var result map[string]any
+	json.NewDecoder(response.Body).Decode(&result)
+
+	return response, result
+}
+
+func cookiesByName(cookies []*http.Cookie) map[string]*http.Cookie {
+	byName := make(map[string]*http.Cookie)
+	for _, cookie := range cookies {
+		byName[cookie.Name] = cookie
+	}
+	return byName
+}
+
+func beforeEachCookieAuth() {
+	dbInstance.Where("email LIKE ?", "%@cookie.test").Delete(&models.User{})
+}
+
+func testCaseCookieAuth(test func(t *testing.T)) func(*testing.T) {
+	return func(t *testing.T) {
+		beforeEachCookieAuth()
+		defer beforeEachCookieAuth()
+		test(t)
+	}
+}
@@ -0,0 +1,118 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/filipio/athletics-backend/internal/models"
+	"github.com/filipio/athletics-backend/pkg/httpio"
+)
+
+func TestETag(t *testing.T) {
+	t.Run("get honours If-None-Match", testCaseETag(func(t *testing.T) {
+		event := &models.Event{Name: "ETag Event", Deadline: time.Now().Add(24 * time.Hour), Status: "published"}
+		dbInstance.Save(event)
+		eventPath := fmt.Sprintf("/api/v1/events/%d", event.ID)
+		authorization := map[string]string{"Authorization": "Bearer " + adminToken}
+
+		response, _ := executeHttpWithCookies(t, "GET", eventPath, nil, nil, authorization)
+		etag := response.Header.Get("ETag")
+		if response.StatusCode != http.StatusOK || etag == "" {
+			t.Fatalf("Expected status code 200 with ETag, got %d %q", response.StatusCode, etag)
+		}
+
+		response, _ = executeHttpWithCookies(t, "GET", eventPath, nil, nil, map[string]string{
+			"Authorization": "Bearer " + adminToken,
+			"If-None-Match": etag,
+		})
+		if response.StatusCode != http.StatusNotModified {
+			t.Errorf("Expected status code 304, got %d", response.StatusCode)
+		}
+
+		response, _ = executeHttpWithCookies(t, "GET", eventPath, nil, nil, map[string]string{
+			"Authorization": "Bearer " + adminToken,
+			"If-None-Match": `"0-0"`,
+		})
+		if response.StatusCode != http.StatusOK {
+			t.Errorf("Expected status code 200 with stale ETag, got %d", response.StatusCode)
+		}
+	}))
+
+	t.Run("update with stale If-Match is rejected", testCaseETag(func(t *testing.T) {
+		event := &models.Event{Name: "ETag Event", Deadline: time.Now().Add(24 * time.Hour), Status: "published"}
+		dbInstance.Save(event)
+		question := &models.Question{EventID: event.ID, Content: "Who wins?", Type: "country", Points: 1}
+		dbInstance.Save(question)
+		questionPath := fmt.Sprintf("/api/v1/questions/%d", question.ID)
+
+		response, _ := executeHttpWithCookies(t, "GET", questionPath, nil, nil, map[string]string{"Authorization": "Bearer " + adminToken})
+		etag := response.Header.Get("ETag")
+
+		payload := map[string]any{"event_id": event.ID, "content": "Who wins the final?", "type": "country", "points": 1}
+		response, _ = executeHttpWithCookies(t, "PUT", questionPath, payload, nil, map[string]string{
+			"Authorization": "Bearer " + adminToken,
+			"If-Match":      etag,
+		})
+		if response.StatusCode != http.StatusOK {
+			t.Fatalf("Expected status code 200 with current ETag, got %d", response.StatusCode)
+		}
+		if newETag := response.Header.Get("ETag"); newETag == "" || newETag == etag {
+			t.Errorf("Expected new ETag after update, got %q", newETag)
+		}
+
+		payload["content"] = "Who wins the semi-final?"
+		response, body := executeHttpWithCookies(t, "PUT", questionPath, payload, nil, map[string]string{
+			"Authorization": "Bearer " + adminToken,
+			"If-Match":      etag,
+		})
+		if response.StatusCode != http.StatusPreconditionFailed {
+			t.Fatalf("Expected status code 412 with stale ETag, got %d", response.StatusCode)
+		}
+		if body["error_type"] != "precondition_failed" {
+			t.Errorf("Expected precondition_failed error, got %v", body)
+		}
+
+		var stored models.Question
+		dbInstance.First(&stored, question.ID)
+		if stored.Content != "Who wins the final?" {
+			t.Errorf("Expected question to be kept after rejected update, got %q", stored.Content)
+		}
+	}))
+
+	t.Run("ETag changes with embedded associations", testCaseETag(func(t *testing.T) {
+		user := createUser("user@etag.test", "etag_user", "password123", httpio.UserRole)
+		userPath := fmt.Sprintf("/api/v1/users/%d", user.ID)
+		authorization := map[string]string{"Authorization": "Bearer " + adminToken}
+
+		response, _ := executeHttpWithCookies(t, "GET", userPath, nil, nil, authorization)
+		etag := response.Header.Get("ETag")
+
+		response, _, _ = Post[map[string]any](userPath+"/roles/"+httpio.OrganizerRole, httpio.AnyMap{"reason": "organizes events"})
+		if response.StatusCode != http.StatusOK {
+			t.Fatalf("Expected status code 200 on role grant, got %d", response.StatusCode)
+		}
+
+		response, _ = executeHttpWithCookies(t, "GET", userPath, nil, nil, map[string]string{
+			"Authorization": "Bearer " + adminToken,
+			"If-None-Match": etag,
+		})
+		if response.StatusCode != http.StatusOK || response.Header.Get("ETag") == etag {
+			t.Errorf("Expected new ETag after role grant, got status %d", response.StatusCode)
+		}
+	}))
+}
+
+func beforeEachETag() {
+	dbInstance.Unscoped().Where("name = ?", "ETag Event").Delete(&models.Event{})
+	dbInstance.Where("email LIKE ?", "%@etag.test").Delete(&models.User{})
+}
+
+func testCaseETag(test func(t *testing.T)) func(*testing.T) {
+	return func(t *testing.T) {
+		beforeEachETag()
+		defer beforeEachETag()
+		test(t)
+	}
+}
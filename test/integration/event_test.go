@@ -181,8 +181,8 @@ func TestEventPublishing(t *testing.T) {
 }
 
 func beforeEachEvent() {
-	dbInstance.Where("1 = 1").Delete(&models.Event{})
-	dbInstance.Where("1 = 1").Delete(&models.Question{})
+	dbInstance.Unscoped().Where("1 = 1").Delete(&models.Event{})
+	dbInstance.Unscoped().Where("1 = 1").Delete(&models.Question{})
 }
 
 func afterEachEvent() {
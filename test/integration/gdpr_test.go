@@ -0,0 +1,197 @@
+package controllers
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/filipio/athletics-backend/internal/models"
+	"github.com/filipio/athletics-backend/pkg/httpio"
+	"github.com/filipio/athletics-backend/pkg/totp"
+)
+
+func TestDataExport(t *testing.T) {
+	t.Run("export is built in the background and downloaded as zip", testCaseGdpr(func(t *testing.T) {
+		createUser("export@gdpr.test", "gdpr_export", "password123", httpio.UserRole)
+		accessToken := loginAs("export@gdpr.test", "password123")["access_token"].(string)
+
+		response, _, _ := executeHttpWithToken[map[string]any]("GET", "/api/v1/users/me/export", nil, accessToken)
+		if response.StatusCode != http.StatusNotFound {
+			t.Fatalf("Expected status code 404 before any export, got %d", response.StatusCode)
+		}
+
+		response, export, err := executeHttpWithToken[models.DataExportResponse]("POST", "/api/v1/users/me/export", nil, accessToken)
+		if err != nil {
+			t.Fatalf("Error executing request: %s", err.Error())
+		}
+		if response.StatusCode != http.StatusAccepted {
+			t.Fatalf("Expected status code 202, got %d", response.StatusCode)
+		}
+
+		deadline := time.Now().Add(10 * time.Second)
+		for export.Status == models.DataExportStatusPending && time.Now().Before(deadline) {
+			time.Sleep(200 * time.Millisecond)
+			_, export, _ = executeHttpWithToken[models.DataExportResponse]("GET", "/api/v1/users/me/export", nil, accessToken)
+		}
+		if export.Status != models.DataExportStatusReady || export.DownloadURL == nil {
+			t.Fatalf("Expected ready export with download url, got %+v", export)
+		}
+
+		req, _ := http.NewRequestWithContext(ctx, "GET", host+*export.DownloadURL, nil)
+		req.Header.Set("Authorization", "Bearer "+accessToken)
+		response, err = http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Error executing request: %s", err.Error())
+		}
+		defer response.Body.Close()
+		if response.StatusCode != http.StatusOK {
+			t.Fatalf("Expected status code 200 on download, got %d", response.StatusCode)
+		}
+
+		content, _ := io.ReadAll(response.Body)
+		archive, err := zip.NewReader(bytes.NewReader(content), int64(len(content)))
+		if err != nil {
+			t.Fatalf("Expected zip archive, got error: %s", err.Error())
+		}
+		if len(archive.File) == 0 || archive.File[0].Name != "data.json" {
+			t.Fatalf("Expected data.json in the archive")
+		}
+
+		dataFile, _ := archive.File[0].Open()
+		var data map[string]any
+		json.NewDecoder(dataFile).Decode(&data)
+		user, _ := data["user"].(map[string]any)
+		if user["email"] != "export@gdpr.test" {
+			t.Errorf("Expected exported user data, got %v", data["user"])
+		}
+		if sessions, _ := data["sessions"].([]any); len(sessions) != 1 {
+			t.Errorf("Expected 1 exported session, got %v", data["sessions"])
+		}
+	}))
+}
+
+func TestDeleteMyAccount(t *testing.T) {
+	t.Run("wrong password does not delete the account", testCaseGdpr(func(t *testing.T) {
+		user := createUser("wrong@gdpr.test", "gdpr_wrong", "password123", httpio.UserRole)
+		accessToken := loginAs("wrong@gdpr.test", "password123")["access_token"].(string)
+
+		response, _, _ := executeHttpWithToken[map[string]any]("DELETE", "/api/v1/users/me", httpio.AnyMap{"password": "wrong"}, accessToken)
+		if response.StatusCode != http.StatusBadRequest {
+			t.Fatalf("Expected status code 400, got %d", response.StatusCode)
+		}
+
+		var count int64
+		dbInstance.Model(&models.User{}).Where("id = ?", user.ID).Count(&count)
+		if count != 1 {
+			t.Errorf("Expected user to be kept")
+		}
+	}))
+
+	t.Run("two factor code replaces the password", testCaseGdpr(func(t *testing.T) {
+		user := createUser("code@gdpr.test", "gdpr_code", "password123", httpio.UserRole)
+		accessToken := loginAs("code@gdpr.test", "password123")["access_token"].(string)
+		secret, _ := enableTwoFactor(t, accessToken, "password123")
+
+		response, _, _ := executeHttpWithToken[map[string]any]("DELETE", "/api/v1/users/me", httpio.AnyMap{"code": "000000"}, accessToken)
+		if response.StatusCode != http.StatusBadRequest {
+			t.Fatalf("Expected status code 400 for invalid code, got %d", response.StatusCode)
+		}
+
+		// code of the next step is within allowed clock skew and was not used for the confirmation
+		code, _ := totp.Code(secret, totp.Step(time.Now())+1)
+		response, _, _ = executeHttpWithToken[map[string]any]("DELETE", "/api/v1/users/me", httpio.AnyMap{"code": code}, accessToken)
+		if response.StatusCode != http.StatusOK {
+			t.Fatalf("Expected status code 200, got %d", response.StatusCode)
+		}
+
+		var count int64
+		dbInstance.Model(&models.User{}).Where("id = ?", user.ID).Count(&count)
+		if count != 0 {
+			t.Errorf("Expected user to be deleted")
+		}
+	}))
+
+	t.Run("user without answers is deleted and the session is revoked", testCaseGdpr(func(t *testing.T) {
+		user := createUser("plain@gdpr.test", "gdpr_plain", "password123", httpio.UserRole)
+		accessToken := loginAs("plain@gdpr.test", "password123")["access_token"].(string)
+
+		response, body, err := executeHttpWithToken[map[string]any]("DELETE", "/api/v1/users/me", httpio.AnyMap{"password": "password123"}, accessToken)
+		if err != nil {
+			t.Fatalf("Error executing request: %s", err.Error())
+		}
+		if response.StatusCode != http.StatusOK || (*body)["anonymized"] != false {
+			t.Fatalf("Expected deletion without anonymization, got %d %v", response.StatusCode, *body)
+		}
+
+		var count int64
+		dbInstance.Model(&models.User{}).Where("id = ?", user.ID).Count(&count)
+		if count != 0 {
+			t.Errorf("Expected user to be deleted")
+		}
+
+		response, _, _ = executeHttpWithToken[map[string]any]("GET", "/api/v1/users/me", nil, accessToken)
+		if response.StatusCode != http.StatusUnauthorized {
+			t.Errorf("Expected status code 401 with token of deleted user, got %d", response.StatusCode)
+		}
+	}))
+
+	t.Run("user with answers is anonymized and stays in the ranking", testCaseGdpr(func(t *testing.T) {
+		user := createUser("ranked@gdpr.test", "gdpr_ranked", "password123", httpio.UserRole)
+		event := &models.Event{Name: "GDPR Event", Deadline: time.Now().Add(24 * time.Hour), Status: "published"}
+		dbInstance.Save(event)
+		question := &models.Question{EventID: event.ID, Content: "Who wins?", Type: "country", Points: 1}
+		dbInstance.Save(question)
+		dbInstance.Save(&models.Answer{
+			UserID:     user.ID,
+			QuestionID: question.ID,
+			Content:    models.AnswerOfQuestion{JSON: []byte(`{"country": "KEN"}`)},
+			Points:     7,
+		})
+		defer dbInstance.Delete(&models.User{}, user.ID)
+		defer dbInstance.Unscoped().Delete(event)
+
+		accessToken := loginAs("ranked@gdpr.test", "password123")["access_token"].(string)
+		response, body, _ := executeHttpWithToken[map[string]any]("DELETE", "/api/v1/users/me", httpio.AnyMap{"password": "password123"}, accessToken)
+		if response.StatusCode != http.StatusOK || (*body)["anonymized"] != true {
+			t.Fatalf("Expected anonymization, got %d %v", response.StatusCode, *body)
+		}
+
+		var anonymized models.User
+		dbInstance.First(&anonymized, user.ID)
+		expectedUsername := fmt.Sprintf("deleted_%d", user.ID)
+		if anonymized.Username != expectedUsername || anonymized.Email == "ranked@gdpr.test" || anonymized.AnonymizedAt == nil {
+			t.Errorf("Expected anonymized user, got %s %s", anonymized.Username, anonymized.Email)
+		}
+
+		response, ranking, _ := Get[map[string]any](fmt.Sprintf("/api/v1/ranking?event_id=%d", event.ID))
+		if response.StatusCode != http.StatusOK {
+			t.Fatalf("Expected status code 200 on ranking, got %d", response.StatusCode)
+		}
+		data := (*ranking)["data"].([]any)
+		if len(data) != 1 || data[0].(map[string]any)["username"] != expectedUsername || data[0].(map[string]any)["total_points"] != float64(7) {
+			t.Errorf("Expected anonymized user in the ranking, got %v", data)
+		}
+
+		response, _, _ = Post[map[string]any]("/api/v1/login", httpio.AnyMap{"email": "ranked@gdpr.test", "password": "password123"})
+		if response.StatusCode == http.StatusOK {
+			t.Errorf("Expected login of deleted user to fail")
+		}
+	}))
+}
+
+func beforeEachGdpr() {
+	dbInstance.Where("email LIKE ?", "%@gdpr.test").Delete(&models.User{})
+}
+
+func testCaseGdpr(test func(t *testing.T)) func(*testing.T) {
+	return func(t *testing.T) {
+		beforeEachGdpr()
+		defer beforeEachGdpr()
+		test(t)
+	}
+}
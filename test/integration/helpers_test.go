@@ -9,6 +9,8 @@ import (
 	"testing"
 
 	"github.com/filipio/athletics-backend/internal/models"
+	"github.com/filipio/athletics-backend/pkg/httpio"
+	"golang.org/x/crypto/bcrypt"
 )
 
 func executeHttp[T any](method string, path string, body any) (*http.Response, *T, error) {
@@ -62,6 +64,10 @@ func Put[T any](path string, body any) (*http.Response, *T, error) {
 	return executeHttp[T]("PUT", path, body)
 }
 
+func Patch[T any](path string, body any) (*http.Response, *T, error) {
+	return executeHttp[T]("PATCH", path, body)
+}
+
 func Delete[T any](path string) (*http.Response, *T, error) {
 	return executeHttp[T]("DELETE", path, nil)
 }
@@ -132,3 +138,33 @@ func executeHttpWithToken[T any](method string, path string, body any, token str
 func executeLogout(path string, token string) (*http.Response, *map[string]any, error) {
 	return executeHttpWithToken[map[string]any]("POST", path, nil, token)
 }
+
+// createUser inserts a user with hashed password and the given role directly into the database
+func createUser(email string, username string, password string, roleName string) models.User {
+	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte(password), 10)
+	user := models.User{
+		Email:    email,
+		Username: username,
+		Password: string(hashedPassword),
+	}
+	dbInstance.Create(&user)
+
+	var role models.Role
+	dbInstance.Where("name = ?", roleName).First(&role)
+	dbInstance.Model(&user).Association("Roles").Append(&role)
+
+	return user
+}
+
+// loginAs logs in with given credentials and returns the whole token pair response
+func loginAs(email string, password string) map[string]any {
+	_, loginResult, err := Post[map[string]any]("/api/v1/login", httpio.AnyMap{
+		"email":    email,
+		"password": password,
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	return *loginResult
+}
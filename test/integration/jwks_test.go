@@ -0,0 +1,44 @@
+package controllers
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/filipio/athletics-backend/pkg/config"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestJWKS_ContainsAccessTokenSigningKey(t *testing.T) {
+	response, jwkSet, err := Get[config.JwkSet]("/.well-known/jwks.json")
+	if err != nil {
+		t.Fatalf("Error executing request: %s", err.Error())
+	}
+
+	if response.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status code 200, got %d", response.StatusCode)
+	}
+
+	token, _, err := jwt.NewParser().ParseUnverified(adminToken, jwt.MapClaims{})
+	if err != nil {
+		t.Fatalf("Failed to decode access token: %s", err.Error())
+	}
+
+	keyID, _ := token.Header["kid"].(string)
+	found := false
+	for _, key := range jwkSet.Keys {
+		if key.Kid == keyID && key.Alg == token.Method.Alg() {
+			found = true
+		}
+	}
+
+	if !found {
+		t.Errorf("Expected jwks to contain key '%s' used to sign access token", keyID)
+	}
+
+	claims := token.Claims.(jwt.MapClaims)
+	for _, claim := range []string{"iss", "aud", "iat", "exp"} {
+		if _, ok := claims[claim]; !ok {
+			t.Errorf("Expected access token to contain '%s' claim", claim)
+		}
+	}
+}
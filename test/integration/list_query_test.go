@@ -0,0 +1,126 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/filipio/athletics-backend/internal/models"
+	"github.com/filipio/athletics-backend/pkg/httpio"
+)
+
+func TestListQuery(t *testing.T) {
+	t.Run("events are filtered, sorted and reduced to the requested fields", testCaseListQuery(func(t *testing.T) {
+		now := time.Now()
+		for i, name := range []string{"List Query Sprint", "List Query Marathon", "List Query Relay"} {
+			dbInstance.Save(&models.Event{Name: name, Deadline: now.Add(time.Duration(i+1) * time.Hour), Status: "published"})
+		}
+
+		query := url.Values{}
+		query.Set("filter[name][like]", "list query")
+		query.Set("filter[deadline][gte]", now.Add(90*time.Minute).Format(time.RFC3339))
+		query.Set("sort", "-deadline")
+		query.Set("fields", "name")
+
+		response, events, err := Get[httpio.PaginatedResponse]("/api/v1/events?" + query.Encode())
+		if err != nil {
+			t.Fatalf("Error executing request: %s", err.Error())
+		}
+		if response.StatusCode != http.StatusOK {
+			t.Fatalf("Expected status code 200, got %d", response.StatusCode)
+		}
+
+		data := events.Data.([]any)
+		if len(data) != 2 {
+			t.Fatalf("Expected 2 events, got %d", len(data))
+		}
+		first := data[0].(map[string]any)
+		if first["name"] != "List Query Relay" || data[1].(map[string]any)["name"] != "List Query Marathon" {
+			t.Errorf("Expected events sorted by deadline descending, got %v", data)
+		}
+		if _, ok := first["deadline"]; ok || first["id"] == nil {
+			t.Errorf("Expected only id and name fields, got %v", first)
+		}
+	}))
+
+	t.Run("multiple values are filtered with in operator", testCaseListQuery(func(t *testing.T) {
+		deadline := time.Now().Add(time.Hour)
+		dbInstance.Save(&models.Event{Name: "List Query Draft", Deadline: deadline, Status: "draft"})
+		dbInstance.Save(&models.Event{Name: "List Query Published", Deadline: deadline, Status: "published"})
+
+		response, events, _ := Get[httpio.PaginatedResponse]("/api/v1/events?filter[name][like]=list+query&filter[status][in]=draft,published&sort=name")
+		if response.StatusCode != http.StatusOK || events.PaginationInfo.TotalCount != 2 {
+			t.Fatalf("Expected 2 events, got %d", response.StatusCode)
+		}
+		if events.Data.([]any)[0].(map[string]any)["name"] != "List Query Draft" {
+			t.Errorf("Expected events sorted by name, got %v", events.Data)
+		}
+	}))
+
+	t.Run("filters which aren't plain columns are applied", testCaseListQuery(func(t *testing.T) {
+		dbInstance.Save(&models.Event{Name: "List Query Finished", Deadline: time.Now().Add(-time.Hour), Status: "published"})
+		dbInstance.Save(&models.Event{Name: "List Query Upcoming", Deadline: time.Now().Add(time.Hour), Status: "published"})
+
+		response, events, _ := Get[httpio.PaginatedResponse]("/api/v1/events?filter[name][like]=list+query&filter[active]=true")
+		if response.StatusCode != http.StatusOK || events.PaginationInfo.TotalCount != 1 {
+			t.Fatalf("Expected 1 active event, got %d", response.StatusCode)
+		}
+		if events.Data.([]any)[0].(map[string]any)["name"] != "List Query Upcoming" {
+			t.Errorf("Expected upcoming event, got %v", events.Data)
+		}
+	}))
+
+	t.Run("legacy parameters are applied as filters", testCaseListQuery(func(t *testing.T) {
+		finished := &models.Event{Name: "List Query Finished", Deadline: time.Now().Add(-time.Hour), Status: "published"}
+		dbInstance.Save(finished)
+		upcoming := &models.Event{Name: "List Query Upcoming", Deadline: time.Now().Add(time.Hour), Status: "published"}
+		dbInstance.Save(upcoming)
+
+		response, events, _ := Get[httpio.PaginatedResponse](fmt.Sprintf("/api/v1/events?ids=%d,%d&active=true", finished.ID, upcoming.ID))
+		if response.StatusCode != http.StatusOK || events.PaginationInfo.TotalCount != 1 {
+			t.Fatalf("Expected 1 active event, got %d", response.StatusCode)
+		}
+		if events.Data.([]any)[0].(map[string]any)["name"] != "List Query Upcoming" {
+			t.Errorf("Expected upcoming event, got %v", events.Data)
+		}
+
+		response, _, _ = Get[map[string]any]("/api/v1/events?ids=1,abc")
+		if response.StatusCode != http.StatusBadRequest {
+			t.Errorf("Expected status code 400 for invalid legacy value, got %d", response.StatusCode)
+		}
+	}))
+
+	t.Run("parameters which are not allowed are rejected", testCaseListQuery(func(t *testing.T) {
+		for _, path := range []string{
+			"/api/v1/events?filter[description]=x",
+			"/api/v1/events?filter[deadline][like]=2026",
+			"/api/v1/events?filter[id]=abc",
+			"/api/v1/events?filter[id][in]=1,abc",
+			"/api/v1/events?filter[deadline][gte]=notadate",
+			"/api/v1/events?sort=description",
+			"/api/v1/events?order_by=name%22%3B+DROP+TABLE+events",
+			"/api/v1/events?order_by=name&order_dir=sideways",
+			"/api/v1/events?fields=name,secret",
+			"/api/v1/users?fields=password",
+		} {
+			response, _, _ := Get[map[string]any](path)
+			if response.StatusCode != http.StatusBadRequest {
+				t.Errorf("Expected status code 400 for %s, got %d", path, response.StatusCode)
+			}
+		}
+	}))
+}
+
+func beforeEachListQuery() {
+	dbInstance.Unscoped().Where("name LIKE ?", "List Query%").Delete(&models.Event{})
+}
+
+func testCaseListQuery(test func(t *testing.T)) func(*testing.T) {
+	return func(t *testing.T) {
+		beforeEachListQuery()
+		defer beforeEachListQuery()
+		test(t)
+	}
+}
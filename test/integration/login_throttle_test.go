@@ -0,0 +1,102 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/filipio/athletics-backend/internal/models"
+	"github.com/filipio/athletics-backend/pkg/httpio"
+)
+
+func TestLoginThrottling(t *testing.T) {
+	t.Run("account is locked after too many failed attempts", testCaseLoginThrottle(func(t *testing.T) {
+		createUser("locked@throttle.test", "locked", "password123", httpio.UserRole)
+
+		for i := range models.MaxFailedLoginsPerAccount {
+			response, _, _ := Post[httpio.ErrorsResponse]("/api/v1/login", httpio.AnyMap{
+				"email":    "locked@throttle.test",
+				"password": "wrongpassword",
+			})
+			if response.StatusCode != http.StatusUnauthorized {
+				t.Fatalf("Attempt %d: expected status code 401, got %d", i+1, response.StatusCode)
+			}
+		}
+
+		response, errorResponse, _ := Post[httpio.ErrorsResponse]("/api/v1/login", httpio.AnyMap{
+			"email":    "locked@throttle.test",
+			"password": "password123",
+		})
+		if response.StatusCode != http.StatusTooManyRequests {
+			t.Fatalf("Expected status code 429, got %d", response.StatusCode)
+		}
+
+		if errorResponse.ErrorType != "rate_limit_error" {
+			t.Errorf("Expected error type 'rate_limit_error', got '%s'", errorResponse.ErrorType)
+		}
+
+		if response.Header.Get("Retry-After") == "" {
+			t.Error("Expected Retry-After header")
+		}
+	}))
+
+	t.Run("admin can unlock account", testCaseLoginThrottle(func(t *testing.T) {
+		user := createUser("unlock@throttle.test", "unlock", "password123", httpio.UserRole)
+
+		for range models.MaxFailedLoginsPerAccount {
+			Post[httpio.ErrorsResponse]("/api/v1/login", httpio.AnyMap{
+				"email":    "unlock@throttle.test",
+				"password": "wrongpassword",
+			})
+		}
+
+		response, _, err := Post[map[string]any](fmt.Sprintf("/api/v1/users/%d/unlock", user.ID), nil)
+		if err != nil {
+			t.Fatalf("Error executing request: %s", err.Error())
+		}
+		if response.StatusCode != http.StatusOK {
+			t.Fatalf("Expected status code 200, got %d", response.StatusCode)
+		}
+
+		response, _, _ = Post[map[string]any]("/api/v1/login", httpio.AnyMap{
+			"email":    "unlock@throttle.test",
+			"password": "password123",
+		})
+		if response.StatusCode != http.StatusOK {
+			t.Errorf("Expected status code 200 after unlock, got %d", response.StatusCode)
+		}
+	}))
+
+	t.Run("ip address is locked after too many failed attempts on different accounts", testCaseLoginThrottle(func(t *testing.T) {
+		createUser("victim@throttle.test", "victim", "password123", httpio.UserRole)
+
+		for i := range models.MaxFailedLoginsPerIP {
+			Post[httpio.ErrorsResponse]("/api/v1/login", httpio.AnyMap{
+				"email":    fmt.Sprintf("spray%d@throttle.test", i),
+				"password": "password123",
+			})
+		}
+
+		response, _, _ := Post[httpio.ErrorsResponse]("/api/v1/login", httpio.AnyMap{
+			"email":    "victim@throttle.test",
+			"password": "password123",
+		})
+		if response.StatusCode != http.StatusTooManyRequests {
+			t.Errorf("Expected status code 429, got %d", response.StatusCode)
+		}
+	}))
+}
+
+func beforeEachLoginThrottle() {
+	dbInstance.Where("1 = 1").Delete(&models.LoginAttemptLimit{})
+	dbInstance.Where("email LIKE ?", "%@throttle.test").Delete(&models.User{})
+}
+
+func testCaseLoginThrottle(test func(t *testing.T)) func(*testing.T) {
+	return func(t *testing.T) {
+		beforeEachLoginThrottle()
+		// failed attempts from the test client must not lock logins in other tests
+		defer beforeEachLoginThrottle()
+		test(t)
+	}
+}
@@ -0,0 +1,99 @@
+package controllers
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/filipio/athletics-backend/internal/models"
+	"github.com/filipio/athletics-backend/pkg/httpio"
+)
+
+func TestMagicLink(t *testing.T) {
+	t.Run("request for unknown email does not reveal account existence", testCaseMagicLink(func(t *testing.T) {
+		response, result, err := Post[map[string]any]("/api/v1/auth/magic-link", httpio.AnyMap{
+			"email": "nobody@magic-link.test",
+		})
+		if err != nil {
+			t.Fatalf("Error executing request: %s", err.Error())
+		}
+
+		if response.StatusCode != http.StatusOK {
+			t.Fatalf("Expected status code 200, got %d", response.StatusCode)
+		}
+
+		if (*result)["message"] == nil {
+			t.Error("Expected message in response")
+		}
+	}))
+
+	t.Run("requests are rate limited", testCaseMagicLink(func(t *testing.T) {
+		for i := 0; i < models.MaxMagicLinkRequests; i++ {
+			Post[map[string]any]("/api/v1/auth/magic-link", httpio.AnyMap{"email": "limited@magic-link.test"})
+		}
+
+		response, _, _ := Post[map[string]any]("/api/v1/auth/magic-link", httpio.AnyMap{"email": "limited@magic-link.test"})
+		if response.StatusCode != http.StatusTooManyRequests {
+			t.Errorf("Expected status code 429, got %d", response.StatusCode)
+		}
+
+		response, _, _ = Post[map[string]any]("/api/v1/auth/magic-link", httpio.AnyMap{"email": "Limited@Magic-Link.test"})
+		if response.StatusCode != http.StatusTooManyRequests {
+			t.Errorf("Expected the same address in another case to be limited, got %d", response.StatusCode)
+		}
+	}))
+
+	t.Run("link logs the user in and can't be reused", testCaseMagicLink(func(t *testing.T) {
+		user := createUser("login@magic-link.test", "magiclogin", "password123", httpio.UserRole)
+
+		magicLinkToken := models.MagicLinkToken{UserID: user.ID}
+		plainToken := magicLinkToken.GenerateToken()
+		dbInstance.Create(&magicLinkToken)
+
+		response, tokens, err := Post[map[string]any]("/api/v1/auth/magic-link/consume", httpio.AnyMap{"token": plainToken})
+		if err != nil {
+			t.Fatalf("Error executing request: %s", err.Error())
+		}
+		if response.StatusCode != http.StatusOK {
+			t.Fatalf("Expected status code 200, got %d", response.StatusCode)
+		}
+		if (*tokens)["access_token"] == nil || (*tokens)["refresh_token"] == nil {
+			t.Errorf("Expected token pair, got %v", *tokens)
+		}
+
+		response, _, _ = Post[map[string]any]("/api/v1/auth/magic-link/consume", httpio.AnyMap{"token": plainToken})
+		if response.StatusCode != http.StatusUnauthorized {
+			t.Errorf("Expected reused link to be rejected with 401, got %d", response.StatusCode)
+		}
+	}))
+
+	t.Run("expired link is rejected", testCaseMagicLink(func(t *testing.T) {
+		user := createUser("expired@magic-link.test", "magicexpired", "password123", httpio.UserRole)
+
+		magicLinkToken := models.MagicLinkToken{UserID: user.ID}
+		plainToken := magicLinkToken.GenerateToken()
+		magicLinkToken.ExpiresAt = time.Now().Add(-time.Minute)
+		dbInstance.Create(&magicLinkToken)
+
+		response, _, _ := Post[map[string]any]("/api/v1/auth/magic-link/consume", httpio.AnyMap{"token": plainToken})
+		if response.StatusCode != http.StatusUnauthorized {
+			t.Errorf("Expected status code 401, got %d", response.StatusCode)
+		}
+	}))
+}
+
+func beforeEachMagicLink() {
+	var userIDs []uint
+	dbInstance.Model(&models.User{}).Where("email LIKE ?", "%@magic-link.test").Pluck("id", &userIDs)
+	dbInstance.Where("user_id IN ?", userIDs).Delete(&models.MagicLinkToken{})
+	dbInstance.Where("email LIKE ?", "%@magic-link.test").Delete(&models.User{})
+	dbInstance.Where("email LIKE ?", "%@magic-link.test").Delete(&models.MagicLinkRateLimit{})
+}
+
+func testCaseMagicLink(test func(t *testing.T)) func(*testing.T) {
+	return func(t *testing.T) {
+		beforeEachMagicLink()
+		defer beforeEachMagicLink()
+		test(t)
+	}
+}
@@ -0,0 +1,155 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/filipio/athletics-backend/internal/models"
+	"github.com/filipio/athletics-backend/pkg/httpio"
+)
+
+func TestUserSuspension(t *testing.T) {
+	t.Run("suspended user is logged out and can't log in until unsuspended", testCaseModeration(func(t *testing.T) {
+		user := createUser("suspended@moderation.test", "suspended", "password123", httpio.UserRole)
+		session := loginAs("suspended@moderation.test", "password123")
+		accessToken := session["access_token"].(string)
+
+		until := time.Now().Add(24 * time.Hour)
+		response, suspendedUser, err := Post[models.UserResponse](fmt.Sprintf("/api/v1/users/%d/suspend", user.ID), httpio.AnyMap{
+			"reason": "spamming",
+			"until":  until,
+		})
+		if err != nil {
+			t.Fatalf("Error executing request: %s", err.Error())
+		}
+		if response.StatusCode != http.StatusOK {
+			t.Fatalf("Expected status code 200, got %d", response.StatusCode)
+		}
+		if !suspendedUser.Suspended || suspendedUser.SuspendedUntil == nil {
+			t.Errorf("Expected user to be suspended until %s", until)
+		}
+
+		response, _, _ = executeHttpWithToken[map[string]any]("GET", "/api/v1/users/me", nil, accessToken)
+		if response.StatusCode != http.StatusForbidden {
+			t.Errorf("Expected status code 403 for suspended user, got %d", response.StatusCode)
+		}
+
+		response, _, _ = Post[map[string]any]("/api/v1/auth/refresh", httpio.AnyMap{"refresh_token": session["refresh_token"]})
+		if response.StatusCode != http.StatusUnauthorized {
+			t.Errorf("Expected revoked refresh token to be rejected, got %d", response.StatusCode)
+		}
+
+		response, _, _ = Post[map[string]any]("/api/v1/login", httpio.AnyMap{"email": "suspended@moderation.test", "password": "password123"})
+		if response.StatusCode != http.StatusForbidden {
+			t.Errorf("Expected status code 403 on login, got %d", response.StatusCode)
+		}
+
+		response, _, _ = Post[models.UserResponse](fmt.Sprintf("/api/v1/users/%d/unsuspend", user.ID), httpio.AnyMap{"reason": "appeal accepted"})
+		if response.StatusCode != http.StatusOK {
+			t.Fatalf("Expected status code 200 on unsuspend, got %d", response.StatusCode)
+		}
+
+		response, _, _ = Post[map[string]any]("/api/v1/login", httpio.AnyMap{"email": "suspended@moderation.test", "password": "password123"})
+		if response.StatusCode != http.StatusOK {
+			t.Errorf("Expected status code 200 on login after unsuspend, got %d", response.StatusCode)
+		}
+
+		response, actions, _ := Get[httpio.PaginatedResponse](fmt.Sprintf("/api/v1/users/%d/moderation-actions", user.ID))
+		if response.StatusCode != http.StatusOK {
+			t.Fatalf("Expected status code 200 on moderation actions, got %d", response.StatusCode)
+		}
+		data := actions.Data.([]any)
+		if len(data) != 2 {
+			t.Fatalf("Expected 2 moderation actions, got %d", len(data))
+		}
+		firstAction := data[0].(map[string]any)
+		if firstAction["action"] != models.ModerationActionSuspend || firstAction["reason"] != "spamming" || firstAction["actor_id"] == nil {
+			t.Errorf("Expected suspension recorded with actor and reason, got %v", firstAction)
+		}
+	}))
+
+	t.Run("reason is required", testCaseModeration(func(t *testing.T) {
+		user := createUser("noreason@moderation.test", "noreason", "password123", httpio.UserRole)
+
+		response, _, _ := Post[map[string]any](fmt.Sprintf("/api/v1/users/%d/suspend", user.ID), httpio.AnyMap{})
+		if response.StatusCode != http.StatusBadRequest {
+			t.Errorf("Expected status code 400, got %d", response.StatusCode)
+		}
+	}))
+
+	t.Run("admin can't suspend themselves", testCaseModeration(func(t *testing.T) {
+		var admin models.User
+		dbInstance.Where("email = ?", adminEmail).First(&admin)
+
+		response, _, _ := Post[map[string]any](fmt.Sprintf("/api/v1/users/%d/suspend", admin.ID), httpio.AnyMap{"reason": "testing"})
+		if response.StatusCode != http.StatusBadRequest {
+			t.Errorf("Expected status code 400, got %d", response.StatusCode)
+		}
+	}))
+}
+
+func TestUserModeration(t *testing.T) {
+	t.Run("offensive username is reset and previous one is recorded", testCaseModeration(func(t *testing.T) {
+		user := createUser("offensive@moderation.test", "offensive_name", "password123", httpio.UserRole)
+
+		response, updatedUser, _ := Post[models.UserResponse](fmt.Sprintf("/api/v1/users/%d/username/reset", user.ID), httpio.AnyMap{"reason": "offensive"})
+		if response.StatusCode != http.StatusOK {
+			t.Fatalf("Expected status code 200, got %d", response.StatusCode)
+		}
+		if updatedUser.Username != fmt.Sprintf("user_%d", user.ID) {
+			t.Errorf("Expected generated username, got %s", updatedUser.Username)
+		}
+
+		var action models.ModerationAction
+		dbInstance.Where("target_user_id = ?", user.ID).First(&action)
+		if action.Action != models.ModerationActionResetUsername || action.Details != "offensive_name" {
+			t.Errorf("Expected previous username to be recorded, got %+v", action)
+		}
+	}))
+
+	t.Run("role is granted and revoked", testCaseModeration(func(t *testing.T) {
+		user := createUser("promoted@moderation.test", "promoted", "password123", httpio.UserRole)
+		path := fmt.Sprintf("/api/v1/users/%d/roles/%s", user.ID, httpio.OrganizerRole)
+
+		response, promotedUser, _ := Post[models.UserResponse](path, httpio.AnyMap{"reason": "runs the event"})
+		if response.StatusCode != http.StatusOK {
+			t.Fatalf("Expected status code 200, got %d", response.StatusCode)
+		}
+		if len(promotedUser.Roles) != 2 {
+			t.Errorf("Expected 2 roles, got %v", promotedUser.Roles)
+		}
+
+		response, _, _ = Post[map[string]any](path, httpio.AnyMap{"reason": "runs the event"})
+		if response.StatusCode != http.StatusBadRequest {
+			t.Errorf("Expected status code 400 when granted twice, got %d", response.StatusCode)
+		}
+
+		response, demotedUser, _ := executeHttpWithToken[models.UserResponse]("DELETE", path, httpio.AnyMap{"reason": "event finished"}, adminToken)
+		if response.StatusCode != http.StatusOK {
+			t.Fatalf("Expected status code 200, got %d", response.StatusCode)
+		}
+		if len(demotedUser.Roles) != 1 {
+			t.Errorf("Expected 1 role, got %v", demotedUser.Roles)
+		}
+
+		var actionsCount int64
+		dbInstance.Model(&models.ModerationAction{}).Where("target_user_id = ?", user.ID).Count(&actionsCount)
+		if actionsCount != 2 {
+			t.Errorf("Expected 2 moderation actions, got %d", actionsCount)
+		}
+	}))
+}
+
+func beforeEachModeration() {
+	dbInstance.Where("email LIKE ?", "%@moderation.test").Delete(&models.User{})
+}
+
+func testCaseModeration(test func(t *testing.T)) func(*testing.T) {
+	return func(t *testing.T) {
+		beforeEachModeration()
+		defer beforeEachModeration()
+		test(t)
+	}
+}
@@ -0,0 +1,135 @@
+package controllers
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/filipio/athletics-backend/internal/controllers"
+	"github.com/filipio/athletics-backend/internal/models"
+	"github.com/filipio/athletics-backend/pkg/httpio"
+)
+
+func TestOidcLogin(t *testing.T) {
+	t.Run("creates user on first login and signs in the same user later", testCaseOidc(func(t *testing.T) {
+		response, tokenPair := oidcLogin(t, "subject-new", "new@oidc.test", true)
+		if response.StatusCode != http.StatusOK {
+			t.Fatalf("Expected status code 200, got %d", response.StatusCode)
+		}
+
+		var user models.User
+		dbInstance.Preload("Roles").Where("email = ?", "new@oidc.test").First(&user)
+		if user.ID == 0 || !user.HasAnyRole(httpio.UserRole) {
+			t.Fatalf("Expected user with role '%s' to be created", httpio.UserRole)
+		}
+
+		response, _, _ = executeHttpWithToken[map[string]any]("GET", "/api/v1/users/me", nil, tokenPair["access_token"].(string))
+		if response.StatusCode != http.StatusOK {
+			t.Errorf("Expected access token to be valid, got status %d", response.StatusCode)
+		}
+
+		// provider subject stays the same even if the email changes at the provider
+		response, _ = oidcLogin(t, "subject-new", "renamed@oidc.test", true)
+		if response.StatusCode != http.StatusOK {
+			t.Fatalf("Expected status code 200, got %d", response.StatusCode)
+		}
+
+		var usersCount int64
+		dbInstance.Model(&models.User{}).Where("email LIKE ?", "%@oidc.test").Count(&usersCount)
+		if usersCount != 1 {
+			t.Errorf("Expected 1 user, got %d", usersCount)
+		}
+	}))
+
+	t.Run("links existing user by verified email", testCaseOidc(func(t *testing.T) {
+		user := createUser("existing@oidc.test", "existing", "password123", httpio.UserRole)
+
+		// emails are compared case-insensitively, providers may return them in another case
+		response, _ := oidcLogin(t, "subject-existing", "Existing@oidc.test", true)
+		if response.StatusCode != http.StatusOK {
+			t.Fatalf("Expected status code 200, got %d", response.StatusCode)
+		}
+
+		var identity models.UserIdentity
+		dbInstance.Where("provider = ? AND subject = ?", fakeOidcProviderName, "subject-existing").First(&identity)
+		if identity.UserID != user.ID {
+			t.Errorf("Expected identity to be linked to user %d, got %d", user.ID, identity.UserID)
+		}
+	}))
+
+	t.Run("rejects unverified email", testCaseOidc(func(t *testing.T) {
+		createUser("victim@oidc.test", "victim", "password123", httpio.UserRole)
+
+		response, _ := oidcLogin(t, "subject-attacker", "victim@oidc.test", false)
+		if response.StatusCode != http.StatusUnauthorized {
+			t.Errorf("Expected status code 401, got %d", response.StatusCode)
+		}
+
+		var identitiesCount int64
+		dbInstance.Model(&models.UserIdentity{}).Where("subject = ?", "subject-attacker").Count(&identitiesCount)
+		if identitiesCount != 0 {
+			t.Errorf("Expected no identity to be linked, got %d", identitiesCount)
+		}
+	}))
+
+	t.Run("state can be used only once", testCaseOidc(func(t *testing.T) {
+		_, authorization, _ := Post[controllers.OidcAuthorizationResponse]("/api/v1/auth/oidc/"+fakeOidcProviderName+"/authorize", nil)
+		code, state := fakeOidc.authorize(authorization.AuthorizationURL, "subject-replay", "replay@oidc.test", true)
+
+		payload := httpio.AnyMap{"code": code, "state": state}
+		response, _, _ := Post[map[string]any]("/api/v1/auth/oidc/"+fakeOidcProviderName+"/callback", payload)
+		if response.StatusCode != http.StatusOK {
+			t.Fatalf("Expected status code 200, got %d", response.StatusCode)
+		}
+
+		response, _, _ = Post[map[string]any]("/api/v1/auth/oidc/"+fakeOidcProviderName+"/callback", payload)
+		if response.StatusCode != http.StatusBadRequest {
+			t.Errorf("Expected status code 400, got %d", response.StatusCode)
+		}
+	}))
+
+	t.Run("unknown provider returns 404", testCaseOidc(func(t *testing.T) {
+		response, _, err := Post[httpio.ErrorsResponse]("/api/v1/auth/oidc/unknown/authorize", nil)
+		if err != nil {
+			t.Fatalf("Error executing request: %s", err.Error())
+		}
+
+		if response.StatusCode != http.StatusNotFound {
+			t.Errorf("Expected status code 404, got %d", response.StatusCode)
+		}
+	}))
+}
+
+// oidcLogin goes through the whole authorization code flow with the fake provider
+func oidcLogin(t *testing.T, subject string, email string, emailVerified bool) (*http.Response, map[string]any) {
+	response, authorization, err := Post[controllers.OidcAuthorizationResponse]("/api/v1/auth/oidc/"+fakeOidcProviderName+"/authorize", nil)
+	if err != nil {
+		t.Fatalf("Error executing request: %s", err.Error())
+	}
+	if response.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status code 200, got %d", response.StatusCode)
+	}
+
+	code, state := fakeOidc.authorize(authorization.AuthorizationURL, subject, email, emailVerified)
+
+	response, tokenPair, err := Post[map[string]any]("/api/v1/auth/oidc/"+fakeOidcProviderName+"/callback", httpio.AnyMap{
+		"code":  code,
+		"state": state,
+	})
+	if err != nil {
+		t.Fatalf("Error executing request: %s", err.Error())
+	}
+
+	return response, *tokenPair
+}
+
+func beforeEachOidc() {
+	dbInstance.Where("provider = ?", fakeOidcProviderName).Delete(&models.UserIdentity{})
+	dbInstance.Where("email LIKE ?", "%@oidc.test").Delete(&models.User{})
+}
+
+func testCaseOidc(test func(t *testing.T)) func(*testing.T) {
+	return func(t *testing.T) {
+		beforeEachOidc()
+		test(t)
+	}
+}
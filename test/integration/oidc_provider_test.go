@@ -0,0 +1,142 @@
+package controllers
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const fakeOidcProviderName = "stub"
+const fakeOidcClientID = "athletics-test-client"
+const fakeOidcKeyID = "stub-key"
+
+// fakeOidcProvider is a local stand-in for OpenID Connect provider (discovery, jwks and token endpoints)
+type fakeOidcProvider struct {
+	server *httptest.Server
+	key    *rsa.PrivateKey
+	lock   sync.Mutex
+	grants map[string]fakeOidcGrant
+}
+
+type fakeOidcGrant struct {
+	claims        jwt.MapClaims
+	codeChallenge string
+}
+
+var fakeOidc *fakeOidcProvider
+
+// startFakeOidcProvider has to be called before the server starts, as providers are configured from env variables
+func startFakeOidcProvider() {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		panic(err)
+	}
+
+	fakeOidc = &fakeOidcProvider{key: key, grants: map[string]fakeOidcGrant{}}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /.well-known/openid-configuration", fakeOidc.discovery)
+	mux.HandleFunc("GET /jwks", fakeOidc.jwks)
+	mux.HandleFunc("POST /token", fakeOidc.token)
+	fakeOidc.server = httptest.NewServer(mux)
+
+	os.Setenv("OIDC_PROVIDERS", fakeOidcProviderName)
+	os.Setenv("OIDC_STUB_ISSUER", fakeOidc.server.URL)
+	os.Setenv("OIDC_STUB_CLIENT_ID", fakeOidcClientID)
+	os.Setenv("OIDC_STUB_CLIENT_SECRET", "athletics-test-secret")
+	os.Setenv("OIDC_STUB_REDIRECT_URL", "http://localhost/oidc/callback")
+}
+
+// authorize simulates the user signing in at the provider, returns code and state which are passed to the callback
+func (p *fakeOidcProvider) authorize(authorizationURL string, subject string, email string, emailVerified bool) (string, string) {
+	parsedURL, err := url.Parse(authorizationURL)
+	if err != nil {
+		panic(err)
+	}
+	query := parsedURL.Query()
+
+	code := rand.Text()
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.grants[code] = fakeOidcGrant{
+		claims: jwt.MapClaims{
+			"iss":            p.server.URL,
+			"aud":            query.Get("client_id"),
+			"sub":            subject,
+			"email":          email,
+			"email_verified": emailVerified,
+			"nonce":          query.Get("nonce"),
+			"iat":            time.Now().Unix(),
+			"exp":            time.Now().Add(time.Hour).Unix(),
+		},
+		codeChallenge: query.Get("code_challenge"),
+	}
+
+	return code, query.Get("state")
+}
+
+func (p *fakeOidcProvider) discovery(w http.ResponseWriter, r *http.Request) {
+	json.NewEncoder(w).Encode(map[string]any{
+		"issuer":                                p.server.URL,
+		"authorization_endpoint":                p.server.URL + "/authorize",
+		"token_endpoint":                        p.server.URL + "/token",
+		"jwks_uri":                              p.server.URL + "/jwks",
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+		"code_challenge_methods_supported":      []string{"S256"},
+	})
+}
+
+func (p *fakeOidcProvider) jwks(w http.ResponseWriter, r *http.Request) {
+	json.NewEncoder(w).Encode(map[string]any{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": fakeOidcKeyID,
+			"alg": "RS256",
+			"use": "sig",
+			"n":   base64.RawURLEncoding.EncodeToString(p.key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(p.key.E)).Bytes()),
+		}},
+	})
+}
+
+func (p *fakeOidcProvider) token(w http.ResponseWriter, r *http.Request) {
+	p.lock.Lock()
+	grant, ok := p.grants[r.FormValue("code")]
+	delete(p.grants, r.FormValue("code"))
+	p.lock.Unlock()
+
+	verifierHash := sha256.Sum256([]byte(r.FormValue("code_verifier")))
+	if !ok || base64.RawURLEncoding.EncodeToString(verifierHash[:]) != grant.codeChallenge {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	idToken := jwt.NewWithClaims(jwt.SigningMethodRS256, grant.claims)
+	idToken.Header["kid"] = fakeOidcKeyID
+	signedIDToken, err := idToken.SignedString(p.key)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"access_token": rand.Text(),
+		"token_type":   "Bearer",
+		"expires_in":   3600,
+		"id_token":     signedIDToken,
+	})
+}
@@ -0,0 +1,73 @@
+package controllers
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/filipio/athletics-backend/internal/models"
+	"github.com/filipio/athletics-backend/pkg/httpio"
+)
+
+func TestPasswordReset(t *testing.T) {
+	t.Run("request for unknown email does not reveal account existence", testCasePasswordReset(func(t *testing.T) {
+		response, result, err := Post[map[string]any]("/api/v1/auth/password-reset/request", httpio.AnyMap{
+			"email": "nobody@password-reset.test",
+		})
+		if err != nil {
+			t.Fatalf("Error executing request: %s", err.Error())
+		}
+
+		if response.StatusCode != http.StatusOK {
+			t.Fatalf("Expected status code 200, got %d", response.StatusCode)
+		}
+
+		if (*result)["message"] == nil {
+			t.Error("Expected message in response")
+		}
+	}))
+
+	t.Run("confirm sets new password, revokes sessions and can't be reused", testCasePasswordReset(func(t *testing.T) {
+		user := createUser("reset@password-reset.test", "reset", "oldpassword", httpio.UserRole)
+		tokens := loginAs("reset@password-reset.test", "oldpassword")
+
+		resetToken := models.PasswordResetToken{UserID: user.ID}
+		plainToken := resetToken.GenerateToken()
+		dbInstance.Create(&resetToken)
+
+		confirmPayload := httpio.AnyMap{"token": plainToken, "password": "newpassword"}
+		response, _, err := Post[map[string]any]("/api/v1/auth/password-reset/confirm", confirmPayload)
+		if err != nil {
+			t.Fatalf("Error executing request: %s", err.Error())
+		}
+
+		if response.StatusCode != http.StatusOK {
+			t.Fatalf("Expected status code 200, got %d", response.StatusCode)
+		}
+
+		response, _, _ = Post[map[string]any]("/api/v1/auth/refresh", httpio.AnyMap{"refresh_token": tokens["refresh_token"]})
+		if response.StatusCode != http.StatusUnauthorized {
+			t.Errorf("Expected refresh token to be revoked, got status %d", response.StatusCode)
+		}
+
+		if newTokens := loginAs("reset@password-reset.test", "newpassword"); newTokens["access_token"] == nil {
+			t.Error("Expected login with new password to succeed")
+		}
+
+		response, _, _ = Post[map[string]any]("/api/v1/auth/password-reset/confirm", confirmPayload)
+		if response.StatusCode != http.StatusBadRequest {
+			t.Errorf("Expected reused token to be rejected with 400, got %d", response.StatusCode)
+		}
+	}))
+}
+
+func beforeEachPasswordReset() {
+	dbInstance.Where("email LIKE ?", "%@password-reset.test").Delete(&models.User{})
+	dbInstance.Where("email LIKE ?", "%@password-reset.test").Delete(&models.PasswordResetRateLimit{})
+}
+
+func testCasePasswordReset(test func(t *testing.T)) func(*testing.T) {
+	return func(t *testing.T) {
+		beforeEachPasswordReset()
+		test(t)
+	}
+}
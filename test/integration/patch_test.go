@@ -0,0 +1,220 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/filipio/athletics-backend/internal/models"
+	"github.com/filipio/athletics-backend/pkg/httpio"
+)
+
+func TestPatch(t *testing.T) {
+	t.Run("only sent fields change and null clears the field", testCasePatch(func(t *testing.T) {
+		description := "Final of the championship"
+		event := &models.Event{Name: "Patch Event", Description: &description, Deadline: time.Now().Add(24 * time.Hour), Status: "published"}
+		dbInstance.Save(event)
+
+		response, patched, err := Patch[models.Event](fmt.Sprintf("/api/v1/events/%d", event.ID), httpio.AnyMap{
+			"description": nil,
+		})
+		if err != nil {
+			t.Fatalf("Error executing request: %s", err.Error())
+		}
+		if response.StatusCode != http.StatusOK {
+			t.Fatalf("Expected status code 200, got %d", response.StatusCode)
+		}
+		if patched.Description != nil {
+			t.Errorf("Expected description to be cleared, got %q", *patched.Description)
+		}
+		if patched.Name != "Patch Event" || patched.Status != "published" {
+			t.Errorf("Expected other fields to be kept, got %s %s", patched.Name, patched.Status)
+		}
+	}))
+
+	t.Run("validation runs on the merged record", testCasePatch(func(t *testing.T) {
+		event := &models.Event{Name: "Patch Event", Deadline: time.Now().Add(24 * time.Hour), Status: "published"}
+		dbInstance.Save(event)
+		question := &models.Question{EventID: event.ID, Content: "Who wins?", Type: "country", Points: 2}
+		dbInstance.Save(question)
+		questionPath := fmt.Sprintf("/api/v1/questions/%d", question.ID)
+
+		response, _, _ := Patch[map[string]any](questionPath, httpio.AnyMap{"points": 0})
+		if response.StatusCode != http.StatusBadRequest {
+			t.Errorf("Expected status code 400 for points below minimum, got %d", response.StatusCode)
+		}
+
+		response, _, _ = Patch[map[string]any](questionPath, httpio.AnyMap{"correct_answer": httpio.AnyMap{"athlete_id": 1}})
+		if response.StatusCode != http.StatusBadRequest {
+			t.Errorf("Expected status code 400 for answer not matching the question type, got %d", response.StatusCode)
+		}
+
+		var stored models.Question
+		dbInstance.First(&stored, question.ID)
+		if stored.Points != 2 || stored.CorrectAnswer != nil {
+			t.Errorf("Expected question to be kept after rejected patches")
+		}
+	}))
+
+	t.Run("correct answer is set and unset", testCasePatch(func(t *testing.T) {
+		event := &models.Event{Name: "Patch Event", Deadline: time.Now().Add(24 * time.Hour), Status: "published"}
+		dbInstance.Save(event)
+		question := &models.Question{EventID: event.ID, Content: "Who wins?", Type: "country", Points: 2}
+		dbInstance.Save(question)
+		questionPath := fmt.Sprintf("/api/v1/questions/%d", question.ID)
+
+		response, patched, _ := Patch[models.Question](questionPath, httpio.AnyMap{"correct_answer": httpio.AnyMap{"country": "KEN"}})
+		if response.StatusCode != http.StatusOK || patched.CorrectAnswer == nil {
+			t.Fatalf("Expected correct answer to be set, got %d", response.StatusCode)
+		}
+		if patched.Content != "Who wins?" || patched.Points != 2 {
+			t.Errorf("Expected other fields to be kept, got %s %d", patched.Content, patched.Points)
+		}
+
+		response, patched, _ = Patch[models.Question](questionPath, httpio.AnyMap{"correct_answer": nil})
+		if response.StatusCode != http.StatusOK || patched.CorrectAnswer != nil {
+			t.Errorf("Expected correct answer to be unset, got %d", response.StatusCode)
+		}
+	}))
+
+	t.Run("patch of missing record is not found", testCasePatch(func(t *testing.T) {
+		response, _, _ := Patch[map[string]any]("/api/v1/events/0", httpio.AnyMap{"name": "Patch Event"})
+		if response.StatusCode != http.StatusNotFound {
+			t.Errorf("Expected status code 404, got %d", response.StatusCode)
+		}
+	}))
+}
+
+func TestPatchUsersRolesAnswers(t *testing.T) {
+	t.Run("user is patched and the password is kept", testCasePatch(func(t *testing.T) {
+		user := createUser("kept@patch.test", "patch_kept", "password123", httpio.UserRole)
+
+		response, patched, _ := Patch[models.UserResponse](fmt.Sprintf("/api/v1/users/%d", user.ID), httpio.AnyMap{
+			"username": "patch_renamed",
+		})
+		if response.StatusCode != http.StatusOK {
+			t.Fatalf("Expected status code 200, got %d", response.StatusCode)
+		}
+		if patched.Username != "patch_renamed" || patched.Email != "kept@patch.test" {
+			t.Errorf("Expected only username to change, got %s %s", patched.Username, patched.Email)
+		}
+
+		response, _, _ = Post[map[string]any]("/api/v1/login", httpio.AnyMap{"email": "kept@patch.test", "password": "password123"})
+		if response.StatusCode != http.StatusOK {
+			t.Errorf("Expected password to be kept, got status %d on login", response.StatusCode)
+		}
+	}))
+
+	t.Run("patched password is hashed and revokes sessions", testCasePatch(func(t *testing.T) {
+		user := createUser("password@patch.test", "patch_password", "password123", httpio.UserRole)
+		accessToken := loginAs("password@patch.test", "password123")["access_token"].(string)
+
+		response, _, _ := Patch[models.UserResponse](fmt.Sprintf("/api/v1/users/%d", user.ID), httpio.AnyMap{
+			"password": "newpassword",
+		})
+		if response.StatusCode != http.StatusOK {
+			t.Fatalf("Expected status code 200, got %d", response.StatusCode)
+		}
+
+		response, _, _ = executeHttpWithToken[map[string]any]("GET", "/api/v1/users/me", nil, accessToken)
+		if response.StatusCode != http.StatusUnauthorized {
+			t.Errorf("Expected session to be revoked, got status %d", response.StatusCode)
+		}
+
+		response, _, _ = Post[map[string]any]("/api/v1/login", httpio.AnyMap{"email": "password@patch.test", "password": "newpassword"})
+		if response.StatusCode != http.StatusOK {
+			t.Errorf("Expected login with new password, got status %d", response.StatusCode)
+		}
+	}))
+
+	t.Run("role is patched and built-in roles can't be renamed", testCasePatch(func(t *testing.T) {
+		_, role, _ := Post[models.RoleResponse]("/api/v1/roles", httpio.AnyMap{
+			"name":        "patch_role",
+			"permissions": []string{httpio.EventsWritePermission},
+		})
+
+		response, patched, _ := Patch[models.RoleResponse](fmt.Sprintf("/api/v1/roles/%d", role.ID), httpio.AnyMap{
+			"requires_two_factor": true,
+		})
+		if response.StatusCode != http.StatusOK {
+			t.Fatalf("Expected status code 200, got %d", response.StatusCode)
+		}
+		if !patched.RequiresTwoFactor || patched.Name != "patch_role" || len(patched.Permissions) != 1 {
+			t.Errorf("Expected only two factor requirement to change, got %+v", *patched)
+		}
+
+		var organizerRole models.Role
+		dbInstance.Where("name = ?", httpio.OrganizerRole).First(&organizerRole)
+		response, _, _ = Patch[map[string]any](fmt.Sprintf("/api/v1/roles/%d", organizerRole.ID), httpio.AnyMap{"name": "organizers"})
+		if response.StatusCode != http.StatusBadRequest {
+			t.Errorf("Expected status code 400 on rename, got %d", response.StatusCode)
+		}
+	}))
+
+	t.Run("answer is patched only by its owner", testCasePatch(func(t *testing.T) {
+		owner := createUser("owner@patch.test", "patch_owner", "password123", httpio.UserRole)
+		other := createUser("other@patch.test", "patch_other", "password123", httpio.UserRole)
+		event := &models.Event{Name: "Patch Event", Deadline: time.Now().Add(24 * time.Hour), Status: "published"}
+		dbInstance.Save(event)
+		question := &models.Question{EventID: event.ID, Content: "Who wins?", Type: "country", Points: 2}
+		dbInstance.Save(question)
+		answer := &models.Answer{
+			UserID:     owner.ID,
+			QuestionID: question.ID,
+			Content:    models.AnswerOfQuestion{JSON: []byte(`{"country": "KEN"}`)},
+		}
+		dbInstance.Save(answer)
+		answerPath := fmt.Sprintf("/api/v1/users/me/answers/%d", answer.ID)
+
+		otherToken := loginAs("other@patch.test", "password123")["access_token"].(string)
+		response, _, _ := executeHttpWithToken[map[string]any]("PATCH", answerPath, httpio.AnyMap{
+			"content": httpio.AnyMap{"country": "ETH"},
+		}, otherToken)
+		if response.StatusCode != http.StatusNotFound {
+			t.Errorf("Expected status code 404 for answer of another user, got %d", response.StatusCode)
+		}
+
+		ownerToken := loginAs("owner@patch.test", "password123")["access_token"].(string)
+		response, _, _ = executeHttpWithToken[map[string]any]("PATCH", answerPath, httpio.AnyMap{
+			"user_id": other.ID,
+		}, ownerToken)
+		if response.StatusCode != http.StatusUnauthorized {
+			t.Errorf("Expected status code 401 when moving the answer to another user, got %d", response.StatusCode)
+		}
+
+		response, _, _ = executeHttpWithToken[map[string]any]("PATCH", answerPath, httpio.AnyMap{
+			"content": httpio.AnyMap{"country": "ETH"},
+		}, ownerToken)
+		if response.StatusCode != http.StatusOK {
+			t.Fatalf("Expected status code 200, got %d", response.StatusCode)
+		}
+
+		var stored models.Answer
+		dbInstance.First(&stored, answer.ID)
+		if stored.UserID != owner.ID || string(stored.Content.JSON) == `{"country": "KEN"}` {
+			t.Errorf("Expected content to change and owner to be kept, got %d %s", stored.UserID, stored.Content.JSON)
+		}
+	}))
+
+	t.Run("pokemon patch requires authentication", testCasePatch(func(t *testing.T) {
+		response, _, _ := executeHttpWithToken[map[string]any]("PATCH", "/api/v1/pokemons/1", httpio.AnyMap{"name": "Pikachu"}, "")
+		if response.StatusCode != http.StatusUnauthorized {
+			t.Errorf("Expected status code 401, got %d", response.StatusCode)
+		}
+	}))
+}
+
+func beforeEachPatch() {
+	dbInstance.Unscoped().Where("name = ?", "Patch Event").Delete(&models.Event{})
+	dbInstance.Where("email LIKE ?", "%@patch.test").Delete(&models.User{})
+	dbInstance.Where("name = ?", "patch_role").Delete(&models.Role{})
+}
+
+func testCasePatch(test func(t *testing.T)) func(*testing.T) {
+	return func(t *testing.T) {
+		beforeEachPatch()
+		defer beforeEachPatch()
+		test(t)
+	}
+}
@@ -0,0 +1,205 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/filipio/athletics-backend/internal/models"
+	"github.com/filipio/athletics-backend/pkg/httpio"
+)
+
+func TestRolePermissions(t *testing.T) {
+	t.Run("custom role grants only its permissions", testCasePermissions(func(t *testing.T) {
+		response, role, err := Post[models.RoleResponse]("/api/v1/roles", httpio.AnyMap{
+			"name":        "event_editor",
+			"permissions": []string{httpio.EventsWritePermission},
+		})
+		if err != nil {
+			t.Fatalf("Error executing request: %s", err.Error())
+		}
+		if response.StatusCode != http.StatusCreated {
+			t.Fatalf("Expected status code 201, got %d", response.StatusCode)
+		}
+
+		user := createUser("editor@permissions.test", "editor", "password123", httpio.UserRole)
+		session := loginAs("editor@permissions.test", "password123")
+		accessToken := session["access_token"].(string)
+		eventPayload := httpio.AnyMap{"name": "Permissions Event", "deadline": time.Now().Add(24 * time.Hour)}
+
+		response, _, _ = executeHttpWithToken[map[string]any]("POST", "/api/v1/events", eventPayload, accessToken)
+		if response.StatusCode != http.StatusUnauthorized {
+			t.Fatalf("Expected status code 401 without permission, got %d", response.StatusCode)
+		}
+
+		response, assignedUser, _ := Put[models.UserResponse](fmt.Sprintf("/api/v1/users/%d/roles", user.ID), httpio.AnyMap{
+			"roles":  []string{httpio.UserRole, role.Name},
+			"reason": "edits event descriptions",
+		})
+		if response.StatusCode != http.StatusOK {
+			t.Fatalf("Expected status code 200 on roles assignment, got %d", response.StatusCode)
+		}
+		if !slices.Equal(assignedUser.Permissions, []string{httpio.EventsWritePermission}) {
+			t.Errorf("Expected permissions %v, got %v", []string{httpio.EventsWritePermission}, assignedUser.Permissions)
+		}
+
+		// permissions are checked live, the same access token can be used
+		response, _, _ = executeHttpWithToken[map[string]any]("POST", "/api/v1/events", eventPayload, accessToken)
+		if response.StatusCode != http.StatusOK {
+			t.Errorf("Expected status code 200 with permission, got %d", response.StatusCode)
+		}
+
+		response, _, _ = executeHttpWithToken[map[string]any]("GET", "/api/v1/users", nil, accessToken)
+		if response.StatusCode != http.StatusUnauthorized {
+			t.Errorf("Expected status code 401 for users management, got %d", response.StatusCode)
+		}
+	}))
+
+	t.Run("setting correct answer requires questions:grade permission", testCasePermissions(func(t *testing.T) {
+		Post[models.RoleResponse]("/api/v1/roles", httpio.AnyMap{
+			"name":        "question_writer",
+			"permissions": []string{httpio.QuestionsWritePermission},
+		})
+		user := createUser("writer@permissions.test", "writer", "password123", httpio.UserRole)
+		Put[models.UserResponse](fmt.Sprintf("/api/v1/users/%d/roles", user.ID), httpio.AnyMap{
+			"roles":  []string{"question_writer"},
+			"reason": "writes questions",
+		})
+		accessToken := loginAs("writer@permissions.test", "password123")["access_token"].(string)
+
+		event := &models.Event{Name: "Permissions Event", Deadline: time.Now().Add(24 * time.Hour), Status: "published"}
+		dbInstance.Create(event)
+		question := &models.Question{EventID: event.ID, Content: "Which country wins?", Type: "country", Points: 1}
+		dbInstance.Create(question)
+
+		questionPayload := httpio.AnyMap{"event_id": event.ID, "content": "Which country wins now?", "type": "country", "points": 1}
+		path := fmt.Sprintf("/api/v1/questions/%d", question.ID)
+
+		response, _, _ := executeHttpWithToken[map[string]any]("PUT", path, questionPayload, accessToken)
+		if response.StatusCode != http.StatusOK {
+			t.Fatalf("Expected status code 200 on content update, got %d", response.StatusCode)
+		}
+
+		questionPayload["correct_answer"] = httpio.AnyMap{"country": "POL"}
+		response, _, _ = executeHttpWithToken[map[string]any]("PUT", path, questionPayload, accessToken)
+		if response.StatusCode != http.StatusUnauthorized {
+			t.Errorf("Expected status code 401 on grading, got %d", response.StatusCode)
+		}
+	}))
+
+	t.Run("roles can't be assigned with users:manage permission only", testCasePermissions(func(t *testing.T) {
+		Post[models.RoleResponse]("/api/v1/roles", httpio.AnyMap{
+			"name":        "user_manager",
+			"permissions": []string{httpio.UsersManagePermission},
+		})
+		manager := createUser("manager@permissions.test", "manager", "password123", httpio.UserRole)
+		Put[models.UserResponse](fmt.Sprintf("/api/v1/users/%d/roles", manager.ID), httpio.AnyMap{
+			"roles":  []string{"user_manager"},
+			"reason": "manages users",
+		})
+		accessToken := loginAs("manager@permissions.test", "password123")["access_token"].(string)
+
+		var adminRole models.Role
+		dbInstance.Where("name = ?", httpio.AdminRole).First(&adminRole)
+		userPayload := httpio.AnyMap{
+			"email":    "created@permissions.test",
+			"username": "created",
+			"password": "password123",
+			"roles":    []httpio.AnyMap{{"id": adminRole.ID, "name": httpio.AdminRole}},
+		}
+
+		response, createdUser, _ := executeHttpWithToken[models.UserResponse]("POST", "/api/v1/users", userPayload, accessToken)
+		if response.StatusCode != http.StatusOK {
+			t.Fatalf("Expected status code 200 on create, got %d", response.StatusCode)
+		}
+
+		response, _, _ = executeHttpWithToken[models.UserResponse]("PUT", fmt.Sprintf("/api/v1/users/%d", manager.ID), httpio.AnyMap{
+			"email":    "manager@permissions.test",
+			"username": "manager",
+			"password": "password123",
+			"roles":    []httpio.AnyMap{{"id": adminRole.ID, "name": httpio.AdminRole}},
+		}, accessToken)
+		if response.StatusCode != http.StatusOK {
+			t.Fatalf("Expected status code 200 on update, got %d", response.StatusCode)
+		}
+
+		for _, userID := range []uint{createdUser.ID, manager.ID} {
+			var user models.User
+			dbInstance.Preload("Roles").First(&user, userID)
+			if user.HasAnyRole(httpio.AdminRole) {
+				t.Errorf("Expected user %d not to be granted admin role", userID)
+			}
+		}
+	}))
+
+	t.Run("unknown permissions are rejected", testCasePermissions(func(t *testing.T) {
+		response, _, _ := Post[map[string]any]("/api/v1/roles", httpio.AnyMap{
+			"name":        "superuser",
+			"permissions": []string{"everything:write"},
+		})
+		if response.StatusCode != http.StatusBadRequest {
+			t.Errorf("Expected status code 400, got %d", response.StatusCode)
+		}
+	}))
+
+	t.Run("built-in roles can't be deleted or renamed", testCasePermissions(func(t *testing.T) {
+		var organizerRole models.Role
+		dbInstance.Where("name = ?", httpio.OrganizerRole).First(&organizerRole)
+		path := fmt.Sprintf("/api/v1/roles/%d", organizerRole.ID)
+
+		response, _, _ := Delete[map[string]any](path)
+		if response.StatusCode != http.StatusBadRequest {
+			t.Errorf("Expected status code 400 on delete, got %d", response.StatusCode)
+		}
+
+		response, _, _ = Put[map[string]any](path, httpio.AnyMap{"name": "organizers", "permissions": []string{}})
+		if response.StatusCode != http.StatusBadRequest {
+			t.Errorf("Expected status code 400 on rename, got %d", response.StatusCode)
+		}
+	}))
+
+	t.Run("last permission to manage roles can't be removed", testCasePermissions(func(t *testing.T) {
+		var adminRole models.Role
+		dbInstance.Where("name = ?", httpio.AdminRole).First(&adminRole)
+
+		response, _, _ := Put[map[string]any](fmt.Sprintf("/api/v1/roles/%d", adminRole.ID), httpio.AnyMap{
+			"name":        httpio.AdminRole,
+			"permissions": []string{httpio.UsersManagePermission},
+		})
+		if response.StatusCode != http.StatusBadRequest {
+			t.Errorf("Expected status code 400, got %d", response.StatusCode)
+		}
+
+		var count int64
+		dbInstance.Model(&models.RolePermission{}).Where("role_id = ?", adminRole.ID).Count(&count)
+		if count != int64(len(httpio.AllPermissions)) {
+			t.Errorf("Expected admin permissions to be kept, got %d", count)
+		}
+	}))
+
+	t.Run("permissions catalogue is listed", testCasePermissions(func(t *testing.T) {
+		response, result, _ := Get[map[string][]string]("/api/v1/permissions")
+		if response.StatusCode != http.StatusOK {
+			t.Fatalf("Expected status code 200, got %d", response.StatusCode)
+		}
+		if !slices.Equal((*result)["permissions"], httpio.AllPermissions) {
+			t.Errorf("Expected permissions %v, got %v", httpio.AllPermissions, (*result)["permissions"])
+		}
+	}))
+}
+
+func beforeEachPermissions() {
+	dbInstance.Where("name NOT IN ?", httpio.BuiltInRoles).Delete(&models.Role{})
+	dbInstance.Where("email LIKE ?", "%@permissions.test").Delete(&models.User{})
+	dbInstance.Unscoped().Where("name = ?", "Permissions Event").Delete(&models.Event{})
+}
+
+func testCasePermissions(test func(t *testing.T)) func(*testing.T) {
+	return func(t *testing.T) {
+		beforeEachPermissions()
+		defer beforeEachPermissions()
+		test(t)
+	}
+}
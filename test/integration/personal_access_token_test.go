@@ -0,0 +1,143 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/filipio/athletics-backend/internal/models"
+	"github.com/filipio/athletics-backend/pkg/httpio"
+)
+
+func TestPersonalAccessTokens(t *testing.T) {
+	t.Run("token acts on behalf of the user within its scopes", testCasePersonalAccessToken(func(t *testing.T) {
+		createUser("organizer@pat.test", "organizer", "password123", httpio.OrganizerRole)
+		accessToken := loginAs("organizer@pat.test", "password123")["access_token"].(string)
+
+		created := createPersonalAccessToken(t, accessToken, []string{httpio.EventsWritePermission})
+		if !strings.HasPrefix(created.Token, models.PersonalAccessTokenPrefix) || !strings.HasPrefix(created.Token, created.Hint) {
+			t.Fatalf("Expected plain token with hint %s, got %s", created.Hint, created.Token)
+		}
+
+		eventPayload := httpio.AnyMap{"name": "PAT Event", "deadline": time.Now().Add(24 * time.Hour)}
+		response, _, _ := executeHttpWithToken[map[string]any]("POST", "/api/v1/events", eventPayload, created.Token)
+		if response.StatusCode != http.StatusOK {
+			t.Errorf("Expected status code 200 for scoped permission, got %d", response.StatusCode)
+		}
+
+		response, _, _ = executeHttpWithToken[map[string]any]("GET", "/api/v1/users/me", nil, created.Token)
+		if response.StatusCode != http.StatusOK {
+			t.Errorf("Expected status code 200 for user endpoint, got %d", response.StatusCode)
+		}
+
+		response, tokens, _ := executeHttpWithToken[httpio.PaginatedResponse]("GET", "/api/v1/users/me/tokens", nil, accessToken)
+		if response.StatusCode != http.StatusOK {
+			t.Fatalf("Expected status code 200 on listing, got %d", response.StatusCode)
+		}
+		data := tokens.Data.([]any)
+		if len(data) != 1 {
+			t.Fatalf("Expected 1 token, got %d", len(data))
+		}
+		listed := data[0].(map[string]any)
+		if listed["token"] != nil || listed["last_used_at"] == nil {
+			t.Errorf("Expected listed token without plain value and with last usage, got %v", listed)
+		}
+	}))
+
+	t.Run("permissions outside of scopes are denied", testCasePersonalAccessToken(func(t *testing.T) {
+		createUser("unscoped@pat.test", "unscoped", "password123", httpio.OrganizerRole)
+		accessToken := loginAs("unscoped@pat.test", "password123")["access_token"].(string)
+		created := createPersonalAccessToken(t, accessToken, []string{httpio.StatsReadPermission})
+
+		eventPayload := httpio.AnyMap{"name": "PAT Event", "deadline": time.Now().Add(24 * time.Hour)}
+		response, _, _ := executeHttpWithToken[map[string]any]("POST", "/api/v1/events", eventPayload, created.Token)
+		if response.StatusCode != http.StatusUnauthorized {
+			t.Errorf("Expected status code 401, got %d", response.StatusCode)
+		}
+	}))
+
+	t.Run("token can't be used for account management", testCasePersonalAccessToken(func(t *testing.T) {
+		createUser("account@pat.test", "account", "password123", httpio.UserRole)
+		accessToken := loginAs("account@pat.test", "password123")["access_token"].(string)
+		created := createPersonalAccessToken(t, accessToken, []string{})
+
+		response, _, _ := executeHttpWithToken[map[string]any]("POST", "/api/v1/users/me/tokens", httpio.AnyMap{
+			"name":       "nested",
+			"scopes":     []string{},
+			"expires_at": time.Now().Add(24 * time.Hour),
+		}, created.Token)
+		if response.StatusCode != http.StatusUnauthorized {
+			t.Errorf("Expected status code 401 on token creation, got %d", response.StatusCode)
+		}
+
+		response, _, _ = executeHttpWithToken[map[string]any]("PUT", "/api/v1/users/me/password", httpio.AnyMap{
+			"current_password": "password123",
+			"new_password":     "newpassword123",
+		}, created.Token)
+		if response.StatusCode != http.StatusUnauthorized {
+			t.Errorf("Expected status code 401 on password change, got %d", response.StatusCode)
+		}
+	}))
+
+	t.Run("revoked token is rejected", testCasePersonalAccessToken(func(t *testing.T) {
+		createUser("revoked@pat.test", "revoked", "password123", httpio.UserRole)
+		accessToken := loginAs("revoked@pat.test", "password123")["access_token"].(string)
+		created := createPersonalAccessToken(t, accessToken, []string{})
+
+		response, _, _ := executeHttpWithToken[map[string]any]("DELETE", fmt.Sprintf("/api/v1/users/me/tokens/%d", created.ID), nil, accessToken)
+		if response.StatusCode != http.StatusOK {
+			t.Fatalf("Expected status code 200 on revocation, got %d", response.StatusCode)
+		}
+
+		response, _, _ = executeHttpWithToken[map[string]any]("GET", "/api/v1/users/me", nil, created.Token)
+		if response.StatusCode != http.StatusUnauthorized {
+			t.Errorf("Expected status code 401, got %d", response.StatusCode)
+		}
+	}))
+
+	t.Run("scopes must be held by the user and lifetime is limited", testCasePersonalAccessToken(func(t *testing.T) {
+		createUser("limited@pat.test", "limited", "password123", httpio.UserRole)
+		accessToken := loginAs("limited@pat.test", "password123")["access_token"].(string)
+
+		for _, payload := range []httpio.AnyMap{
+			{"name": "script", "scopes": []string{httpio.EventsWritePermission}, "expires_at": time.Now().Add(24 * time.Hour)},
+			{"name": "script", "scopes": []string{}, "expires_at": time.Now().Add(2 * models.MaxPersonalAccessTokenLifetime)},
+		} {
+			response, _, _ := executeHttpWithToken[map[string]any]("POST", "/api/v1/users/me/tokens", payload, accessToken)
+			if response.StatusCode != http.StatusBadRequest {
+				t.Errorf("Expected status code 400 for %v, got %d", payload, response.StatusCode)
+			}
+		}
+	}))
+}
+
+func createPersonalAccessToken(t *testing.T, accessToken string, scopes []string) models.PersonalAccessTokenResponse {
+	response, created, err := executeHttpWithToken[models.PersonalAccessTokenResponse]("POST", "/api/v1/users/me/tokens", httpio.AnyMap{
+		"name":       "results script",
+		"scopes":     scopes,
+		"expires_at": time.Now().Add(30 * 24 * time.Hour),
+	}, accessToken)
+	if err != nil {
+		t.Fatalf("Error executing request: %s", err.Error())
+	}
+	if response.StatusCode != http.StatusCreated {
+		t.Fatalf("Expected status code 201, got %d", response.StatusCode)
+	}
+
+	return *created
+}
+
+func beforeEachPersonalAccessToken() {
+	dbInstance.Where("email LIKE ?", "%@pat.test").Delete(&models.User{})
+	dbInstance.Unscoped().Where("name = ?", "PAT Event").Delete(&models.Event{})
+}
+
+func testCasePersonalAccessToken(test func(t *testing.T)) func(*testing.T) {
+	return func(t *testing.T) {
+		beforeEachPersonalAccessToken()
+		defer beforeEachPersonalAccessToken()
+		test(t)
+	}
+}
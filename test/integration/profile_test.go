@@ -0,0 +1,155 @@
+package controllers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"image"
+	_ "image/jpeg"
+	"image/png"
+	"mime/multipart"
+	"net/http"
+	"testing"
+
+	"github.com/filipio/athletics-backend/internal/models"
+	"github.com/filipio/athletics-backend/pkg/httpio"
+)
+
+func TestUserProfile(t *testing.T) {
+	t.Run("profile is updated by the user and shown publicly without email", testCaseProfile(func(t *testing.T) {
+		user := createUser("runner@profile.test", "profile_runner", "password123", httpio.UserRole)
+		accessToken := loginAs("runner@profile.test", "password123")["access_token"].(string)
+
+		discipline := models.Discipline{Name: "Profile 100m", Type: "running"}
+		dbInstance.Create(&discipline)
+		firstName, lastName := "Profile", "Sprinter"
+		athlete := models.Athlete{FirstName: &firstName, LastName: &lastName, Gender: "male"}
+		dbInstance.Create(&athlete)
+
+		response, profile, err := executeHttpWithToken[models.UserProfileResponse]("PUT", "/api/v1/users/me/profile", httpio.AnyMap{
+			"display_name":             "Fast Runner",
+			"country":                  "PL",
+			"bio":                      "Running since 2010",
+			"favourite_discipline_ids": []uint{discipline.ID},
+			"favourite_athlete_ids":    []uint{athlete.ID},
+		}, accessToken)
+		if err != nil {
+			t.Fatalf("Error executing request: %s", err.Error())
+		}
+		if response.StatusCode != http.StatusOK {
+			t.Fatalf("Expected status code 200, got %d", response.StatusCode)
+		}
+		if profile.DisplayName != "Fast Runner" || len(profile.FavouriteDisciplines) != 1 || len(profile.FavouriteAthletes) != 1 {
+			t.Errorf("Expected updated profile, got %+v", profile)
+		}
+
+		response, publicProfile, _ := executeHttpWithToken[map[string]any]("GET", fmt.Sprintf("/api/v1/users/%d/profile", user.ID), nil, "")
+		if response.StatusCode != http.StatusOK {
+			t.Fatalf("Expected status code 200 on public profile, got %d", response.StatusCode)
+		}
+		if _, ok := (*publicProfile)["email"]; ok {
+			t.Errorf("Expected public profile without email, got %v", *publicProfile)
+		}
+		if (*publicProfile)["country"] != "PL" || (*publicProfile)["username"] != "profile_runner" {
+			t.Errorf("Expected public profile data, got %v", *publicProfile)
+		}
+
+		response, profile, _ = executeHttpWithToken[models.UserProfileResponse]("PUT", "/api/v1/users/me/profile", httpio.AnyMap{
+			"display_name": "Fast Runner",
+		}, accessToken)
+		if response.StatusCode != http.StatusOK || len(profile.FavouriteDisciplines) != 0 || profile.Country != nil {
+			t.Errorf("Expected favourites and country to be cleared, got %d %+v", response.StatusCode, profile)
+		}
+	}))
+
+	t.Run("invalid profile is rejected", testCaseProfile(func(t *testing.T) {
+		createUser("invalid@profile.test", "profile_invalid", "password123", httpio.UserRole)
+		accessToken := loginAs("invalid@profile.test", "password123")["access_token"].(string)
+
+		for _, payload := range []httpio.AnyMap{
+			{"country": "Poland"},
+			{"favourite_discipline_ids": []uint{999999}},
+			{"display_name": "kurwa"},
+		} {
+			response, _, _ := executeHttpWithToken[map[string]any]("PUT", "/api/v1/users/me/profile", payload, accessToken)
+			if response.StatusCode != http.StatusBadRequest {
+				t.Errorf("Expected status code 400 for %v, got %d", payload, response.StatusCode)
+			}
+		}
+	}))
+
+	t.Run("avatar is resized and can be removed", testCaseProfile(func(t *testing.T) {
+		createUser("avatar@profile.test", "profile_avatar", "password123", httpio.UserRole)
+		accessToken := loginAs("avatar@profile.test", "password123")["access_token"].(string)
+
+		var imageContent bytes.Buffer
+		png.Encode(&imageContent, image.NewRGBA(image.Rect(0, 0, 600, 400)))
+
+		response, profile := uploadAvatar(t, accessToken, imageContent.Bytes())
+		if response.StatusCode != http.StatusOK {
+			t.Fatalf("Expected status code 200, got %d", response.StatusCode)
+		}
+		if profile.AvatarURL == nil {
+			t.Fatal("Expected avatar url")
+		}
+
+		// test environment uses local storage without public url, so the avatar url is relative
+		avatarResponse, err := http.Get(host + *profile.AvatarURL)
+		if err != nil || avatarResponse.StatusCode != http.StatusOK {
+			t.Fatalf("Expected avatar to be served, got %v %v", avatarResponse, err)
+		}
+		avatar, _, err := image.DecodeConfig(avatarResponse.Body)
+		if err != nil || avatar.Width != 256 || avatar.Height != 256 {
+			t.Errorf("Expected 256x256 avatar, got %v %v", avatar, err)
+		}
+
+		response, _ = uploadAvatar(t, accessToken, []byte("not an image"))
+		if response.StatusCode != http.StatusBadRequest {
+			t.Errorf("Expected status code 400 for invalid image, got %d", response.StatusCode)
+		}
+
+		response, removed, _ := executeHttpWithToken[models.UserProfileResponse]("DELETE", "/api/v1/users/me/profile/avatar", nil, accessToken)
+		if response.StatusCode != http.StatusOK || removed.AvatarURL != nil {
+			t.Errorf("Expected avatar to be removed, got %d %v", response.StatusCode, removed.AvatarURL)
+		}
+	}))
+}
+
+func uploadAvatar(t *testing.T, accessToken string, content []byte) (*http.Response, models.UserProfileResponse) {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	part, _ := writer.CreateFormFile("avatar", "avatar.png")
+	part.Write(content)
+	writer.Close()
+
+	req, err := http.NewRequestWithContext(ctx, "PUT", host+"/api/v1/users/me/profile/avatar", &body)
+	if err != nil {
+		t.Fatalf("failed to create request: %s", err.Error())
+	}
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+
+	response, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("failed to do request: %s", err.Error())
+	}
+
+	var profile models.UserProfileResponse
+	json.NewDecoder(response.Body).Decode(&profile)
+
+	return response, profile
+}
+
+func beforeEachProfile() {
+	dbInstance.Where("email LIKE ?", "%@profile.test").Delete(&models.User{})
+	dbInstance.Where("name LIKE ?", "Profile %").Delete(&models.Discipline{})
+	dbInstance.Where("first_name = ?", "Profile").Delete(&models.Athlete{})
+}
+
+func testCaseProfile(test func(t *testing.T)) func(*testing.T) {
+	return func(t *testing.T) {
+		beforeEachProfile()
+		defer beforeEachProfile()
+		test(t)
+	}
+}
@@ -0,0 +1,83 @@
+package controllers
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/filipio/athletics-backend/internal/models"
+	"github.com/filipio/athletics-backend/pkg/httpio"
+)
+
+func TestGetMyRankingAround(t *testing.T) {
+	t.Run("returns places surrounding the current user", testCaseRanking(func(t *testing.T) {
+		event := &models.Event{Name: "Ranking Event", Deadline: time.Now().Add(24 * time.Hour), Status: "published"}
+		dbInstance.Save(event)
+		question := &models.Question{EventID: event.ID, Content: "Who wins?", Type: "country", Points: 1}
+		dbInstance.Save(question)
+
+		pointsByUsername := map[string]uint{"first": 30, "second": 20, "third": 10, "fourth": 5}
+		for username, points := range pointsByUsername {
+			user := createUser(username+"@ranking.test", username, "password123", httpio.UserRole)
+			dbInstance.Save(&models.Answer{
+				UserID:     user.ID,
+				QuestionID: question.ID,
+				Content:    models.AnswerOfQuestion{JSON: []byte(`{"country": "KEN"}`)},
+				Points:     points,
+			})
+		}
+
+		tokens := loginAs("second@ranking.test", "password123")
+		response, around, err := executeHttpWithToken[map[string]any]("GET", "/api/v1/users/me/ranking/around?radius=1", nil, tokens["access_token"].(string))
+		if err != nil {
+			t.Fatalf("Error executing request: %s", err.Error())
+		}
+
+		if response.StatusCode != http.StatusOK {
+			t.Fatalf("Expected status code 200, got %d", response.StatusCode)
+		}
+
+		if (*around)["place"] != float64(2) {
+			t.Errorf("Expected place 2, got %v", (*around)["place"])
+		}
+
+		data := (*around)["data"].([]any)
+		expectedUsernames := []string{"first", "second", "third"}
+		if len(data) != len(expectedUsernames) {
+			t.Fatalf("Expected %d ranking items, got %d", len(expectedUsernames), len(data))
+		}
+
+		for i, item := range data {
+			itemMap := item.(map[string]any)
+			if itemMap["username"] != expectedUsernames[i] {
+				t.Errorf("Expected username %s at index %d, got %v", expectedUsernames[i], i, itemMap["username"])
+			}
+			if itemMap["is_current_user"] != (expectedUsernames[i] == "second") {
+				t.Errorf("Unexpected is_current_user for %v", itemMap["username"])
+			}
+		}
+	}))
+
+	t.Run("rejects radius out of range", testCaseRanking(func(t *testing.T) {
+		response, _, err := Get[map[string]any]("/api/v1/users/me/ranking/around?radius=0")
+		if err != nil {
+			t.Fatalf("Error executing request: %s", err.Error())
+		}
+
+		if response.StatusCode != http.StatusBadRequest {
+			t.Errorf("Expected status code 400, got %d", response.StatusCode)
+		}
+	}))
+}
+
+func beforeEachRanking() {
+	dbInstance.Where("email LIKE ?", "%@ranking.test").Delete(&models.User{})
+	dbInstance.Unscoped().Where("1 = 1").Delete(&models.Event{})
+}
+
+func testCaseRanking(test func(t *testing.T)) func(*testing.T) {
+	return func(t *testing.T) {
+		beforeEachRanking()
+		test(t)
+	}
+}
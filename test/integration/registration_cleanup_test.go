@@ -0,0 +1,151 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/filipio/athletics-backend/internal/models"
+	"github.com/filipio/athletics-backend/internal/storage"
+	"github.com/filipio/athletics-backend/internal/workers"
+	args "github.com/filipio/athletics-backend/internal/workers/args"
+	"github.com/filipio/athletics-backend/pkg/config"
+	"github.com/filipio/athletics-backend/pkg/httpio"
+	"github.com/riverqueue/river"
+)
+
+func TestResendVerification(t *testing.T) {
+	t.Run("email without pending registration is rejected", testCaseRegistrationCleanup(func(t *testing.T) {
+		response, _, _ := Post[map[string]any]("/api/v1/auth/register/resend", httpio.AnyMap{"email": "unknown@cleanup.test"})
+		if response.StatusCode != http.StatusBadRequest {
+			t.Errorf("Expected status code 400, got %d", response.StatusCode)
+		}
+	}))
+
+	t.Run("resend shares rate limit with registration", testCaseRegistrationCleanup(func(t *testing.T) {
+		blockedUntil := time.Now().Add(10 * time.Minute)
+		dbInstance.Create(&models.EmailVerificationRateLimit{
+			Email:            "blocked@cleanup.test",
+			RequestRateLimit: models.RequestRateLimit{RequestCount: models.MaxVerificationRequests, BlockedUntil: &blockedUntil},
+		})
+
+		response, _, _ := Post[map[string]any]("/api/v1/auth/register/resend", httpio.AnyMap{"email": "blocked@cleanup.test"})
+		if response.StatusCode != http.StatusTooManyRequests {
+			t.Errorf("Expected status code 429, got %d", response.StatusCode)
+		}
+		if response.Header.Get("Retry-After") == "" {
+			t.Error("Expected Retry-After header")
+		}
+	}))
+}
+
+func TestCleanupWorker(t *testing.T) {
+	t.Run("expired and stale rows are purged, active ones are kept", testCaseRegistrationCleanup(func(t *testing.T) {
+		past := time.Now().Add(-time.Hour)
+		future := time.Now().Add(time.Hour)
+
+		expiredRegistration := models.PendingRegistration{Email: "expired@cleanup.test", Username: "expired", PasswordHash: "x", VerificationToken: "expired-cleanup", ExpiresAt: past}
+		activeRegistration := models.PendingRegistration{Email: "active@cleanup.test", Username: "active", PasswordHash: "x", VerificationToken: "active-cleanup", ExpiresAt: future}
+		dbInstance.Create(&expiredRegistration)
+		dbInstance.Create(&activeRegistration)
+
+		staleRateLimit := models.PasswordResetRateLimit{Email: "stale@cleanup.test", RequestRateLimit: models.RequestRateLimit{RequestCount: 1, LastRequestAt: &past}}
+		blockedRateLimit := models.PasswordResetRateLimit{Email: "blocked@cleanup.test", RequestRateLimit: models.RequestRateLimit{RequestCount: 3, LastRequestAt: &past, BlockedUntil: &future}}
+		dbInstance.Create(&staleRateLimit)
+		dbInstance.Create(&blockedRateLimit)
+
+		user := createUser("tokens@cleanup.test", "cleanuptokens", "password123", httpio.UserRole)
+		longAgo := time.Now().Add(-2 * workers.RevokedRefreshTokenRetention)
+		expiredToken := models.RefreshToken{UserID: user.ID, TokenHash: "expired", SessionID: "00000000-0000-0000-0000-000000000001", ExpiresAt: past}
+		revokedToken := models.RefreshToken{UserID: user.ID, TokenHash: "revoked", SessionID: "00000000-0000-0000-0000-000000000002", ExpiresAt: future, RevokedAt: &longAgo}
+		activeToken := models.RefreshToken{UserID: user.ID, TokenHash: "active", SessionID: "00000000-0000-0000-0000-000000000003", ExpiresAt: future}
+		dbInstance.Create(&expiredToken)
+		dbInstance.Create(&revokedToken)
+		dbInstance.Create(&activeToken)
+
+		expiredOidcRequest := models.OidcLoginRequest{Provider: "cleanup", StateHash: "expired-cleanup", Nonce: "x", CodeVerifier: "x", ExpiresAt: past}
+		activeOidcRequest := models.OidcLoginRequest{Provider: "cleanup", StateHash: "active-cleanup", Nonce: "x", CodeVerifier: "x", ExpiresAt: future}
+		expiredChallenge := models.LoginChallenge{UserID: user.ID, TokenHash: "expired-cleanup", ExpiresAt: past}
+		activeChallenge := models.LoginChallenge{UserID: user.ID, TokenHash: "active-cleanup", ExpiresAt: future}
+		expiredMagicLink := models.MagicLinkToken{UserID: user.ID, TokenHash: "expired-cleanup", ExpiresAt: past}
+		activeMagicLink := models.MagicLinkToken{UserID: user.ID, TokenHash: "active-cleanup", ExpiresAt: future}
+		expiredPasswordReset := models.PasswordResetToken{UserID: user.ID, TokenHash: "expired-cleanup", ExpiresAt: past}
+		activePasswordReset := models.PasswordResetToken{UserID: user.ID, TokenHash: "active-cleanup", ExpiresAt: future}
+		expiredEmailChange := models.PendingEmailChange{UserID: user.ID, NewEmail: "expired-new@cleanup.test", VerificationToken: "expired-cleanup", ExpiresAt: past}
+		activeEmailChange := models.PendingEmailChange{UserID: user.ID, NewEmail: "active-new@cleanup.test", VerificationToken: "active-cleanup", ExpiresAt: future}
+		expiredAccessToken := models.PersonalAccessToken{UserID: user.ID, Name: "expired", TokenHash: "expired-cleanup", Hint: "x", Scopes: []string{}, ExpiresAt: past}
+		revokedAccessToken := models.PersonalAccessToken{UserID: user.ID, Name: "revoked", TokenHash: "revoked-cleanup", Hint: "x", Scopes: []string{}, ExpiresAt: future, RevokedAt: &past}
+		activeAccessToken := models.PersonalAccessToken{UserID: user.ID, Name: "active", TokenHash: "active-cleanup", Hint: "x", Scopes: []string{}, ExpiresAt: future}
+		for _, record := range []any{
+			&expiredOidcRequest, &activeOidcRequest, &expiredChallenge, &activeChallenge, &expiredMagicLink, &activeMagicLink,
+			&expiredPasswordReset, &activePasswordReset, &expiredEmailChange, &activeEmailChange,
+			&expiredAccessToken, &revokedAccessToken, &activeAccessToken,
+		} {
+			if err := dbInstance.Create(record).Error; err != nil {
+				t.Fatalf("Error creating %T: %s", record, err.Error())
+			}
+		}
+
+		fileStorage, _ := storage.LoadStorage()
+		worker := workers.NewCleanupWorker(&config.Dependencies{DB: dbInstance, Storage: fileStorage})
+		if err := worker.Work(context.Background(), &river.Job[args.CleanupArgs]{}); err != nil {
+			t.Fatalf("Cleanup failed: %s", err.Error())
+		}
+
+		for _, record := range []struct {
+			name    string
+			model   any
+			id      uint
+			present bool
+		}{
+			{"expired registration", &models.PendingRegistration{}, expiredRegistration.ID, false},
+			{"active registration", &models.PendingRegistration{}, activeRegistration.ID, true},
+			{"stale rate limit", &models.PasswordResetRateLimit{}, staleRateLimit.ID, false},
+			{"blocked rate limit", &models.PasswordResetRateLimit{}, blockedRateLimit.ID, true},
+			{"expired refresh token", &models.RefreshToken{}, expiredToken.ID, false},
+			{"revoked refresh token", &models.RefreshToken{}, revokedToken.ID, false},
+			{"active refresh token", &models.RefreshToken{}, activeToken.ID, true},
+			{"expired oidc login request", &models.OidcLoginRequest{}, expiredOidcRequest.ID, false},
+			{"active oidc login request", &models.OidcLoginRequest{}, activeOidcRequest.ID, true},
+			{"expired login challenge", &models.LoginChallenge{}, expiredChallenge.ID, false},
+			{"active login challenge", &models.LoginChallenge{}, activeChallenge.ID, true},
+			{"expired magic link", &models.MagicLinkToken{}, expiredMagicLink.ID, false},
+			{"active magic link", &models.MagicLinkToken{}, activeMagicLink.ID, true},
+			{"expired password reset", &models.PasswordResetToken{}, expiredPasswordReset.ID, false},
+			{"active password reset", &models.PasswordResetToken{}, activePasswordReset.ID, true},
+			{"expired email change", &models.PendingEmailChange{}, expiredEmailChange.ID, false},
+			{"active email change", &models.PendingEmailChange{}, activeEmailChange.ID, true},
+			{"expired access token", &models.PersonalAccessToken{}, expiredAccessToken.ID, false},
+			{"revoked access token", &models.PersonalAccessToken{}, revokedAccessToken.ID, false},
+			{"active access token", &models.PersonalAccessToken{}, activeAccessToken.ID, true},
+		} {
+			var count int64
+			dbInstance.Model(record.model).Where("id = ?", record.id).Count(&count)
+			if (count == 1) != record.present {
+				t.Errorf("Expected %s present: %v, got count %d", record.name, record.present, count)
+			}
+		}
+	}))
+}
+
+func beforeEachRegistrationCleanup() {
+	var userIDs []uint
+	dbInstance.Model(&models.User{}).Where("email LIKE ?", "%@cleanup.test").Pluck("id", &userIDs)
+	for _, model := range []any{&models.RefreshToken{}, &models.LoginChallenge{}, &models.MagicLinkToken{}, &models.PasswordResetToken{}, &models.PendingEmailChange{}, &models.PersonalAccessToken{}} {
+		dbInstance.Where("user_id IN ?", userIDs).Delete(model)
+	}
+	dbInstance.Where("provider = ?", "cleanup").Delete(&models.OidcLoginRequest{})
+	dbInstance.Where("email LIKE ?", "%@cleanup.test").Delete(&models.User{})
+	dbInstance.Where("email LIKE ?", "%@cleanup.test").Delete(&models.PendingRegistration{})
+	dbInstance.Where("email LIKE ?", "%@cleanup.test").Delete(&models.EmailVerificationRateLimit{})
+	dbInstance.Where("email LIKE ?", "%@cleanup.test").Delete(&models.PasswordResetRateLimit{})
+}
+
+func testCaseRegistrationCleanup(test func(t *testing.T)) func(*testing.T) {
+	return func(t *testing.T) {
+		beforeEachRegistrationCleanup()
+		defer beforeEachRegistrationCleanup()
+		test(t)
+	}
+}
@@ -0,0 +1,99 @@
+package controllers
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/filipio/athletics-backend/internal/models"
+	"github.com/filipio/athletics-backend/pkg/httpio"
+)
+
+func TestSessions(t *testing.T) {
+	t.Run("lists active sessions with client details", testCaseSessions(func(t *testing.T) {
+		createUser("list@sessions.test", "list", "password123", httpio.UserRole)
+		currentSession := loginAs("list@sessions.test", "password123")
+		loginAs("list@sessions.test", "password123")
+
+		response, sessions, err := executeHttpWithToken[[]map[string]any]("GET", "/api/v1/users/me/sessions", nil, currentSession["access_token"].(string))
+		if err != nil {
+			t.Fatalf("Error executing request: %s", err.Error())
+		}
+
+		if response.StatusCode != http.StatusOK {
+			t.Fatalf("Expected status code 200, got %d", response.StatusCode)
+		}
+
+		if len(*sessions) != 2 {
+			t.Fatalf("Expected 2 sessions, got %d", len(*sessions))
+		}
+
+		currentCount := 0
+		for _, session := range *sessions {
+			if session["is_current"] == true {
+				currentCount++
+			}
+			if session["user_agent"] == "" || session["ip_address"] == "" {
+				t.Errorf("Expected user agent and ip address to be captured, got %v", session)
+			}
+		}
+
+		if currentCount != 1 {
+			t.Errorf("Expected exactly one current session, got %d", currentCount)
+		}
+	}))
+
+	t.Run("revokes single session and other sessions", testCaseSessions(func(t *testing.T) {
+		user := createUser("revoke@sessions.test", "revoke", "password123", httpio.UserRole)
+		currentSession := loginAs("revoke@sessions.test", "password123")
+		secondSession := loginAs("revoke@sessions.test", "password123")
+		thirdSession := loginAs("revoke@sessions.test", "password123")
+
+		var secondToken models.RefreshToken
+		dbInstance.Where("user_id = ?", user.ID).Order("id DESC").Offset(1).First(&secondToken)
+
+		response, _, err := executeHttpWithToken[map[string]any]("DELETE", "/api/v1/users/me/sessions/"+secondToken.SessionID, nil, currentSession["access_token"].(string))
+		if err != nil {
+			t.Fatalf("Error executing request: %s", err.Error())
+		}
+
+		if response.StatusCode != http.StatusOK {
+			t.Fatalf("Expected status code 200, got %d", response.StatusCode)
+		}
+
+		response, _, _ = executeHttpWithToken[map[string]any]("GET", "/api/v1/users/me", nil, secondSession["access_token"].(string))
+		if response.StatusCode != http.StatusUnauthorized {
+			t.Errorf("Expected revoked session to be rejected, got status %d", response.StatusCode)
+		}
+
+		response, _, _ = executeHttpWithToken[map[string]any]("DELETE", "/api/v1/users/me/sessions/not-a-session", nil, currentSession["access_token"].(string))
+		if response.StatusCode != http.StatusNotFound {
+			t.Errorf("Expected status code 404 for invalid session id, got %d", response.StatusCode)
+		}
+
+		response, _, _ = executeHttpWithToken[map[string]any]("DELETE", "/api/v1/users/me/sessions", nil, currentSession["access_token"].(string))
+		if response.StatusCode != http.StatusOK {
+			t.Fatalf("Expected status code 200, got %d", response.StatusCode)
+		}
+
+		response, _, _ = executeHttpWithToken[map[string]any]("GET", "/api/v1/users/me", nil, thirdSession["access_token"].(string))
+		if response.StatusCode != http.StatusUnauthorized {
+			t.Errorf("Expected other session to be revoked, got status %d", response.StatusCode)
+		}
+
+		response, _, _ = executeHttpWithToken[map[string]any]("GET", "/api/v1/users/me", nil, currentSession["access_token"].(string))
+		if response.StatusCode != http.StatusOK {
+			t.Errorf("Expected current session to stay active, got status %d", response.StatusCode)
+		}
+	}))
+}
+
+func beforeEachSessions() {
+	dbInstance.Where("email LIKE ?", "%@sessions.test").Delete(&models.User{})
+}
+
+func testCaseSessions(test func(t *testing.T)) func(*testing.T) {
+	return func(t *testing.T) {
+		beforeEachSessions()
+		test(t)
+	}
+}
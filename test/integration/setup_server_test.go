@@ -10,6 +10,7 @@ import (
 	"time"
 
 	"github.com/filipio/athletics-backend/internal/app"
+	"github.com/filipio/athletics-backend/internal/models"
 	"github.com/filipio/athletics-backend/pkg/config"
 	"github.com/filipio/athletics-backend/pkg/httpio"
 	"gorm.io/gorm"
@@ -31,6 +32,9 @@ func TestMain(m *testing.M) {
 	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
+	startFakeOidcProvider()
+	defer fakeOidc.server.Close()
+
 	go func() {
 		if err := app.Run(ctx, envPath); err != nil {
 			slog.Error("failed to start server", "error", err)
@@ -44,6 +48,8 @@ func TestMain(m *testing.M) {
 	}
 
 	dbInstance = config.DatabaseConnection()
+	// all tests login from the same address, lockouts from previous runs must not block them
+	dbInstance.Where("1 = 1").Delete(&models.LoginAttemptLimit{})
 
 	// Store admin credentials from environment variables for use in tests
 	adminEmail = os.Getenv("ADMIN_EMAIL")
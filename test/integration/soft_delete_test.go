@@ -0,0 +1,163 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/filipio/athletics-backend/internal/models"
+	"github.com/filipio/athletics-backend/pkg/httpio"
+)
+
+func TestSoftDelete(t *testing.T) {
+	t.Run("deleted event is hidden and restored with its questions and answers", testCaseSoftDelete(func(t *testing.T) {
+		event := &models.Event{Name: "Soft Delete Event", Deadline: time.Now().Add(24 * time.Hour), Status: "published"}
+		dbInstance.Save(event)
+		question := &models.Question{EventID: event.ID, Content: "Who wins?", Type: "country", Points: 1}
+		dbInstance.Save(question)
+		user := createUser("answering@softdelete.test", "softdelete_user", "password123", httpio.UserRole)
+		dbInstance.Save(&models.Answer{
+			UserID:     user.ID,
+			QuestionID: question.ID,
+			Content:    models.AnswerOfQuestion{JSON: []byte(`{"country": "KEN"}`)},
+			Points:     3,
+		})
+
+		eventPath := fmt.Sprintf("/api/v1/events/%d", event.ID)
+		questionPath := fmt.Sprintf("/api/v1/questions/%d", question.ID)
+
+		response, _, _ := Delete[map[string]any](eventPath)
+		if response.StatusCode != http.StatusOK {
+			t.Fatalf("Expected status code 200 on delete, got %d", response.StatusCode)
+		}
+
+		var answersCount int64
+		dbInstance.Model(&models.Answer{}).Where("question_id = ?", question.ID).Count(&answersCount)
+		if answersCount != 1 {
+			t.Errorf("Expected answers to be kept after event deletion, got %d", answersCount)
+		}
+
+		for _, path := range []string{eventPath, questionPath} {
+			response, _, _ = Get[map[string]any](path)
+			if response.StatusCode != http.StatusNotFound {
+				t.Errorf("Expected status code 404 on %s after delete, got %d", path, response.StatusCode)
+			}
+		}
+
+		response, ranking, _ := Get[map[string]any](fmt.Sprintf("/api/v1/ranking?event_id=%d", event.ID))
+		if response.StatusCode != http.StatusOK || len((*ranking)["data"].([]any)) != 0 {
+			t.Errorf("Expected answers of deleted event to be left out of the ranking, got %v", *ranking)
+		}
+
+		response, deletedEvent, _ := Get[models.Event](eventPath + "?include_deleted=true")
+		if response.StatusCode != http.StatusOK || !deletedEvent.DeletedAt.Valid {
+			t.Errorf("Expected admin to see deleted event, got %d", response.StatusCode)
+		}
+
+		response, restoredEvent, _ := Post[models.Event](eventPath+"/restore", nil)
+		if response.StatusCode != http.StatusOK || restoredEvent.DeletedAt.Valid {
+			t.Fatalf("Expected event to be restored, got %d", response.StatusCode)
+		}
+
+		response, _, _ = Get[map[string]any](questionPath)
+		if response.StatusCode != http.StatusOK {
+			t.Errorf("Expected question to be visible with restored event, got %d", response.StatusCode)
+		}
+
+		response, ranking, _ = Get[map[string]any](fmt.Sprintf("/api/v1/ranking?event_id=%d", event.ID))
+		if response.StatusCode != http.StatusOK || len((*ranking)["data"].([]any)) != 1 {
+			t.Errorf("Expected answers of restored event in the ranking, got %v", *ranking)
+		}
+
+		response, _, _ = Post[map[string]any](eventPath+"/restore", nil)
+		if response.StatusCode != http.StatusNotFound {
+			t.Errorf("Expected status code 404 on restore of not deleted event, got %d", response.StatusCode)
+		}
+	}))
+
+	t.Run("deleted question is listed only with include_deleted and is restored", testCaseSoftDelete(func(t *testing.T) {
+		event := &models.Event{Name: "Soft Delete Event", Deadline: time.Now().Add(24 * time.Hour), Status: "published"}
+		dbInstance.Save(event)
+		question := &models.Question{EventID: event.ID, Content: "Who wins?", Type: "country", Points: 1}
+		dbInstance.Save(question)
+		questionPath := fmt.Sprintf("/api/v1/questions/%d", question.ID)
+
+		response, _, _ := Delete[map[string]any](questionPath)
+		if response.StatusCode != http.StatusOK {
+			t.Fatalf("Expected status code 200 on delete, got %d", response.StatusCode)
+		}
+
+		response, questions, _ := Get[httpio.PaginatedResponse](fmt.Sprintf("/api/v1/questions?filter[event_id]=%d", event.ID))
+		if response.StatusCode != http.StatusOK || questions.PaginationInfo.TotalCount != 0 {
+			t.Errorf("Expected deleted question to be left out of the list")
+		}
+
+		response, questions, _ = Get[httpio.PaginatedResponse](fmt.Sprintf("/api/v1/questions?filter[event_id]=%d&include_deleted=true", event.ID))
+		if response.StatusCode != http.StatusOK || questions.PaginationInfo.TotalCount != 1 {
+			t.Errorf("Expected deleted question in the list with include_deleted")
+		}
+
+		response, _, _ = Post[map[string]any](questionPath+"/restore", nil)
+		if response.StatusCode != http.StatusOK {
+			t.Errorf("Expected status code 200 on restore, got %d", response.StatusCode)
+		}
+	}))
+
+	t.Run("deleted event can't be referenced", testCaseSoftDelete(func(t *testing.T) {
+		event := &models.Event{Name: "Soft Delete Event", Deadline: time.Now().Add(24 * time.Hour), Status: "published"}
+		dbInstance.Save(event)
+		questionPayload := httpio.AnyMap{"event_id": event.ID, "content": "Who wins?", "type": "country", "points": 1}
+
+		response, _, _ := Delete[map[string]any](fmt.Sprintf("/api/v1/events/%d", event.ID))
+		if response.StatusCode != http.StatusOK {
+			t.Fatalf("Expected status code 200 on delete, got %d", response.StatusCode)
+		}
+
+		response, _, _ = Post[map[string]any]("/api/v1/questions", questionPayload)
+		if response.StatusCode != http.StatusBadRequest {
+			t.Errorf("Expected status code 400 for question of deleted event, got %d", response.StatusCode)
+		}
+
+		response, _, _ = Post[map[string]any](fmt.Sprintf("/api/v1/events/%d/restore", event.ID), nil)
+		if response.StatusCode != http.StatusOK {
+			t.Fatalf("Expected status code 200 on restore, got %d", response.StatusCode)
+		}
+
+		response, _, _ = Post[map[string]any]("/api/v1/questions", questionPayload)
+		if response.StatusCode != http.StatusCreated {
+			t.Errorf("Expected question of restored event to be created, got %d", response.StatusCode)
+		}
+	}))
+
+	t.Run("include_deleted requires write permission of the resource", testCaseSoftDelete(func(t *testing.T) {
+		createUser("viewer@softdelete.test", "softdelete_viewer", "password123", httpio.UserRole)
+		accessToken := loginAs("viewer@softdelete.test", "password123")["access_token"].(string)
+
+		response, _, _ := executeHttpWithToken[map[string]any]("GET", "/api/v1/events?include_deleted=true", nil, accessToken)
+		if response.StatusCode != http.StatusUnauthorized {
+			t.Errorf("Expected status code 401, got %d", response.StatusCode)
+		}
+
+		createUser("organizer@softdelete.test", "softdelete_organizer", "password123", httpio.OrganizerRole)
+		accessToken = loginAs("organizer@softdelete.test", "password123")["access_token"].(string)
+
+		response, _, _ = executeHttpWithToken[map[string]any]("GET", "/api/v1/events?include_deleted=true", nil, accessToken)
+		if response.StatusCode != http.StatusOK {
+			t.Errorf("Expected status code 200 with events:write permission, got %d", response.StatusCode)
+		}
+	}))
+}
+
+func beforeEachSoftDelete() {
+	dbInstance.Where("email LIKE ?", "%@softdelete.test").Delete(&models.User{})
+	dbInstance.Unscoped().Where("name = ?", "Soft Delete Event").Delete(&models.Event{})
+}
+
+func testCaseSoftDelete(test func(t *testing.T)) func(*testing.T) {
+	return func(t *testing.T) {
+		beforeEachSoftDelete()
+		defer beforeEachSoftDelete()
+		test(t)
+	}
+}
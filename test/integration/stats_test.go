@@ -0,0 +1,105 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/filipio/athletics-backend/internal/models"
+	"github.com/filipio/athletics-backend/pkg/httpio"
+)
+
+func TestQuestionStats(t *testing.T) {
+	t.Run("returns distribution of country answers", testCaseStats(func(t *testing.T) {
+		event := &models.Event{Name: "Stats Event", Deadline: time.Now().Add(-time.Hour), Status: "published"}
+		dbInstance.Save(event)
+		question := &models.Question{EventID: event.ID, Content: "Which country wins?", Type: "country", Points: 2}
+		dbInstance.Save(question)
+
+		now := time.Now()
+		countries := []string{"KEN", "KEN", "KEN", "ETH"}
+		for i, country := range countries {
+			user := createUser(fmt.Sprintf("user%d@stats.test", i), fmt.Sprintf("user%d", i), "password123", httpio.UserRole)
+			points := uint(0)
+			if country == "KEN" {
+				points = question.Points
+			}
+			dbInstance.Save(&models.Answer{
+				UserID:          user.ID,
+				QuestionID:      question.ID,
+				Content:         models.AnswerOfQuestion{JSON: []byte(fmt.Sprintf(`{"country": "%s"}`, country))},
+				Points:          points,
+				PointsGrantedAt: &now,
+			})
+		}
+
+		tokens := loginAs("user0@stats.test", "password123")
+		response, stats, err := executeHttpWithToken[map[string]any]("GET", fmt.Sprintf("/api/v1/questions/%d/stats", question.ID), nil, tokens["access_token"].(string))
+		if err != nil {
+			t.Fatalf("Error executing request: %s", err.Error())
+		}
+
+		if response.StatusCode != http.StatusOK {
+			t.Fatalf("Expected status code 200, got %d", response.StatusCode)
+		}
+
+		if (*stats)["percentage_correct"] != float64(75) {
+			t.Errorf("Expected percentage_correct 75, got %v", (*stats)["percentage_correct"])
+		}
+
+		if (*stats)["average_points"] != float64(1.5) {
+			t.Errorf("Expected average_points 1.5, got %v", (*stats)["average_points"])
+		}
+
+		distribution := (*stats)["distribution"].([]any)
+		if len(distribution) != 2 {
+			t.Fatalf("Expected 2 distribution items, got %d", len(distribution))
+		}
+
+		mostPopular := distribution[0].(map[string]any)
+		if mostPopular["country"] != "KEN" || mostPopular["count"] != float64(3) || mostPopular["percentage"] != float64(75) {
+			t.Errorf("Unexpected most popular answer: %v", mostPopular)
+		}
+	}))
+
+	t.Run("is hidden for regular users before the deadline", testCaseStats(func(t *testing.T) {
+		event := &models.Event{Name: "Stats Event", Deadline: time.Now().Add(time.Hour), Status: "published"}
+		dbInstance.Save(event)
+		question := &models.Question{EventID: event.ID, Content: "Which country wins?", Type: "country", Points: 1}
+		dbInstance.Save(question)
+
+		createUser("regular@stats.test", "regular", "password123", httpio.UserRole)
+		tokens := loginAs("regular@stats.test", "password123")
+
+		response, _, err := executeHttpWithToken[map[string]any]("GET", fmt.Sprintf("/api/v1/questions/%d/stats", question.ID), nil, tokens["access_token"].(string))
+		if err != nil {
+			t.Fatalf("Error executing request: %s", err.Error())
+		}
+
+		if response.StatusCode != http.StatusForbidden {
+			t.Errorf("Expected status code 403, got %d", response.StatusCode)
+		}
+
+		response, _, err = Get[map[string]any](fmt.Sprintf("/api/v1/events/%d/stats", event.ID))
+		if err != nil {
+			t.Fatalf("Error executing request: %s", err.Error())
+		}
+
+		if response.StatusCode != http.StatusOK {
+			t.Errorf("Expected status code 200 for admin, got %d", response.StatusCode)
+		}
+	}))
+}
+
+func beforeEachStats() {
+	dbInstance.Where("email LIKE ?", "%@stats.test").Delete(&models.User{})
+	dbInstance.Unscoped().Where("1 = 1").Delete(&models.Event{})
+}
+
+func testCaseStats(test func(t *testing.T)) func(*testing.T) {
+	return func(t *testing.T) {
+		beforeEachStats()
+		test(t)
+	}
+}
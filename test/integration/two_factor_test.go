@@ -0,0 +1,186 @@
+package controllers
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/filipio/athletics-backend/internal/controllers"
+	"github.com/filipio/athletics-backend/internal/models"
+	"github.com/filipio/athletics-backend/pkg/httpio"
+	"github.com/filipio/athletics-backend/pkg/totp"
+)
+
+func TestTwoFactorLogin(t *testing.T) {
+	t.Run("login requires second factor after enrollment", testCaseTwoFactor(func(t *testing.T) {
+		createUser("enrolled@twofactor.test", "enrolled", "password123", httpio.OrganizerRole)
+		session := loginAs("enrolled@twofactor.test", "password123")
+		secret, recoveryCodes := enableTwoFactor(t, session["access_token"].(string), "password123")
+
+		if len(recoveryCodes) != models.RecoveryCodesCount {
+			t.Errorf("Expected %d recovery codes, got %d", models.RecoveryCodesCount, len(recoveryCodes))
+		}
+
+		response, challenge, err := Post[controllers.LoginChallengeResponse]("/api/v1/login", httpio.AnyMap{
+			"email":    "enrolled@twofactor.test",
+			"password": "password123",
+		})
+		if err != nil {
+			t.Fatalf("Error executing request: %s", err.Error())
+		}
+
+		if response.StatusCode != http.StatusOK || !challenge.TwoFactorRequired || challenge.ChallengeToken == "" {
+			t.Fatalf("Expected login challenge, got status %d and %+v", response.StatusCode, challenge)
+		}
+
+		// code of the next step is within allowed clock skew and was not used for the confirmation
+		code, _ := totp.Code(secret, totp.Step(time.Now())+1)
+		response, tokenPair, _ := Post[map[string]any]("/api/v1/auth/2fa/verify", httpio.AnyMap{
+			"challenge_token": challenge.ChallengeToken,
+			"code":            code,
+		})
+		if response.StatusCode != http.StatusOK || (*tokenPair)["access_token"] == nil {
+			t.Fatalf("Expected token pair, got status %d", response.StatusCode)
+		}
+
+		response, _, _ = Post[map[string]any]("/api/v1/auth/2fa/verify", httpio.AnyMap{
+			"challenge_token": challenge.ChallengeToken,
+			"code":            code,
+		})
+		if response.StatusCode != http.StatusUnauthorized {
+			t.Errorf("Expected used challenge to be rejected, got status %d", response.StatusCode)
+		}
+	}))
+
+	t.Run("recovery code can be used only once", testCaseTwoFactor(func(t *testing.T) {
+		createUser("recovery@twofactor.test", "recovery", "password123", httpio.UserRole)
+		session := loginAs("recovery@twofactor.test", "password123")
+		_, recoveryCodes := enableTwoFactor(t, session["access_token"].(string), "password123")
+
+		for i, expectedStatus := range []int{http.StatusOK, http.StatusBadRequest} {
+			challenge := loginAs("recovery@twofactor.test", "password123")
+			response, _, _ := Post[map[string]any]("/api/v1/auth/2fa/verify", httpio.AnyMap{
+				"challenge_token": challenge["challenge_token"],
+				"code":            recoveryCodes[0],
+			})
+			if response.StatusCode != expectedStatus {
+				t.Errorf("Attempt %d: expected status code %d, got %d", i+1, expectedStatus, response.StatusCode)
+			}
+		}
+	}))
+
+	t.Run("challenge is invalidated after too many failed attempts", testCaseTwoFactor(func(t *testing.T) {
+		createUser("bruteforce@twofactor.test", "bruteforce", "password123", httpio.UserRole)
+		session := loginAs("bruteforce@twofactor.test", "password123")
+		secret, _ := enableTwoFactor(t, session["access_token"].(string), "password123")
+
+		challenge := loginAs("bruteforce@twofactor.test", "password123")
+		for range models.MaxLoginChallengeFailedAttempts {
+			Post[map[string]any]("/api/v1/auth/2fa/verify", httpio.AnyMap{
+				"challenge_token": challenge["challenge_token"],
+				"code":            "000000",
+			})
+		}
+
+		code, _ := totp.Code(secret, totp.Step(time.Now())+1)
+		response, _, _ := Post[map[string]any]("/api/v1/auth/2fa/verify", httpio.AnyMap{
+			"challenge_token": challenge["challenge_token"],
+			"code":            code,
+		})
+		if response.StatusCode != http.StatusUnauthorized {
+			t.Errorf("Expected status code 401, got %d", response.StatusCode)
+		}
+	}))
+
+	t.Run("failed codes count against account login limit", testCaseTwoFactor(func(t *testing.T) {
+		createUser("guessing@twofactor.test", "guessing", "password123", httpio.UserRole)
+		session := loginAs("guessing@twofactor.test", "password123")
+		enableTwoFactor(t, session["access_token"].(string), "password123")
+
+		// every guess uses a fresh challenge, the correct password must not reset the counter
+		for range models.MaxFailedLoginsPerAccount {
+			challenge := loginAs("guessing@twofactor.test", "password123")
+			Post[map[string]any]("/api/v1/auth/2fa/verify", httpio.AnyMap{
+				"challenge_token": challenge["challenge_token"],
+				"code":            "000000",
+			})
+		}
+
+		response, _, _ := Post[map[string]any]("/api/v1/login", httpio.AnyMap{
+			"email":    "guessing@twofactor.test",
+			"password": "password123",
+		})
+		if response.StatusCode != http.StatusTooManyRequests {
+			t.Errorf("Expected status code 429, got %d", response.StatusCode)
+		}
+	}))
+}
+
+func TestTwoFactorRequiredForRole(t *testing.T) {
+	t.Run("organizer without 2FA can't create events when role requires it", testCaseTwoFactor(func(t *testing.T) {
+		response, _, err := Put[map[string]any]("/api/v1/roles/"+httpio.OrganizerRole+"/two-factor", httpio.AnyMap{"required": true})
+		if err != nil {
+			t.Fatalf("Error executing request: %s", err.Error())
+		}
+		if response.StatusCode != http.StatusOK {
+			t.Fatalf("Expected status code 200, got %d", response.StatusCode)
+		}
+
+		createUser("organizer@twofactor.test", "organizer", "password123", httpio.OrganizerRole)
+		session := loginAs("organizer@twofactor.test", "password123")
+		eventPayload := httpio.AnyMap{"name": "2FA Event", "deadline": time.Now().Add(24 * time.Hour)}
+
+		response, _, _ = executeHttpWithToken[map[string]any]("POST", "/api/v1/events", eventPayload, session["access_token"].(string))
+		if response.StatusCode != http.StatusForbidden {
+			t.Fatalf("Expected status code 403, got %d", response.StatusCode)
+		}
+
+		enableTwoFactor(t, session["access_token"].(string), "password123")
+
+		response, _, _ = executeHttpWithToken[map[string]any]("POST", "/api/v1/events", eventPayload, session["access_token"].(string))
+		if response.StatusCode != http.StatusOK {
+			t.Errorf("Expected status code 200 after enabling 2FA, got %d", response.StatusCode)
+		}
+	}))
+}
+
+// enableTwoFactor enrolls and confirms 2FA for the user, returns TOTP secret and recovery codes
+func enableTwoFactor(t *testing.T, accessToken string, password string) (string, []string) {
+	response, enrollment, err := executeHttpWithToken[controllers.TwoFactorEnrollmentResponse]("POST", "/api/v1/users/me/2fa/enroll", httpio.AnyMap{
+		"password": password,
+	}, accessToken)
+	if err != nil {
+		t.Fatalf("Error executing request: %s", err.Error())
+	}
+	if response.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status code 200 on enrollment, got %d", response.StatusCode)
+	}
+
+	code, _ := totp.Code(enrollment.Secret, totp.Step(time.Now()))
+	response, recoveryCodes, err := executeHttpWithToken[controllers.RecoveryCodesResponse]("POST", "/api/v1/users/me/2fa/confirm", httpio.AnyMap{
+		"code": code,
+	}, accessToken)
+	if err != nil {
+		t.Fatalf("Error executing request: %s", err.Error())
+	}
+	if response.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status code 200 on confirmation, got %d", response.StatusCode)
+	}
+
+	return enrollment.Secret, recoveryCodes.RecoveryCodes
+}
+
+func beforeEachTwoFactor() {
+	dbInstance.Model(&models.Role{}).Where("1 = 1").Update("requires_two_factor", false)
+	dbInstance.Where("email LIKE ?", "%@twofactor.test").Delete(&models.User{})
+	dbInstance.Unscoped().Where("name = ?", "2FA Event").Delete(&models.Event{})
+	dbInstance.Where("1 = 1").Delete(&models.LoginAttemptLimit{})
+}
+
+func testCaseTwoFactor(test func(t *testing.T)) func(*testing.T) {
+	return func(t *testing.T) {
+		beforeEachTwoFactor()
+		defer beforeEachTwoFactor()
+		test(t)
+	}
+}
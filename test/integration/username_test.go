@@ -0,0 +1,106 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/filipio/athletics-backend/internal/controllers"
+	"github.com/filipio/athletics-backend/internal/models"
+	"github.com/filipio/athletics-backend/pkg/httpio"
+)
+
+func TestUsernameAvailability(t *testing.T) {
+	t.Run("username is checked against rules, blocklist and existing accounts", testCaseUsername(func(t *testing.T) {
+		createUser("taken@username.test", "TakenName", "password123", httpio.UserRole)
+		dbInstance.Create(&models.PendingRegistration{
+			Email:             "pending@username.test",
+			Username:          "pending_name",
+			PasswordHash:      "x",
+			VerificationToken: "pending-username-test",
+			ExpiresAt:         time.Now().Add(time.Hour),
+		})
+
+		for _, testCase := range []struct {
+			username  string
+			email     string
+			available bool
+		}{
+			{"free_name", "", true},
+			{"takenname", "", false},
+			{"ab", "", false},
+			{"with space", "", false},
+			{"_leading", "", false},
+			{"Admin", "", false},
+			{"user_123", "", false},
+			{"athlete_123", "", false},
+			{"xx_kurwa_xx", "", false},
+			{"Pending_Name", "", false},
+			{"pending_name", "pending@username.test", true},
+		} {
+			query := url.Values{"username": {testCase.username}, "email": {testCase.email}}
+			response, result, err := Get[controllers.UsernameAvailabilityResponse]("/api/v1/auth/username-availability?" + query.Encode())
+			if err != nil {
+				t.Fatalf("Error executing request: %s", err.Error())
+			}
+			if response.StatusCode != http.StatusOK {
+				t.Fatalf("Expected status code 200, got %d", response.StatusCode)
+			}
+			if result.Available != testCase.available {
+				t.Errorf("Expected %s available: %v, got %v (%v)", testCase.username, testCase.available, result.Available, result.Reason)
+			}
+			if !result.Available && result.Reason == nil {
+				t.Errorf("Expected reason for unavailable %s", testCase.username)
+			}
+		}
+	}))
+
+	t.Run("registration rejects taken username before sending the email", testCaseUsername(func(t *testing.T) {
+		createUser("owner@username.test", "owner_name", "password123", httpio.UserRole)
+
+		response, _, _ := Post[map[string]any]("/api/v1/auth/register/request-verification", httpio.AnyMap{
+			"email":    "newcomer@username.test",
+			"username": "OWNER_NAME",
+			"password": "password123",
+		})
+		if response.StatusCode != http.StatusBadRequest {
+			t.Errorf("Expected status code 400, got %d", response.StatusCode)
+		}
+
+		var pendingCount int64
+		dbInstance.Model(&models.PendingRegistration{}).Where("email = ?", "newcomer@username.test").Count(&pendingCount)
+		if pendingCount != 0 {
+			t.Errorf("Expected no pending registration, got %d", pendingCount)
+		}
+	}))
+
+	t.Run("admin update keeps username rules", testCaseUsername(func(t *testing.T) {
+		createUser("first@username.test", "first_name", "password123", httpio.UserRole)
+		second := createUser("second@username.test", "second_name", "password123", httpio.UserRole)
+
+		response, _, _ := Put[map[string]any](fmt.Sprintf("/api/v1/users/%d", second.ID), httpio.AnyMap{
+			"email":    "second@username.test",
+			"username": "First_Name",
+			"password": "password123",
+		})
+		if response.StatusCode != http.StatusBadRequest {
+			t.Errorf("Expected status code 400, got %d", response.StatusCode)
+		}
+	}))
+}
+
+func beforeEachUsername() {
+	dbInstance.Where("email LIKE ?", "%@username.test").Delete(&models.User{})
+	dbInstance.Where("email LIKE ?", "%@username.test").Delete(&models.PendingRegistration{})
+	dbInstance.Where("email LIKE ?", "%@username.test").Delete(&models.EmailVerificationRateLimit{})
+}
+
+func testCaseUsername(test func(t *testing.T)) func(*testing.T) {
+	return func(t *testing.T) {
+		beforeEachUsername()
+		defer beforeEachUsername()
+		test(t)
+	}
+}
@@ -35,6 +35,62 @@ func (m *MockEmailSender) EXPECT() *MockEmailSenderMockRecorder {
 	return m.recorder
 }
 
+// SendEmailChangeConfirmationEmail mocks base method.
+func (m *MockEmailSender) SendEmailChangeConfirmationEmail(arg0 context.Context, arg1 email.EmailChangeConfirmationEmailParams) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SendEmailChangeConfirmationEmail", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SendEmailChangeConfirmationEmail indicates an expected call of SendEmailChangeConfirmationEmail.
+func (mr *MockEmailSenderMockRecorder) SendEmailChangeConfirmationEmail(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SendEmailChangeConfirmationEmail", reflect.TypeOf((*MockEmailSender)(nil).SendEmailChangeConfirmationEmail), arg0, arg1)
+}
+
+// SendMagicLinkEmail mocks base method.
+func (m *MockEmailSender) SendMagicLinkEmail(arg0 context.Context, arg1 email.MagicLinkEmailParams) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SendMagicLinkEmail", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SendMagicLinkEmail indicates an expected call of SendMagicLinkEmail.
+func (mr *MockEmailSenderMockRecorder) SendMagicLinkEmail(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SendMagicLinkEmail", reflect.TypeOf((*MockEmailSender)(nil).SendMagicLinkEmail), arg0, arg1)
+}
+
+// SendPasswordResetEmail mocks base method.
+func (m *MockEmailSender) SendPasswordResetEmail(arg0 context.Context, arg1 email.PasswordResetEmailParams) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SendPasswordResetEmail", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SendPasswordResetEmail indicates an expected call of SendPasswordResetEmail.
+func (mr *MockEmailSenderMockRecorder) SendPasswordResetEmail(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SendPasswordResetEmail", reflect.TypeOf((*MockEmailSender)(nil).SendPasswordResetEmail), arg0, arg1)
+}
+
+// SendSecurityAlertEmail mocks base method.
+func (m *MockEmailSender) SendSecurityAlertEmail(arg0 context.Context, arg1 email.SecurityAlertEmailParams) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SendSecurityAlertEmail", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SendSecurityAlertEmail indicates an expected call of SendSecurityAlertEmail.
+func (mr *MockEmailSenderMockRecorder) SendSecurityAlertEmail(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SendSecurityAlertEmail", reflect.TypeOf((*MockEmailSender)(nil).SendSecurityAlertEmail), arg0, arg1)
+}
+
 // SendVerificationEmail mocks base method.
 func (m *MockEmailSender) SendVerificationEmail(arg0 context.Context, arg1 email.VerificationEmailParams) error {
 	m.ctrl.T.Helper()